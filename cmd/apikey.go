@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/bgildson/unico-challenge/entity"
+	apikeyRepository "github.com/bgildson/unico-challenge/repository/apikey"
+	apikeyService "github.com/bgildson/unico-challenge/service/apikey"
+)
+
+// runAPIKeys opens the configured database and runs fn with the apikey service
+func runAPIKeys(cmd *cobra.Command, fn func(context.Context, apikeyService.Service) error) {
+	cfg := loadConfig(cmd)
+	if err := cfg.ValidateDatabaseURL(); err != nil {
+		logrus.Error(err)
+		os.Exit(1)
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		logrus.Fatalf("could not connect to the database: %v", err)
+	}
+	defer db.Close()
+
+	if err := fn(context.Background(), apikeyService.New(apikeyRepository.NewPostgresRepository(db))); err != nil {
+		logrus.Error(err)
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+var apikeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Manages the api keys used to authenticate the requests",
+}
+
+var apikeyCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Creates an api key, it is printed only once",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		name, _ := cmd.Flags().GetString("name")
+		scopes, _ := cmd.Flags().GetStringSlice("scopes")
+
+		runAPIKeys(cmd, func(ctx context.Context, s apikeyService.Service) error {
+			key, created, err := s.Create(ctx, name, scopes)
+			if err != nil {
+				return err
+			}
+			logrus.Infof("api key %d created with the scopes %s", created.ID, strings.Join(created.Scopes, ","))
+			fmt.Println(key)
+			return nil
+		})
+	},
+}
+
+var apikeyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the api keys",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		runAPIKeys(cmd, func(ctx context.Context, s apikeyService.Service) error {
+			keys, err := s.List(ctx)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tCREATED AT\tREVOKED AT")
+			for _, k := range keys {
+				revokedAt := "-"
+				if k.Revoked() {
+					revokedAt = k.RevokedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Prefix, strings.Join(k.Scopes, ","), k.CreatedAt.Format(time.RFC3339), revokedAt)
+			}
+			return w.Flush()
+		})
+	},
+}
+
+var apikeyRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Revokes an api key, the requests using it are refused from now on",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			logrus.Errorf("invalid id %q", args[0])
+			os.Exit(1)
+		}
+
+		runAPIKeys(cmd, func(ctx context.Context, s apikeyService.Service) error {
+			if err := s.Revoke(ctx, id); err != nil {
+				return err
+			}
+			logrus.Infof("api key %d revoked", id)
+			return nil
+		})
+	},
+}
+
+func init() {
+	apikeyCmd.PersistentFlags().StringP("dsn", "d", "", "The Data Source Name that should be used to connect in the database, overrides database_url.")
+	apikeyCreateCmd.Flags().String("name", "", "Name identifying who uses the key, up to "+strconv.Itoa(apikeyService.MaxNameLength)+" characters.")
+	apikeyCreateCmd.Flags().StringSlice("scopes", []string{entity.ScopeRead}, "Scopes of the key: "+strings.Join(entity.Scopes, ", ")+".")
+	apikeyCreateCmd.MarkFlagRequired("name")
+
+	apikeyCmd.AddCommand(apikeyCreateCmd, apikeyListCmd, apikeyRevokeCmd)
+	rootCmd.AddCommand(apikeyCmd)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/bgildson/unico-challenge/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspects the configuration",
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Prints the effective configuration, with the secrets redacted",
+	Run: func(cmd *cobra.Command, _ []string) {
+		cfg := loadConfig(cmd)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tENV\tVALUE")
+		for _, f := range config.Fields(cfg) {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", f.Key, f.Env, f.Value())
+		}
+		w.Flush()
+	},
+}
+
+// loadConfig loads the config file informed by --config or CONFIG_FILE, the envs and the command
+// flags, exiting when they could not be parsed
+func loadConfig(cmd *cobra.Command) config.Config {
+	path, _ := cmd.Flags().GetString("config")
+	if path == "" {
+		path = os.Getenv(config.FileEnv)
+	}
+
+	cfg, err := config.Load(path, cmd.Flags())
+	if err != nil {
+		logrus.Fatalf("could not load the config: %v", err)
+	}
+	return cfg
+}
+
+// openDatabase opens the database pool with the configured limits
+func openDatabase(cfg config.Config) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
+	return db, nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringP("config", "c", "", "Path of a YAML or TOML config file, CONFIG_FILE is used when empty.")
+
+	configCmd.AddCommand(configPrintCmd)
+	rootCmd.AddCommand(configCmd)
+}
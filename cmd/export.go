@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	feiralivreRepo "github.com/bgildson/unico-challenge/repository/feiralivre"
+	feiralivreServ "github.com/bgildson/unico-challenge/service/feiralivre"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports the feiras livres to a CSV, JSON, NDJSON or GeoJSON file",
+	Run: func(cmd *cobra.Command, _ []string) {
+		cfg := loadConfig(cmd)
+		if err := cfg.ValidateDatabaseURL(); err != nil {
+			logrus.Fatal(err)
+		}
+
+		path := cmd.Flag("file").Value.String()
+		format := cmd.Flag("format").Value.String()
+		if format == "" {
+			format = feiralivreServ.FormatCSV
+			if path != feiralivreServ.StdinPath {
+				detected, err := feiralivreServ.FormatFromPath(path)
+				if err != nil {
+					logrus.Errorf("could not detect the format of %s: %v", path, err)
+					os.Exit(1)
+				}
+				format = detected
+			}
+		}
+
+		var qp feiralivreRepo.QueryParams
+		qp.Distrito, _ = cmd.Flags().GetString("distrito")
+		qp.Regiao5, _ = cmd.Flags().GetString("regiao5")
+		qp.NomeFeira, _ = cmd.Flags().GetString("nome-feira")
+		qp.Bairro, _ = cmd.Flags().GetString("bairro")
+		qp.Pagination.Limit, _ = cmd.Flags().GetInt("limit")
+		qp.Pagination.Offset, _ = cmd.Flags().GetInt("offset")
+
+		stopTracing := setupTracing(cfg.Tracing)
+		defer stopTracing()
+
+		db, err := openDatabase(cfg)
+		if err != nil {
+			logrus.Fatalf("could not connect to the database: %v", err)
+		}
+		defer db.Close()
+
+		fs := afero.NewOsFs()
+
+		// the registers are written to a temporary file renamed to path only when the export
+		// succeeds, so a failed export keeps the previous file
+		var out io.Writer = os.Stdout
+		var tmp afero.File
+		if path != feiralivreServ.StdinPath {
+			tmp, err = afero.TempFile(fs, filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+			if err != nil {
+				logrus.Errorf("could not create a temporary file for %s: %v", path, err)
+				os.Exit(1)
+			}
+			out = tmp
+		}
+
+		r := feiralivreRepo.NewPostgresRepository(db)
+
+		s := feiralivreServ.New(fs, r, feiralivreServ.DefaultOptions())
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		count, err := s.Export(ctx, out, format, qp)
+		if err == nil && tmp != nil {
+			err = replaceFile(fs, tmp, path)
+		}
+		if err != nil {
+			if tmp != nil {
+				tmp.Close()
+				fs.Remove(tmp.Name())
+			}
+			logrus.Errorf("could not export: %v", err)
+			os.Exit(1)
+		}
+
+		logrus.Infof("Export finished! Wrote %d registers.", count)
+	},
+}
+
+// replaceFile closes the temporary file tmp and renames it to path, replacing the previous file
+func replaceFile(fs afero.Fs, tmp afero.File, path string) error {
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write %s: %v", path, err)
+	}
+	// the temporary files are only readable by the owner
+	if err := fs.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("could not change the mode of %s: %v", path, err)
+	}
+	if err := fs.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("could not replace %s: %v", path, err)
+	}
+	return nil
+}
+
+func init() {
+	exportCmd.Flags().StringP("dsn", "d", "", "The Data Source Name that should be used to connect in the database, overrides database_url.")
+	exportCmd.Flags().StringP("file", "f", feiralivreServ.StdinPath, "File path where the registers should be written, use - to write to the stdout.")
+	exportCmd.Flags().String("format", "", "Format of the file (csv, json, ndjson or geojson), detected by the file extension when empty (csv for the stdout).")
+	exportCmd.Flags().String("distrito", "", "Exports only the registers with this distrito.")
+	exportCmd.Flags().String("regiao5", "", "Exports only the registers with this regiao5.")
+	exportCmd.Flags().String("nome-feira", "", "Exports only the registers with this nome_feira.")
+	exportCmd.Flags().String("bairro", "", "Exports only the registers with this bairro.")
+	exportCmd.Flags().Int("limit", 0, "Maximum number of registers exported, 0 means unlimited.")
+	exportCmd.Flags().Int("offset", 0, "Number of registers skipped before exporting.")
+
+	rootCmd.AddCommand(exportCmd)
+}
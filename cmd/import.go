@@ -1,9 +1,13 @@
 package cmd
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
@@ -15,33 +19,58 @@ import (
 
 var importCmd = &cobra.Command{
 	Use:   "import",
-	Short: "Imports a CSV to the database",
+	Short: "Imports a CSV, JSON, NDJSON or GeoJSON file to the database",
 	Run: func(cmd *cobra.Command, _ []string) {
-		databaseURL := os.Getenv("DATABASE_URL")
-		if databaseURL == "" {
-			logrus.Error("could not load the database connection string")
-		}
-		if databaseURLFlag := cmd.Flag("dsn"); databaseURLFlag.Value.String() != "" {
-			databaseURL = databaseURLFlag.Value.String()
+		cfg := loadConfig(cmd)
+		if err := cfg.ValidateDatabaseURL(); err != nil {
+			logrus.Fatal(err)
 		}
 
-		db, err := sql.Open("postgres", databaseURL)
+		stopTracing := setupTracing(cfg.Tracing)
+		defer stopTracing()
+
+		db, err := openDatabase(cfg)
 		if err != nil {
-			logrus.Errorf("could not connect to the database: %v", err)
+			logrus.Fatalf("could not connect to the database: %v", err)
 		}
 		defer db.Close()
 
+		workers, _ := cmd.Flags().GetInt("workers")
+		batchSize, _ := cmd.Flags().GetInt("batch-size")
+		maxRowsPerSecond, _ := cmd.Flags().GetInt("max-rows-per-second")
+		progressInterval, _ := cmd.Flags().GetDuration("progress-interval")
+		progress, progressInterval := newProgressReporter(os.Stderr, progressInterval)
+
 		fs := afero.NewOsFs()
 
 		r := feiralivreRepo.NewPostgresRepository(db)
 
-		s := feiralivreServ.New(fs, r)
+		s := feiralivreServ.New(fs, r, feiralivreServ.Options{
+			Workers:          workers,
+			BatchSize:        batchSize,
+			MaxRowsPerSecond: maxRowsPerSecond,
+			MaxOpenConns:     db.Stats().MaxOpenConnections,
+			Progress:         progress,
+			ProgressInterval: progressInterval,
+		})
 
 		path := cmd.Flag("file")
+		format := cmd.Flag("format")
 
-		msg, err := s.Import(path.Value.String())
-		if err != nil {
+		// SIGINT and SIGTERM stop the reading and wait the registers already read to be persisted
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		msg, err := s.Import(ctx, path.Value.String(), format.Value.String())
+		switch {
+		case errors.Is(err, feiralivreServ.ErrCouldNotSyncPK):
+			// the registers were imported, only the sequence is behind
+			logrus.Warnf("could not import completely: %v", err)
+		case err != nil:
+			// a canceled import also prints what was imported until there
 			logrus.Errorf("could not import: %v", err)
+			fmt.Println(msg)
+			os.Exit(1)
 		}
 
 		fmt.Println(msg)
@@ -49,8 +78,14 @@ var importCmd = &cobra.Command{
 }
 
 func init() {
-	importCmd.Flags().StringP("dsn", "d", "", "The Data Source Name that should be used to connect in the database.")
-	importCmd.Flags().StringP("file", "f", "", "CSV file path that should be imported.")
+	importCmd.Flags().StringP("dsn", "d", "", "The Data Source Name that should be used to connect in the database, overrides database_url.")
+	importCmd.Flags().StringP("file", "f", "", "File path that should be imported, use - to read from the stdin. Gzip, bzip2 and zip files are decompressed.")
+	importCmd.Flags().String("format", "", "Format of the file (csv, json, ndjson or geojson), detected by the file extension when empty.")
+	importCmd.Flags().Int("workers", feiralivreServ.DefaultWorkers, "Number of workers persisting the registers, never exceeds --max-open-conns.")
+	importCmd.Flags().Int("batch-size", feiralivreServ.DefaultBatchSize, "Number of registers persisted in each transaction.")
+	importCmd.Flags().Int("max-rows-per-second", 0, "Limits how many rows are imported per second, 0 means unlimited.")
+	importCmd.Flags().Int("max-open-conns", 0, "Maximum number of open connections to the database, 0 means unlimited, overrides database.max_open_conns.")
+	importCmd.Flags().Duration("progress-interval", 5*time.Second, "Interval between the progress log lines when the output is not a terminal.")
 	importCmd.MarkFlagRequired("file")
 
 	rootCmd.AddCommand(importCmd)
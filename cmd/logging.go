@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"io"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bgildson/unico-challenge/config"
+	"github.com/bgildson/unico-challenge/server"
+	"github.com/bgildson/unico-challenge/server/logging"
+)
+
+// setupLogging configures the standard logger and returns the writer of the configured outputs,
+// which must be closed before the process exits
+func setupLogging(cfg config.Config) (io.WriteCloser, error) {
+	fallback := logrus.DebugLevel
+	if cfg.Environment == server.ProductionEnvironment {
+		fallback = logrus.WarnLevel
+	}
+	level, err := logging.ParseLevel(cfg.Log.Level, fallback)
+	if err != nil {
+		return nil, err
+	}
+	formatter, err := logging.NewFormatter(cfg.Log.Format)
+	if err != nil {
+		return nil, err
+	}
+	out, err := logging.Open(cfg.Logging())
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.SetOutput(out)
+	logrus.SetFormatter(formatter)
+	logrus.SetLevel(level)
+	logrus.SetReportCaller(cfg.Environment != server.ProductionEnvironment)
+
+	return out, nil
+}
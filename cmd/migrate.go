@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/bgildson/unico-challenge/migrations"
+	"github.com/bgildson/unico-challenge/repository/migration"
+)
+
+// newMigrator creates a migrator for the embedded migrations and returns the latest version
+// between them, db must be closed by the caller
+func newMigrator(db *sql.DB) (migration.Migrator, int64, error) {
+	ms, err := migration.Load(migrations.FS)
+	if err != nil {
+		return nil, 0, err
+	}
+	return migration.NewPostgresMigrator(db, ms), migration.LatestVersion(ms), nil
+}
+
+// databaseAhead checks if the database has a version newer than latest, applied by a newer binary,
+// it is false when the version could not be read in 5 seconds
+func databaseAhead(m migration.Migrator, latest int64) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	version, _, err := m.Version(ctx)
+	if err != nil {
+		logrus.Errorf("could not get the migrations version: %v", err)
+		return false
+	}
+	return version > latest
+}
+
+// runMigrator opens the configured database and runs fn with the migrator
+func runMigrator(cmd *cobra.Command, fn func(migration.Migrator) error) {
+	cfg := loadConfig(cmd)
+	if err := cfg.ValidateDatabaseURL(); err != nil {
+		logrus.Error(err)
+		os.Exit(1)
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		logrus.Fatalf("could not connect to the database: %v", err)
+	}
+	defer db.Close()
+
+	m, _, err := newMigrator(db)
+	if err != nil {
+		logrus.Errorf("could not load the migrations: %v", err)
+		os.Exit(1)
+	}
+
+	if err := fn(m); err != nil {
+		logrus.Error(err)
+		db.Close()
+		os.Exit(1)
+	}
+}
+
+func parseVersion(arg string) (int64, error) {
+	version, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid version %q", arg)
+	}
+	return version, nil
+}
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Applies or reverts the database migrations embedded in the binary",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up [N]",
+	Short: "Applies the next N migrations, every pending migration when N is omitted",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		steps := 0
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				logrus.Errorf("invalid number of migrations %q", args[0])
+				os.Exit(1)
+			}
+			steps = n
+		}
+
+		runMigrator(cmd, func(m migration.Migrator) error {
+			applied, err := m.Up(steps)
+			logrus.Infof("%d migrations applied", applied)
+			return err
+		})
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [N]",
+	Short: "Reverts the last N migrations (default 1), use --all to revert every migration",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		steps := 1
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			steps = 0
+		} else if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				logrus.Errorf("invalid number of migrations %q", args[0])
+				os.Exit(1)
+			}
+			steps = n
+		}
+
+		runMigrator(cmd, func(m migration.Migrator) error {
+			reverted, err := m.Down(steps)
+			logrus.Infof("%d migrations reverted", reverted)
+			return err
+		})
+	},
+}
+
+var migrateToCmd = &cobra.Command{
+	Use:   "to <version>",
+	Short: "Applies or reverts the migrations until version, 0 reverts every migration",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		version, err := parseVersion(args[0])
+		if err != nil {
+			logrus.Error(err)
+			os.Exit(1)
+		}
+
+		runMigrator(cmd, func(m migration.Migrator) error {
+			changed, err := m.To(version)
+			logrus.Infof("%d migrations applied or reverted", changed)
+			return err
+		})
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Lists the migrations and which of them were applied",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		runMigrator(cmd, func(m migration.Migrator) error {
+			statuses, err := m.Status()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+			for _, s := range statuses {
+				status := "pending"
+				if s.Dirty {
+					status = "dirty"
+				} else if s.Applied {
+					status = "applied"
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, status)
+			}
+			return w.Flush()
+		})
+	},
+}
+
+var migrateForceCmd = &cobra.Command{
+	Use:   "force <version>",
+	Short: "Sets the version without running any migration, used after fixing a dirty database",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		version, err := parseVersion(args[0])
+		if err != nil {
+			logrus.Error(err)
+			os.Exit(1)
+		}
+
+		runMigrator(cmd, func(m migration.Migrator) error {
+			if err := m.Force(version); err != nil {
+				return err
+			}
+			logrus.Infof("version forced to %d", version)
+			return nil
+		})
+	},
+}
+
+func init() {
+	migrateCmd.PersistentFlags().StringP("dsn", "d", "", "The Data Source Name that should be used to connect in the database, overrides database_url.")
+	migrateDownCmd.Flags().Bool("all", false, "Reverts every migration.")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateToCmd, migrateStatusCmd, migrateForceCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
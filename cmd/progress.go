@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	feiralivreServ "github.com/bgildson/unico-challenge/service/feiralivre"
+)
+
+const (
+	progressBarWidth       = 30
+	progressBarRefreshRate = 250 * time.Millisecond
+)
+
+// isTerminal indicates if the file is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// renderProgressBar writes the progress as a single line, overwriting the previous one
+func renderProgressBar(out io.Writer, p feiralivreServ.Progress) {
+	line := fmt.Sprintf(
+		"read %d, persisted %d, failed %d, %.0f rows/s",
+		p.Read,
+		p.Persisted,
+		p.Failed,
+		p.Throughput(),
+	)
+
+	if p.TotalBytes > 0 {
+		done := int(p.Ratio() * progressBarWidth)
+		bar := strings.Repeat("=", done) + strings.Repeat(" ", progressBarWidth-done)
+		line = fmt.Sprintf("[%s] %5.1f%% %s", bar, p.Ratio()*100, line)
+		if eta := p.ETA(); eta > 0 {
+			line += fmt.Sprintf(", eta %s", eta.Round(time.Second))
+		}
+	}
+
+	// the trailing spaces clean the remains of a longer previous line
+	fmt.Fprintf(out, "\r%s   ", line)
+	if p.Done {
+		fmt.Fprintln(out)
+	}
+}
+
+// logProgress writes the progress as a structured log line
+func logProgress(p feiralivreServ.Progress) {
+	fields := logrus.Fields{
+		"read":       p.Read,
+		"persisted":  p.Persisted,
+		"failed":     p.Failed,
+		"bytes_read": p.BytesRead,
+		"throughput": p.Throughput(),
+		"elapsed":    p.Elapsed.Seconds(),
+	}
+	if p.TotalBytes > 0 {
+		fields["total_bytes"] = p.TotalBytes
+		fields["eta"] = p.ETA().Seconds()
+	}
+
+	msg := "import progress"
+	if p.Done {
+		msg = "import done"
+	}
+
+	logrus.WithFields(fields).Info(msg)
+}
+
+// newProgressReporter creates how the import progress is shown, a progress bar when out
+// is a terminal and periodic log lines otherwise
+func newProgressReporter(out *os.File, logInterval time.Duration) (feiralivreServ.ProgressFunc, time.Duration) {
+	if isTerminal(out) {
+		return func(p feiralivreServ.Progress) {
+			renderProgressBar(out, p)
+		}, progressBarRefreshRate
+	}
+
+	return logProgress, logInterval
+}
@@ -1,92 +1,296 @@
 package cmd
 
 import (
-	"database/sql"
-	"io"
+	"context"
+	"errors"
+	"fmt"
 	"os"
-	"strconv"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	_ "github.com/lib/pq" // init postgres database driver
 	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 
 	feiralivreController "github.com/bgildson/unico-challenge/controller/feiralivre"
+	grantController "github.com/bgildson/unico-challenge/controller/grant"
+	healthController "github.com/bgildson/unico-challenge/controller/health"
+	importjobController "github.com/bgildson/unico-challenge/controller/importjob"
+	apikeyRepository "github.com/bgildson/unico-challenge/repository/apikey"
 	feiralivreRepository "github.com/bgildson/unico-challenge/repository/feiralivre"
+	grantRepository "github.com/bgildson/unico-challenge/repository/grant"
+	importjobRepository "github.com/bgildson/unico-challenge/repository/importjob"
+	"github.com/bgildson/unico-challenge/repository/migration"
 	"github.com/bgildson/unico-challenge/server"
+	"github.com/bgildson/unico-challenge/server/auth"
+	"github.com/bgildson/unico-challenge/server/deprecation"
+	"github.com/bgildson/unico-challenge/server/logging"
+	"github.com/bgildson/unico-challenge/server/metrics"
+	"github.com/bgildson/unico-challenge/server/openapi"
 	"github.com/bgildson/unico-challenge/server/parser"
+	"github.com/bgildson/unico-challenge/server/ratelimit"
+	"github.com/bgildson/unico-challenge/server/response"
+	"github.com/bgildson/unico-challenge/server/routes"
+	"github.com/bgildson/unico-challenge/server/security"
+	"github.com/bgildson/unico-challenge/server/tracing"
+	apikeyService "github.com/bgildson/unico-challenge/service/apikey"
+	feiralivreService "github.com/bgildson/unico-challenge/service/feiralivre"
+	grantService "github.com/bgildson/unico-challenge/service/grant"
+	importjobService "github.com/bgildson/unico-challenge/service/importjob"
 )
 
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Starts the rest api server",
 	Run: func(cmd *cobra.Command, args []string) {
-		paginationDefaultLimit, _ := strconv.Atoi(os.Getenv("PAGINATION_DEFAULT_LIMIT"))
-		paginationMaxLimit, _ := strconv.Atoi(os.Getenv("PAGINATION_MAX_LIMIT"))
-		config := server.NewConfig(
-			os.Getenv("ENVIRONMENT"),
-			os.Getenv("PORT"),
-			os.Getenv("DATABASE_URL"),
-			os.Getenv("LOGS_PATH"),
-			paginationDefaultLimit,
-			paginationMaxLimit,
-		)
-		if err := config.Validate(); err != nil {
-			logrus.Error(err)
+		cfg := loadConfig(cmd)
+		if err := cfg.Validate(); err != nil {
+			logrus.Fatalf("invalid config: %v", err)
 		}
 
-		logsFile, err := os.OpenFile(config.LogsPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0777)
+		logsOut, err := setupLogging(cfg)
 		if err != nil {
-			logrus.Error(err)
+			logrus.Fatalf("could not setup the logging: %v", err)
 		}
-		defer logsFile.Close()
+		defer logsOut.Close()
 
-		logsOut := io.MultiWriter(os.Stdout, logsFile)
+		stopTracing := setupTracing(cfg.Tracing)
+		defer stopTracing()
 
-		logrus.SetOutput(logsOut)
-		logrus.SetFormatter(&logrus.JSONFormatter{
-			FieldMap: logrus.FieldMap{
-				logrus.FieldKeyTime:  "timestamp",
-				logrus.FieldKeyLevel: "level",
-				logrus.FieldKeyFunc:  "caller",
-				logrus.FieldKeyMsg:   "message",
-			},
+		app := fiber.New(fiber.Config{
+			ReadTimeout:  cfg.HTTP.ReadTimeout,
+			WriteTimeout: cfg.HTTP.WriteTimeout,
+			IdleTimeout:  cfg.HTTP.IdleTimeout,
+			ProxyHeader:  cfg.HTTP.ProxyHeader,
+			// the uploads are the biggest bodies, the others are limited by security.Limits
+			BodyLimit: cfg.HTTP.MaxUploadSize,
+			// the routes not found and the errors returned by the handlers are answered as problems
+			ErrorHandler: response.ErrorHandler,
 		})
-		if config.Environment == server.ProductionEnvironment {
-			logrus.SetLevel(logrus.WarnLevel)
-		} else {
-			logrus.SetReportCaller(true)
-			logrus.SetLevel(logrus.DebugLevel)
+		// first, so the routes not found reach the ErrorHandler without being taken as matched by it
+		app.Use(response.NotFound)
+
+		db, err := openDatabase(cfg)
+		if err != nil {
+			logrus.Fatalf("could not connect to the database: %v", err)
+		}
+		defer db.Close()
+
+		migrator, latestVersion, err := newMigrator(db)
+		if err != nil {
+			logrus.Fatalf("could not load the migrations: %v", err)
+		}
+		if autoMigrate, _ := cmd.Flags().GetBool("auto-migrate"); autoMigrate {
+			// the migrator holds an advisory lock, so only one instance applies the migrations
+			applied, err := migrator.Up(0)
+			switch {
+			case errors.Is(err, migration.ErrUnknownVersion) && databaseAhead(migrator, latestVersion):
+				// an older instance started during a rolling deploy or a rollback, the readiness
+				// decides if it could serve
+				logrus.Warnf("the database is ahead of the migrations %d, none was applied", latestVersion)
+			case err != nil:
+				logrus.Fatalf("could not apply the migrations: %v", err)
+			default:
+				logrus.Infof("%d migrations applied", applied)
+			}
+		}
+
+		m := metrics.New()
+		if err := m.RegisterDB(db, "unico_challenge"); err != nil {
+			logrus.Errorf("could not export the database pool metrics: %v", err)
 		}
 
-		app := fiber.New()
+		// registered before the middlewares to keep the probes and the scrapes out of the access log and metrics
+		healthCtrl := healthController.New(db, migrator, latestVersion)
+		healthCtrl.Register(app)
+		m.Register(app)
 
-		app.Use(logger.New(
-			logger.Config{
-				Format:     `{"timestamp":"${time}", "method":"${method}", "path":"${path}", "query_params":"${queryParams}", "status":${status}, "latency":"${latency}", "pid":${pid}"}` + "\n",
-				TimeFormat: "2006-01-02T15:04:05Z07:00",
-				Output:     logsOut,
-			},
-		))
+		// reuses the X-Request-ID informed by the caller or generates a new one, echoing it in the response
+		app.Use(requestid.New(requestid.Config{ContextKey: server.RequestIDKey}))
+		app.Use(logging.Middleware(logrus.StandardLogger()))
+		app.Use(m.Middleware())
+		app.Use(tracing.Middleware())
+		// the access log has its own logger, so the requests are logged whatever the application log level
+		accessLogger := logrus.New()
+		accessLogger.SetOutput(logsOut)
+		accessLogger.SetFormatter(logrus.StandardLogger().Formatter)
+		app.Use(logging.AccessLog(accessLogger))
 
-		db, err := sql.Open("postgres", config.DatabaseURL)
+		var guard auth.Guard
+		if cfg.Auth.Enabled {
+			var authenticators []auth.Authenticator
+			if cfg.Auth.APIKeys {
+				authenticators = append(authenticators, apikeyService.New(apikeyRepository.NewInstrumentedRepository(
+					apikeyRepository.NewPostgresRepository(db),
+					m.ObserveRepository("apikey"),
+				)))
+			}
+			if cfg.Auth.JWT.Enabled {
+				jwtConfig, err := cfg.Auth.JWT.Config()
+				if err != nil {
+					logrus.Fatalf("could not parse the jwt config: %v", err)
+				}
+				jwtAuth, err := auth.NewJWTAuthenticator(jwtConfig)
+				if err != nil {
+					logrus.Fatalf("could not create the jwt authenticator: %v", err)
+				}
+				authenticators = append(authenticators, jwtAuth)
+			}
+			guard = auth.New(auth.Chain(authenticators...), cfg.Auth.PublicRead)
+		} else {
+			logrus.Warn("the authentication is disabled, every route is open")
+		}
+
+		app.Use(security.Headers(cfg.HTTP.HSTSMaxAge))
+		// before the rate limit, so the preflights do not spend the budgets and the 429 have the cors headers
+		if cfg.CORS.Enabled {
+			app.Use(security.CORS(cfg.CORS.Config()))
+		}
+		// the ip budget is taken before the credentials are looked up, so their floods do not reach
+		// the database, and refunded by the budget of the principal, so only the requests with a
+		// missing or invalid credential spend it
+		rateLimitStore := ratelimit.NewMemoryStore()
+		if cfg.RateLimit.Enabled {
+			app.Use(ratelimit.Middleware(rateLimitStore, cfg.RateLimit.IPConfig()))
+		}
+		app.Use(auth.Identify(guard))
+		if cfg.RateLimit.Enabled {
+			app.Use(ratelimit.Middleware(rateLimitStore, cfg.RateLimit.Config()))
+		}
+		app.Use(security.Limits(cfg.HTTP.Limits(routes.ImportsPath)))
+
+		openapi.Register(app)
+		validator, err := openapi.NewValidator(openapi.Spec)
 		if err != nil {
-			logrus.Error(err)
+			logrus.Fatalf("could not load the openapi document: %v", err)
 		}
-		defer db.Close()
 
-		feiralivreRepo := feiralivreRepository.NewPostgresRepository(db)
-		queryParamsParser := parser.NewQueryParamsParser(config.PaginationDefaultLimit, config.PaginationMaxLimit)
-		feiralivreCtrl := feiralivreController.New(feiralivreRepo, queryParamsParser)
-		feiralivreCtrl.Register(app, "/feiras-livres")
+		feiralivreRepo := feiralivreRepository.NewInstrumentedRepository(
+			feiralivreRepository.NewPostgresRepository(db),
+			m.ObserveRepository("feiralivre"),
+		)
+		queryParamsParser := parser.NewQueryParamsParser(cfg.Pagination.DefaultLimit, cfg.Pagination.MaxLimit)
+		var authorizer auth.Authorizer
+		var grantCtrl *grantController.Controller
+		if cfg.Auth.Enabled {
+			grantServ := grantService.New(grantRepository.NewInstrumentedRepository(
+				grantRepository.NewPostgresRepository(db),
+				m.ObserveRepository("grant"),
+			))
+			if cfg.Auth.RBAC {
+				authorizer = grantServ
+			}
+			grantCtrl = grantController.New(grantServ, guard, validator)
+		}
+
+		feiralivreServ := feiralivreService.New(afero.NewOsFs(), feiralivreRepo, feiralivreService.Options{
+			Workers:      cfg.ImportWorkers(),
+			MaxOpenConns: db.Stats().MaxOpenConnections,
+			Rows:         m.ObserveImportRows,
+		})
+		importjobRepo := importjobRepository.NewInstrumentedRepository(
+			importjobRepository.NewPostgresRepository(db),
+			m.ObserveRepository("importjob"),
+		)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		// the running import jobs are interrupted by the signal
+		importjobServ := importjobService.New(ctx, afero.NewOsFs(), cfg.ImportsPath, cfg.MaxImportJobs, importjobRepo, feiralivreServ)
+		// the sweep is repeated while the server runs, since the jobs of an instance that restarted
+		// before StaleAfter are only stale after this one started
+		interruptUnfinished(importjobServ)
+		go sweepUnfinished(ctx, importjobServ, importjobService.HeartbeatInterval)
+		routes.Register(app, routes.Controllers{
+			FeiraLivre: feiralivreController.New(feiralivreRepo, queryParamsParser, guard, authorizer, validator),
+			ImportJob:  importjobController.New(importjobServ, guard, authorizer, validator),
+			Grant:      grantCtrl,
+		}, func(prefix string) fiber.Handler {
+			return deprecation.Middleware(cfg.API.V1(prefix))
+		})
+
+		listenErr := make(chan error, 1)
+		go func() {
+			listenErr <- app.Listen(":" + cfg.Port)
+		}()
+
+		select {
+		case err := <-listenErr:
+			if err != nil {
+				logrus.Error(err)
+			}
+			return
+		case <-ctx.Done():
+		}
+		// a second signal stops the process immediately
+		stop()
+
+		logrus.Info("shutting down the server")
+		healthCtrl.ShuttingDown()
+
+		if err := shutdown(app, cfg.HTTP.ShutdownTimeout); err != nil {
+			logrus.Errorf("could not drain the in-flight requests: %v", err)
+		}
 
-		if err := app.Listen(":" + config.Port); err != nil {
-			logrus.Error(err)
+		// the import jobs must record that were interrupted before the database is closed
+		waitCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+		defer cancel()
+		if err := importjobServ.Wait(waitCtx); err != nil {
+			logrus.Errorf("could not wait the import jobs: %v", err)
 		}
+
+		logrus.Info("server stopped")
 	},
 }
 
+// shutdown stops accepting connections and waits the in-flight requests until timeout
+func shutdown(app *fiber.App, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Shutdown()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+}
+
+// interruptUnfinished marks the import jobs left by a stopped instance as interrupted
+func interruptUnfinished(serv importjobService.Service) {
+	if n, err := serv.InterruptUnfinished(); err != nil {
+		logrus.Errorf("could not interrupt the unfinished import jobs: %v", err)
+	} else if n > 0 {
+		logrus.Warnf("%d import jobs left by a stopped instance were interrupted", n)
+	}
+}
+
+// sweepUnfinished interrupts the import jobs left by a stopped instance every interval until ctx is done
+func sweepUnfinished(ctx context.Context, serv importjobService.Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			interruptUnfinished(serv)
+		}
+	}
+}
+
 func init() {
+	serveCmd.Flags().Bool("auto-migrate", false, "Applies the pending migrations before starting the server.")
+	serveCmd.Flags().Duration("shutdown-timeout", 10*time.Second, "Maximum time waiting the in-flight requests and then the import jobs after a SIGINT or SIGTERM, overrides http.shutdown_timeout.")
+	serveCmd.Flags().StringP("port", "p", "", "Port listened by the server, overrides port.")
+	serveCmd.Flags().String("log-level", "", "Log level (debug, info, warn or error), overrides log.level.")
+	serveCmd.Flags().String("log-format", "", "Log format (json or console), overrides log.format.")
+
 	rootCmd.AddCommand(serveCmd)
 }
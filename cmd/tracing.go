@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/bgildson/unico-challenge/config"
+	"github.com/bgildson/unico-challenge/server/tracing"
+)
+
+// tracingShutdownTimeout is the maximum time waiting the pending spans to be exported
+const tracingShutdownTimeout = 5 * time.Second
+
+// setupTracing installs the tracer configured by the tracing exporter (none, otlp or file) and
+// file, the returned func flushes the pending spans
+func setupTracing(cfg config.Tracing) func() {
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{
+		ServiceName: "unico-challenge",
+		Exporter:    cfg.Exporter,
+		FilePath:    cfg.File,
+	})
+	if err != nil {
+		logrus.Errorf("could not setup the tracing: %v", err)
+		return func() {}
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			logrus.Errorf("could not export the pending spans: %v", err)
+		}
+	}
+}
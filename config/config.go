@@ -0,0 +1,426 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bgildson/unico-challenge/server"
+	"github.com/bgildson/unico-challenge/server/auth"
+	"github.com/bgildson/unico-challenge/server/deprecation"
+	"github.com/bgildson/unico-challenge/server/logging"
+	"github.com/bgildson/unico-challenge/server/ratelimit"
+	"github.com/bgildson/unico-challenge/server/security"
+	"github.com/bgildson/unico-challenge/server/tracing"
+)
+
+var (
+	// ErrDatabaseConfigIsInvalid is used to represent an error in the Database config
+	ErrDatabaseConfigIsInvalid = errors.New("the Database config is invalid")
+	// ErrHTTPConfigIsInvalid is used to represent an error in the HTTP config
+	ErrHTTPConfigIsInvalid = errors.New("the HTTP config is invalid")
+	// ErrLogConfigIsInvalid is used to represent an error in the Log config
+	ErrLogConfigIsInvalid = errors.New("the Log config is invalid")
+	// ErrTracingConfigIsInvalid is used to represent an error in the Tracing config
+	ErrTracingConfigIsInvalid = errors.New("the Tracing config is invalid")
+	// ErrAuthConfigIsInvalid is used to represent an error in the Auth config
+	ErrAuthConfigIsInvalid = errors.New("the Auth config is invalid")
+	// ErrRateLimitConfigIsInvalid is used to represent an error in the RateLimit config
+	ErrRateLimitConfigIsInvalid = errors.New("the RateLimit config is invalid")
+	// ErrCORSConfigIsInvalid is used to represent an error in the CORS config
+	ErrCORSConfigIsInvalid = errors.New("the CORS config is invalid")
+	// ErrAPIConfigIsInvalid is used to represent an error in the API config
+	ErrAPIConfigIsInvalid = errors.New("the API config is invalid")
+	// ErrImportsConfigIsInvalid is used to represent an error in the imports config
+	ErrImportsConfigIsInvalid = errors.New("the imports config is invalid")
+)
+
+// Config contains every setting of the application, the keys are the yaml tags joined by dots
+// and the envs are the keys in upper case with the dots replaced by underscores
+type Config struct {
+	Environment string `yaml:"environment"`
+	Port        string `yaml:"port"`
+	DatabaseURL string `yaml:"database_url" secret:"true"`
+	LogsPath    string `yaml:"logs_path"`
+	ImportsPath string `yaml:"imports_path"`
+	// MaxImportJobs is the number of import jobs running at the same time in each instance, the
+	// others wait as pending
+	MaxImportJobs int        `yaml:"max_import_jobs"`
+	Pagination    Pagination `yaml:"pagination"`
+	Database      Database   `yaml:"database"`
+	HTTP          HTTP       `yaml:"http"`
+	Log           Log        `yaml:"log"`
+	Tracing       Tracing    `yaml:"tracing"`
+	Auth          Auth       `yaml:"auth"`
+	RateLimit     RateLimit  `yaml:"rate_limit"`
+	CORS          CORS       `yaml:"cors"`
+	API           API        `yaml:"api"`
+}
+
+// Pagination contains the limits used when the query params do not inform them
+type Pagination struct {
+	DefaultLimit int `yaml:"default_limit"`
+	MaxLimit     int `yaml:"max_limit"`
+}
+
+// Database contains the connection pool settings
+type Database struct {
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+// HTTP contains the server timeouts, zero means no timeout, how to find the client ip and the
+// limits of the requests
+type HTTP struct {
+	ReadTimeout     time.Duration `yaml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// ProxyHeader is the header with the client ip set by the proxy (X-Forwarded-For), empty
+	// when the requests are received directly
+	ProxyHeader string `yaml:"proxy_header"`
+	// MaxBodySize is the maximum size in bytes of the bodies and MaxUploadSize of the uploads
+	MaxBodySize   int `yaml:"max_body_size"`
+	MaxUploadSize int `yaml:"max_upload_size"`
+	MaxJSONDepth  int `yaml:"max_json_depth"`
+	// HSTSMaxAge is sent in the Strict-Transport-Security header, zero does not send it
+	HSTSMaxAge time.Duration `yaml:"hsts_max_age"`
+}
+
+// Limits returns the config used by the request limits middleware, the uploads to uploadPaths
+// are only limited by MaxUploadSize
+func (h HTTP) Limits(uploadPaths ...string) security.LimitsConfig {
+	return security.LimitsConfig{
+		MaxBodySize:  h.MaxBodySize,
+		MaxJSONDepth: h.MaxJSONDepth,
+		UploadPaths:  uploadPaths,
+	}
+}
+
+// CORS contains the origins allowed to call the api from a browser, the lists are comma separated
+type CORS struct {
+	Enabled          bool          `yaml:"enabled"`
+	AllowOrigins     string        `yaml:"allow_origins"`
+	AllowMethods     string        `yaml:"allow_methods"`
+	AllowHeaders     string        `yaml:"allow_headers"`
+	ExposeHeaders    string        `yaml:"expose_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+// Config returns the config used by the cors middleware
+func (c CORS) Config() security.CORSConfig {
+	return security.CORSConfig{
+		AllowOrigins:     security.ParseList(c.AllowOrigins),
+		AllowMethods:     security.ParseList(c.AllowMethods),
+		AllowHeaders:     security.ParseList(c.AllowHeaders),
+		ExposeHeaders:    security.ParseList(c.ExposeHeaders),
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           c.MaxAge,
+	}
+}
+
+// Validate checks the cors settings when it is enabled
+func (c CORS) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	origins := security.ParseList(c.AllowOrigins)
+	if len(origins) == 0 {
+		return errors.New("the cors requires the allow_origins")
+	}
+	for _, origin := range origins {
+		if origin == "*" && c.AllowCredentials {
+			return errors.New("the cors could not allow the credentials of every origin")
+		}
+	}
+	if c.MaxAge < 0 {
+		return errors.New("the cors max_age could not be negative")
+	}
+	return nil
+}
+
+// DateLayout is the layout of the dates of the config
+const DateLayout = "2006-01-02"
+
+// API contains the lifecycle of the versions of the api, the dates are formatted as DateLayout, the
+// routes of the v1 are always answered as deprecated since V1Deprecation, which is required, and
+// with the Sunset header only when V1Sunset is informed
+type API struct {
+	V1Deprecation string `yaml:"v1_deprecation"`
+	V1Sunset      string `yaml:"v1_sunset"`
+}
+
+// V1 returns the deprecation of the v1, prefix is the prefix of its routes, replaced by /v2 in the
+// link to the successor
+func (a API) V1(prefix string) deprecation.Config {
+	// the dates are checked by Validate, an empty sunset is parsed as the zero time
+	deprecatedAt, _ := time.Parse(DateLayout, a.V1Deprecation)
+	sunsetAt, _ := time.Parse(DateLayout, a.V1Sunset)
+	return deprecation.Config{
+		Deprecation: deprecatedAt,
+		Sunset:      sunsetAt,
+		Prefix:      prefix,
+		Successor:   "/v2",
+	}
+}
+
+// Validate checks the dates of the versions
+func (a API) Validate() error {
+	deprecatedAt, err := time.Parse(DateLayout, a.V1Deprecation)
+	if err != nil {
+		return fmt.Errorf("the v1_deprecation is required and formatted as %s", DateLayout)
+	}
+	var sunsetAt time.Time
+	if a.V1Sunset != "" {
+		if sunsetAt, err = time.Parse(DateLayout, a.V1Sunset); err != nil {
+			return fmt.Errorf("the v1_sunset is not formatted as %s", DateLayout)
+		}
+	}
+	if !sunsetAt.IsZero() && !sunsetAt.After(deprecatedAt) {
+		return errors.New("the v1_sunset must be after the v1_deprecation")
+	}
+	return nil
+}
+
+// Log contains the logging settings, Output is a comma separated list of outputs
+type Log struct {
+	Level         string `yaml:"level"`
+	Format        string `yaml:"format"`
+	Output        string `yaml:"output"`
+	MaxSize       int    `yaml:"max_size"`
+	MaxAge        int    `yaml:"max_age"`
+	MaxBackups    int    `yaml:"max_backups"`
+	Compress      bool   `yaml:"compress"`
+	SyslogNetwork string `yaml:"syslog_network"`
+	SyslogAddress string `yaml:"syslog_address"`
+}
+
+// Tracing contains the tracing settings
+type Tracing struct {
+	Exporter string `yaml:"exporter"`
+	File     string `yaml:"file"`
+}
+
+// Auth contains the authentication settings, when PublicRead is true the GET routes do not
+// require a credential and when RBAC is true the changes are restricted to the areas granted
+type Auth struct {
+	Enabled    bool `yaml:"enabled"`
+	PublicRead bool `yaml:"public_read"`
+	APIKeys    bool `yaml:"api_keys"`
+	RBAC       bool `yaml:"rbac"`
+	JWT        JWT  `yaml:"jwt"`
+}
+
+// JWT contains the settings used to accept the bearer tokens issued by the SSO, Roles maps the
+// token roles to scopes and is formatted as role=scope,scope;role=scope
+type JWT struct {
+	Enabled       bool          `yaml:"enabled"`
+	Issuer        string        `yaml:"issuer"`
+	Audience      string        `yaml:"audience"`
+	JWKSFile      string        `yaml:"jwks_file"`
+	PublicKeyFile string        `yaml:"public_key_file"`
+	HMACSecret    string        `yaml:"hmac_secret" secret:"true"`
+	RolesClaim    string        `yaml:"roles_claim"`
+	Roles         string        `yaml:"roles"`
+	Leeway        time.Duration `yaml:"leeway"`
+}
+
+// Config returns the config used by the jwt authenticator
+func (j JWT) Config() (auth.JWTConfig, error) {
+	roles, err := auth.ParseRoles(j.Roles)
+	if err != nil {
+		return auth.JWTConfig{}, err
+	}
+	return auth.JWTConfig{
+		Issuer:        j.Issuer,
+		Audience:      j.Audience,
+		JWKSFile:      j.JWKSFile,
+		PublicKeyFile: j.PublicKeyFile,
+		HMACSecret:    j.HMACSecret,
+		RolesClaim:    j.RolesClaim,
+		Roles:         roles,
+		Leeway:        j.Leeway,
+	}, nil
+}
+
+// Server returns the config validated by the server package
+func (c Config) Server() server.Config {
+	return server.NewConfig(
+		c.Environment,
+		c.Port,
+		c.DatabaseURL,
+		c.LogsPath,
+		c.Pagination.DefaultLimit,
+		c.Pagination.MaxLimit,
+	)
+}
+
+// Logging returns the config used by the logging package
+func (c Config) Logging() logging.Config {
+	return logging.Config{
+		Level:   c.Log.Level,
+		Format:  c.Log.Format,
+		Outputs: logging.ParseOutputs(c.Log.Output),
+		File: logging.FileConfig{
+			Path:       c.LogsPath,
+			MaxSize:    c.Log.MaxSize,
+			MaxAge:     c.Log.MaxAge,
+			MaxBackups: c.Log.MaxBackups,
+			Compress:   c.Log.Compress,
+		},
+		Syslog: logging.SyslogConfig{
+			Network: c.Log.SyslogNetwork,
+			Address: c.Log.SyslogAddress,
+			Tag:     "unico-challenge",
+		},
+	}
+}
+
+// ImportWorkers is the number of workers of each import job, the jobs running together use at most
+// half of the database pool so the other routes keep their connections, zero uses the default
+func (c Config) ImportWorkers() int {
+	if c.Database.MaxOpenConns <= 0 || c.MaxImportJobs <= 0 {
+		return 0
+	}
+
+	if workers := c.Database.MaxOpenConns / 2 / c.MaxImportJobs; workers > 0 {
+		return workers
+	}
+
+	return 1
+}
+
+// Validate applies the validation for every field used by the server
+func (c Config) Validate() error {
+	if err := c.Server().Validate(); err != nil {
+		return err
+	}
+
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 ||
+		c.Database.ConnMaxLifetime < 0 || c.Database.ConnMaxIdleTime < 0 {
+		return fmt.Errorf("%w: the values could not be negative", ErrDatabaseConfigIsInvalid)
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		return fmt.Errorf("%w: max_idle_conns is greater than max_open_conns", ErrDatabaseConfigIsInvalid)
+	}
+
+	if c.MaxImportJobs <= 0 {
+		return fmt.Errorf("%w: max_import_jobs must be positive", ErrImportsConfigIsInvalid)
+	}
+
+	if c.HTTP.ReadTimeout < 0 || c.HTTP.WriteTimeout < 0 || c.HTTP.IdleTimeout < 0 || c.HTTP.ShutdownTimeout <= 0 {
+		return fmt.Errorf("%w: the timeouts could not be negative and shutdown_timeout must be positive", ErrHTTPConfigIsInvalid)
+	}
+	if c.HTTP.MaxBodySize <= 0 || c.HTTP.MaxJSONDepth <= 0 || c.HTTP.MaxUploadSize < c.HTTP.MaxBodySize {
+		return fmt.Errorf("%w: the limits must be positive and max_upload_size at least max_body_size", ErrHTTPConfigIsInvalid)
+	}
+	if c.HTTP.HSTSMaxAge < 0 {
+		return fmt.Errorf("%w: hsts_max_age could not be negative", ErrHTTPConfigIsInvalid)
+	}
+
+	if _, err := logging.ParseLevel(c.Log.Level, 0); err != nil {
+		return fmt.Errorf("%w: %v", ErrLogConfigIsInvalid, err)
+	}
+	if _, err := logging.NewFormatter(c.Log.Format); err != nil {
+		return fmt.Errorf("%w: %v", ErrLogConfigIsInvalid, err)
+	}
+	for _, output := range logging.ParseOutputs(c.Log.Output) {
+		switch output {
+		case logging.OutputStdout, logging.OutputFile, logging.OutputSyslog:
+		default:
+			return fmt.Errorf("%w: %v: %s", ErrLogConfigIsInvalid, logging.ErrUnknownOutput, output)
+		}
+	}
+
+	if c.Auth.Enabled {
+		if !c.Auth.APIKeys && !c.Auth.JWT.Enabled {
+			return fmt.Errorf("%w: enable the api keys or the jwt", ErrAuthConfigIsInvalid)
+		}
+		if err := c.Auth.JWT.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrAuthConfigIsInvalid, err)
+		}
+	}
+
+	if c.RateLimit.Enabled && (c.RateLimit.ReadPerMinute <= 0 || c.RateLimit.ReadBurst <= 0 ||
+		c.RateLimit.WritePerMinute <= 0 || c.RateLimit.WriteBurst <= 0 ||
+		c.RateLimit.IPPerMinute <= 0 || c.RateLimit.IPBurst <= 0) {
+		return fmt.Errorf("%w: the budgets must be positive", ErrRateLimitConfigIsInvalid)
+	}
+
+	if err := c.CORS.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrCORSConfigIsInvalid, err)
+	}
+
+	if err := c.API.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrAPIConfigIsInvalid, err)
+	}
+
+	switch strings.ToLower(c.Tracing.Exporter) {
+	case "", tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterFile:
+	default:
+		return fmt.Errorf("%w: %v: %s", ErrTracingConfigIsInvalid, tracing.ErrUnknownExporter, c.Tracing.Exporter)
+	}
+
+	return nil
+}
+
+// RateLimit contains the budgets of each principal, the read budget is used by the GET routes and the
+// write budget by the others, every budget allows Burst requests at once refilled PerMinute, the ip
+// budget is taken before the credential is looked up and refunded when it is valid, so it is only
+// spent by the requests without a principal
+type RateLimit struct {
+	Enabled        bool `yaml:"enabled"`
+	ReadPerMinute  int  `yaml:"read_per_minute"`
+	ReadBurst      int  `yaml:"read_burst"`
+	WritePerMinute int  `yaml:"write_per_minute"`
+	WriteBurst     int  `yaml:"write_burst"`
+	IPPerMinute    int  `yaml:"ip_per_minute"`
+	IPBurst        int  `yaml:"ip_burst"`
+}
+
+// Config returns the config used by the rate limit middleware
+func (r RateLimit) Config() ratelimit.Config {
+	return ratelimit.Config{
+		Read:  ratelimit.Limit{PerMinute: r.ReadPerMinute, Burst: r.ReadBurst},
+		Write: ratelimit.Limit{PerMinute: r.WritePerMinute, Burst: r.WriteBurst},
+		Key:   ratelimit.PrincipalKey,
+	}
+}
+
+// IPConfig returns the config used by the rate limit middleware before the authentication
+func (r RateLimit) IPConfig() ratelimit.Config {
+	limit := ratelimit.Limit{PerMinute: r.IPPerMinute, Burst: r.IPBurst}
+	return ratelimit.Config{Read: limit, Write: limit, Key: ratelimit.IPKey, Prefix: "identify:"}
+}
+
+// Validate checks the jwt settings when it is enabled
+func (j JWT) Validate() error {
+	if !j.Enabled {
+		return nil
+	}
+	if j.JWKSFile == "" && j.PublicKeyFile == "" && j.HMACSecret == "" {
+		return errors.New("the jwt requires a jwks_file, a public_key_file or a hmac_secret")
+	}
+	if j.Issuer == "" || j.Audience == "" {
+		return errors.New("the jwt requires the issuer and the audience")
+	}
+	if j.Leeway < 0 {
+		return errors.New("the jwt leeway could not be negative")
+	}
+	if _, err := auth.ParseRoles(j.Roles); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ValidateDatabaseURL applies the validation used by the commands that only connect to the database
+func (c Config) ValidateDatabaseURL() error {
+	if c.DatabaseURL == "" {
+		return server.ErrDatabaseURLConfigIsInvalid
+	}
+	return nil
+}
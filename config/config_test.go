@@ -0,0 +1,139 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bgildson/unico-challenge/server"
+	"github.com/bgildson/unico-challenge/server/deprecation"
+)
+
+func TestConfigValidate(t *testing.T) {
+	valid := Defaults()
+	valid.DatabaseURL = "postgres://db"
+
+	testCases := []struct {
+		name  string
+		setup func(c *Config)
+		out   error
+	}{
+		{name: "when config is valid", setup: func(c *Config) {}},
+		{name: "when DatabaseURL is empty", setup: func(c *Config) { c.DatabaseURL = "" }, out: server.ErrDatabaseURLConfigIsInvalid},
+		{name: "when pool is negative", setup: func(c *Config) { c.Database.MaxOpenConns = -1 }, out: ErrDatabaseConfigIsInvalid},
+		{name: "when idle conns exceed open conns", setup: func(c *Config) { c.Database.MaxOpenConns = 2; c.Database.MaxIdleConns = 3 }, out: ErrDatabaseConfigIsInvalid},
+		{name: "when max import jobs is zero", setup: func(c *Config) { c.MaxImportJobs = 0 }, out: ErrImportsConfigIsInvalid},
+		{name: "when shutdown timeout is zero", setup: func(c *Config) { c.HTTP.ShutdownTimeout = 0 }, out: ErrHTTPConfigIsInvalid},
+		{name: "when body size is zero", setup: func(c *Config) { c.HTTP.MaxBodySize = 0 }, out: ErrHTTPConfigIsInvalid},
+		{name: "when upload size is smaller than body size", setup: func(c *Config) { c.HTTP.MaxUploadSize = c.HTTP.MaxBodySize - 1 }, out: ErrHTTPConfigIsInvalid},
+		{name: "when json depth is zero", setup: func(c *Config) { c.HTTP.MaxJSONDepth = 0 }, out: ErrHTTPConfigIsInvalid},
+		{name: "when log level is invalid", setup: func(c *Config) { c.Log.Level = "loud" }, out: ErrLogConfigIsInvalid},
+		{name: "when log format is invalid", setup: func(c *Config) { c.Log.Format = "xml" }, out: ErrLogConfigIsInvalid},
+		{name: "when log output is invalid", setup: func(c *Config) { c.Log.Output = "stdout,kafka" }, out: ErrLogConfigIsInvalid},
+		{name: "when tracing exporter is invalid", setup: func(c *Config) { c.Tracing.Exporter = "zipkin" }, out: ErrTracingConfigIsInvalid},
+		{name: "when rate limit budget is zero", setup: func(c *Config) { c.RateLimit.WriteBurst = 0 }, out: ErrRateLimitConfigIsInvalid},
+		{name: "when rate limit is disabled", setup: func(c *Config) { c.RateLimit = RateLimit{} }},
+		{name: "when cors has no origin", setup: func(c *Config) { c.CORS.Enabled = true }, out: ErrCORSConfigIsInvalid},
+		{name: "when cors allows the credentials of every origin", setup: func(c *Config) {
+			c.CORS = CORS{Enabled: true, AllowOrigins: "*", AllowCredentials: true}
+		}, out: ErrCORSConfigIsInvalid},
+		{name: "when cors is valid", setup: func(c *Config) { c.CORS.Enabled = true; c.CORS.AllowOrigins = "https://app.example.com" }},
+		{name: "when v1 deprecation is empty", setup: func(c *Config) { c.API.V1Deprecation = "" }, out: ErrAPIConfigIsInvalid},
+		{name: "when v1 sunset is not a date", setup: func(c *Config) { c.API.V1Sunset = "18/04/2027" }, out: ErrAPIConfigIsInvalid},
+		{name: "when v1 sunset is before the deprecation", setup: func(c *Config) {
+			c.API = API{V1Deprecation: "2026-10-18", V1Sunset: "2026-01-01"}
+		}, out: ErrAPIConfigIsInvalid},
+		{name: "when v1 has no sunset", setup: func(c *Config) { c.API = API{V1Deprecation: "2026-10-18"} }},
+		{name: "when v1 is deprecated", setup: func(c *Config) { c.API = API{V1Deprecation: "2026-10-18", V1Sunset: "2027-04-18"} }},
+		{name: "when no authenticator is enabled", setup: func(c *Config) { c.Auth.Enabled = true; c.Auth.APIKeys = false }, out: ErrAuthConfigIsInvalid},
+		{name: "when auth is disabled", setup: func(c *Config) { c.Auth.Enabled = false; c.Auth.APIKeys = false }},
+		{name: "when jwt has no key", setup: func(c *Config) {
+			c.Auth.Enabled = true
+			c.Auth.JWT = JWT{Enabled: true, Issuer: "sso", Audience: "feiras"}
+		}, out: ErrAuthConfigIsInvalid},
+		{name: "when jwt has no audience", setup: func(c *Config) {
+			c.Auth.Enabled = true
+			c.Auth.JWT = JWT{Enabled: true, Issuer: "sso", HMACSecret: "secret"}
+		}, out: ErrAuthConfigIsInvalid},
+		{name: "when jwt roles are invalid", setup: func(c *Config) {
+			c.Auth.Enabled = true
+			c.Auth.JWT = JWT{Enabled: true, Issuer: "sso", Audience: "feiras", HMACSecret: "secret", Roles: "editor=delete"}
+		}, out: ErrAuthConfigIsInvalid},
+		{name: "when jwt is valid", setup: func(c *Config) {
+			c.Auth.Enabled = true
+			c.Auth.JWT = JWT{Enabled: true, Issuer: "sso", Audience: "feiras", HMACSecret: "secret", Roles: "editor=read,write"}
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := valid
+			tc.setup(&c)
+			if err := c.Validate(); !errors.Is(err, tc.out) {
+				t.Errorf("was expecting %v, but returns %v", tc.out, err)
+			}
+		})
+	}
+}
+
+func TestAPIV1(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   API
+		out  deprecation.Config
+	}{
+		{
+			name: "when only the deprecation is informed",
+			in:   Defaults().API,
+			out: deprecation.Config{
+				Deprecation: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+				Prefix:      "/v1",
+				Successor:   "/v2",
+			},
+		},
+		{
+			name: "when the dates are informed",
+			in:   API{V1Deprecation: "2026-10-18", V1Sunset: "2027-04-18"},
+			out: deprecation.Config{
+				Deprecation: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+				Sunset:      time.Date(2027, 4, 18, 0, 0, 0, 0, time.UTC),
+				Prefix:      "/v1",
+				Successor:   "/v2",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if out := tc.in.V1("/v1"); !reflect.DeepEqual(out, tc.out) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, out)
+			}
+		})
+	}
+}
+
+func TestConfigImportWorkers(t *testing.T) {
+	testCases := []struct {
+		name         string
+		inOpenConns  int
+		inImportJobs int
+		out          int
+	}{
+		{name: "when the pool is unlimited", inOpenConns: 0, inImportJobs: 2, out: 0},
+		{name: "when the jobs share half of the pool", inOpenConns: 20, inImportJobs: 2, out: 5},
+		{name: "when the pool is smaller than the jobs", inOpenConns: 2, inImportJobs: 4, out: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := Defaults()
+			c.Database.MaxOpenConns = tc.inOpenConns
+			c.MaxImportJobs = tc.inImportJobs
+
+			if out := c.ImportWorkers(); out != tc.out {
+				t.Errorf("was expecting %d, but returns %d", tc.out, out)
+			}
+		})
+	}
+}
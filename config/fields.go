@@ -0,0 +1,72 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the secrets in the printed values
+const Redacted = "xxxxx"
+
+// Field is a config value with its key and env
+type Field struct {
+	Key    string
+	Env    string
+	Secret bool
+	value  interface{}
+}
+
+// Value returns the field value as text, with the secrets redacted
+func (f Field) Value() string {
+	s := fmt.Sprint(f.value)
+	if f.Secret && s != "" {
+		return redact(s)
+	}
+	return s
+}
+
+// Fields lists the config values ordered as declared in the Config struct
+func Fields(c Config) []Field {
+	return fields(reflect.ValueOf(c), "")
+}
+
+func fields(v reflect.Value, prefix string) []Field {
+	var fs []Field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := prefix + sf.Tag.Get("yaml")
+		if sf.Type.Kind() == reflect.Struct {
+			fs = append(fs, fields(v.Field(i), key+".")...)
+			continue
+		}
+		fs = append(fs, Field{
+			Key:    key,
+			Env:    strings.ToUpper(strings.ReplaceAll(key, ".", "_")),
+			Secret: sf.Tag.Get("secret") == "true",
+			value:  v.Field(i).Interface(),
+		})
+	}
+	return fs
+}
+
+// dsnPassword matches the password of the key=value connection strings and of the url query
+var dsnPassword = regexp.MustCompile(`(password=)('[^']*'|[^&\s]*)`)
+
+// redact hides the password of the urls and of the key=value connection strings, any other
+// secret is entirely replaced
+func redact(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Scheme != "" && u.Host != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), Redacted)
+		}
+		return dsnPassword.ReplaceAllString(u.String(), "${1}"+Redacted)
+	}
+	if dsnPassword.MatchString(s) {
+		return dsnPassword.ReplaceAllString(s, "${1}"+Redacted)
+	}
+	return Redacted
+}
@@ -0,0 +1,69 @@
+package config
+
+import "testing"
+
+func TestFieldValue(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   Field
+		out  string
+	}{
+		{
+			name: "when the field is not a secret",
+			in:   Field{Key: "port", value: "8080"},
+			out:  "8080",
+		},
+		{
+			name: "when the secret is an url with password",
+			in:   Field{Key: "database_url", Secret: true, value: "postgres://user:pass@db:5432/app?sslmode=disable"},
+			out:  "postgres://user:xxxxx@db:5432/app?sslmode=disable",
+		},
+		{
+			name: "when the secret is an url with password in the query",
+			in:   Field{Key: "database_url", Secret: true, value: "postgres://db/app?password=pass&sslmode=disable"},
+			out:  "postgres://db/app?password=xxxxx&sslmode=disable",
+		},
+		{
+			name: "when the secret is a key value connection string",
+			in:   Field{Key: "database_url", Secret: true, value: "host=db user=user password='p a' dbname=app"},
+			out:  "host=db user=user password=xxxxx dbname=app",
+		},
+		{
+			name: "when the secret is not an url",
+			in:   Field{Key: "token", Secret: true, value: "abc"},
+			out:  Redacted,
+		},
+		{
+			name: "when the secret is empty",
+			in:   Field{Key: "token", Secret: true, value: ""},
+			out:  "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if out := tc.in.Value(); out != tc.out {
+				t.Errorf("was expecting %v, but returns %v", tc.out, out)
+			}
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	fs := Fields(Defaults())
+
+	found := map[string]Field{}
+	for _, f := range fs {
+		found[f.Key] = f
+	}
+
+	if f := found["pagination.default_limit"]; f.Env != "PAGINATION_DEFAULT_LIMIT" || f.Value() != "10" {
+		t.Errorf("was expecting PAGINATION_DEFAULT_LIMIT=10, but returns %s=%s", f.Env, f.Value())
+	}
+	if f := found["http.shutdown_timeout"]; f.Value() != "10s" {
+		t.Errorf("was expecting %v, but returns %v", "10s", f.Value())
+	}
+	if f := found["database_url"]; !f.Secret {
+		t.Errorf("was expecting database_url to be a secret")
+	}
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/bgildson/unico-challenge/server"
+)
+
+// FileEnv is the env used to inform the config file when the --config flag is not used
+const FileEnv = "CONFIG_FILE"
+
+// FlagKeys maps the command flags to the config keys they override
+var FlagKeys = map[string]string{
+	"dsn":              "database_url",
+	"port":             "port",
+	"max-open-conns":   "database.max_open_conns",
+	"shutdown-timeout": "http.shutdown_timeout",
+	"log-level":        "log.level",
+	"log-format":       "log.format",
+}
+
+// Defaults returns the values used when a key is not informed
+func Defaults() Config {
+	return Config{
+		Environment:   server.DevelopmentEnvironment,
+		Port:          "8080",
+		LogsPath:      "logs.txt",
+		ImportsPath:   os.TempDir(),
+		MaxImportJobs: 2,
+		Pagination: Pagination{
+			DefaultLimit: 10,
+			MaxLimit:     50,
+		},
+		Database: Database{
+			MaxOpenConns:    20,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		HTTP: HTTP{
+			ReadTimeout:     time.Minute,
+			WriteTimeout:    time.Minute,
+			IdleTimeout:     2 * time.Minute,
+			ShutdownTimeout: 10 * time.Second,
+			MaxBodySize:     1 << 20,
+			MaxUploadSize:   100 << 20,
+			MaxJSONDepth:    32,
+		},
+		Log: Log{
+			Format:     "json",
+			Output:     "stdout,file",
+			MaxSize:    100,
+			MaxAge:     7,
+			MaxBackups: 10,
+			Compress:   true,
+		},
+		Tracing: Tracing{
+			Exporter: "none",
+			File:     "traces.json",
+		},
+		// disabled by default, so the deployments older than the authentication keep their routes open
+		// until the operators create the keys and enable it
+		Auth: Auth{
+			Enabled:    false,
+			PublicRead: true,
+			APIKeys:    true,
+			JWT: JWT{
+				RolesClaim: "roles",
+				Leeway:     30 * time.Second,
+			},
+		},
+		RateLimit: RateLimit{
+			Enabled:        true,
+			ReadPerMinute:  600,
+			ReadBurst:      100,
+			WritePerMinute: 60,
+			WriteBurst:     20,
+			IPPerMinute:    1200,
+			IPBurst:        200,
+		},
+		CORS: CORS{
+			AllowMethods:  "GET,POST,PUT,DELETE,HEAD",
+			AllowHeaders:  "Authorization,Content-Type,X-API-Key,X-Request-ID",
+			ExposeHeaders: "Location,X-Request-ID,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After,Deprecation,Sunset,Link",
+			MaxAge:        10 * time.Minute,
+		},
+		API: API{
+			// when the v2 was released
+			V1Deprecation: "2026-10-18",
+		},
+	}
+}
+
+// Load merges the config sources, each one overriding the previous: the defaults, the YAML or
+// TOML file informed by path, the envs and the flags changed in the command line
+func Load(path string, flags *pflag.FlagSet) (Config, error) {
+	v := viper.New()
+
+	for _, f := range Fields(Defaults()) {
+		v.SetDefault(f.Key, f.value)
+	}
+
+	if path != "" {
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return Config{}, fmt.Errorf("could not read the config file: %v", err)
+		}
+	}
+
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	if flags != nil {
+		for name, key := range FlagKeys {
+			if flag := flags.Lookup(name); flag != nil {
+				if err := v.BindPFlag(key, flag); err != nil {
+					return Config{}, fmt.Errorf("could not bind the flag %s: %v", name, err)
+				}
+			}
+		}
+	}
+
+	var config Config
+	err := v.Unmarshal(&config, func(dc *mapstructure.DecoderConfig) {
+		dc.TagName = "yaml"
+	})
+	if err != nil {
+		return Config{}, fmt.Errorf("could not parse the config: %v", err)
+	}
+
+	return config, nil
+}
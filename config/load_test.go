@@ -0,0 +1,123 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "config.yaml")
+	ioutil.WriteFile(yamlPath, []byte("port: \"9000\"\ndatabase_url: postgres://file\nlog:\n  level: info\n  format: console\nhttp:\n  read_timeout: 5s\n"), 0600)
+	tomlPath := filepath.Join(dir, "config.toml")
+	ioutil.WriteFile(tomlPath, []byte("port = \"9001\"\n[pagination]\nmax_limit = 100\n"), 0600)
+
+	testCases := []struct {
+		name     string
+		path     string
+		env      map[string]string
+		args     []string
+		check    func(t *testing.T, c Config)
+		hasError bool
+	}{
+		{
+			name: "when nothing is informed",
+			check: func(t *testing.T, c Config) {
+				if c.Port != Defaults().Port || c.HTTP.ShutdownTimeout != 10*time.Second {
+					t.Errorf("was expecting the defaults, but returns %+v", c)
+				}
+			},
+		},
+		{
+			name: "when a yaml file is informed",
+			path: yamlPath,
+			check: func(t *testing.T, c Config) {
+				if c.Port != "9000" || c.DatabaseURL != "postgres://file" || c.Log.Level != "info" || c.HTTP.ReadTimeout != 5*time.Second {
+					t.Errorf("was expecting the file values, but returns %+v", c)
+				}
+				if c.Log.MaxSize != Defaults().Log.MaxSize {
+					t.Errorf("was expecting %v, but returns %v", Defaults().Log.MaxSize, c.Log.MaxSize)
+				}
+			},
+		},
+		{
+			name: "when a toml file is informed",
+			path: tomlPath,
+			check: func(t *testing.T, c Config) {
+				if c.Port != "9001" || c.Pagination.MaxLimit != 100 || c.Pagination.DefaultLimit != 10 {
+					t.Errorf("was expecting the file values, but returns %+v", c)
+				}
+			},
+		},
+		{
+			name: "when the envs override the file",
+			path: yamlPath,
+			env:  map[string]string{"PORT": "9002", "LOG_LEVEL": "error", "HTTP_READ_TIMEOUT": "1s", "LOG_COMPRESS": "false"},
+			check: func(t *testing.T, c Config) {
+				if c.Port != "9002" || c.Log.Level != "error" || c.HTTP.ReadTimeout != time.Second || c.Log.Compress {
+					t.Errorf("was expecting the env values, but returns %+v", c)
+				}
+				if c.Log.Format != "console" {
+					t.Errorf("was expecting %v, but returns %v", "console", c.Log.Format)
+				}
+			},
+		},
+		{
+			name: "when the flags override the envs",
+			path: yamlPath,
+			env:  map[string]string{"PORT": "9002", "DATABASE_URL": "postgres://env"},
+			args: []string{"--port", "9003"},
+			check: func(t *testing.T, c Config) {
+				if c.Port != "9003" {
+					t.Errorf("was expecting %v, but returns %v", "9003", c.Port)
+				}
+				if c.DatabaseURL != "postgres://env" {
+					t.Errorf("was expecting %v, but returns %v", "postgres://env", c.DatabaseURL)
+				}
+			},
+		},
+		{
+			name:     "when the file does not exist",
+			path:     filepath.Join(dir, "missing.yaml"),
+			hasError: true,
+		},
+		{
+			name:     "when a value could not be parsed",
+			env:      map[string]string{"HTTP_READ_TIMEOUT": "soon"},
+			hasError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for k, v := range tc.env {
+				os.Setenv(k, v)
+			}
+			defer func() {
+				for k := range tc.env {
+					os.Unsetenv(k)
+				}
+			}()
+
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.String("port", "", "")
+			flags.String("dsn", "", "")
+			if err := flags.Parse(tc.args); err != nil {
+				t.Fatal(err)
+			}
+
+			c, err := Load(tc.path, flags)
+			if (err != nil) != tc.hasError {
+				t.Fatalf("was expecting error %v, but returns %v", tc.hasError, err)
+			}
+			if tc.check != nil {
+				tc.check(t, c)
+			}
+		})
+	}
+}
@@ -1,16 +1,18 @@
 package feiralivre
 
 import (
-	"database/sql"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/sirupsen/logrus"
 
 	"github.com/bgildson/unico-challenge/entity"
 	"github.com/bgildson/unico-challenge/repository/feiralivre"
+	"github.com/bgildson/unico-challenge/server/auth"
+	"github.com/bgildson/unico-challenge/server/logging"
+	"github.com/bgildson/unico-challenge/server/openapi"
 	"github.com/bgildson/unico-challenge/server/parser"
 	"github.com/bgildson/unico-challenge/server/response"
 )
@@ -19,190 +21,239 @@ import (
 type Controller struct {
 	feiralivreRepo    feiralivre.Repository
 	queryParamsParser parser.QueryParamsParser
+	guard             auth.Guard
+	authorizer        auth.Authorizer
+	validator         *openapi.Validator
+	version           Version
 }
 
-// New creates a new Controller struct
-func New(feiralivreRepo feiralivre.Repository, queryParamsParser parser.QueryParamsParser) *Controller {
+// New creates a new Controller struct, the routes are open when guard is nil, the changes are
+// not restricted by area when authorizer is nil and the requests are not checked against the
+// OpenAPI document when validator is nil
+func New(feiralivreRepo feiralivre.Repository, queryParamsParser parser.QueryParamsParser, guard auth.Guard, authorizer auth.Authorizer, validator *openapi.Validator) *Controller {
 	return &Controller{
 		feiralivreRepo:    feiralivreRepo,
 		queryParamsParser: queryParamsParser,
+		guard:             guard,
+		authorizer:        authorizer,
+		validator:         validator,
 	}
 }
 
-// Register attachs the controller routes to the fiber app
-func (c Controller) Register(app *fiber.App, path string) {
-	app.Get(path, c.GetByQueryParams)
-	app.Get(path+"/:id", c.GetByID)
-	app.Post(path, c.Create)
-	app.Put(path+"/:id", c.Update)
-	app.Delete(path+"/:id", c.Remove)
+// Register attachs the controller routes to the router, answering the bodies of the version, the
+// handlers are bound to a copy of the controller so it could be registered once per version
+func (c Controller) Register(router fiber.Router, path string, version Version) {
+	c.version = version
+
+	read := auth.Require(c.guard, entity.ScopeRead)
+	write := auth.Require(c.guard, entity.ScopeWrite)
+	validate := openapi.Validate(c.validator)
+
+	router.Get(path, read, validate, c.GetByQueryParams)
+	router.Get(path+"/:id", read, validate, c.GetByID)
+	router.Post(path, write, validate, c.Create)
+	router.Put(path+"/:id", write, validate, c.Update)
+	router.Delete(path+"/:id", write, validate, c.Remove)
 }
 
 // GetByQueryParams implements a controller to search feiralivre by query
 func (c Controller) GetByQueryParams(ctx *fiber.Ctx) error {
 	queryParams := c.queryParamsParser(ctx)
 
-	res, err := c.feiralivreRepo.GetByQueryParams(queryParams)
+	res, err := c.feiralivreRepo.GetByQueryParams(ctx.UserContext(), queryParams)
 	if err != nil {
-		logrus.Errorf("could not query with %+v: %v", queryParams, err)
-		return ctx.
-			Status(http.StatusInternalServerError).
-			JSON(
-				response.Generic{
-					Code:    http.StatusInternalServerError,
-					Message: "could not query",
-				},
-			)
+		logging.From(ctx).Errorf("could not query with %+v: %v", queryParams, err)
+		return response.Error(ctx, err, "could not query the feiras livres")
 	}
 
-	return ctx.JSON(res)
+	return ctx.JSON(c.version.EncodeList(res, queryParams.Pagination))
 }
 
 // GetByID implements a controller to get a feiralivre by id
 func (c Controller) GetByID(ctx *fiber.Ctx) error {
-	idParam := ctx.Params("id")
-	id, err := strconv.Atoi(idParam)
-	if err != nil {
-		logrus.Errorf("could not parse '%v' as id: %v", idParam, err)
-		return ctx.
-			Status(http.StatusBadRequest).
-			JSON(
-				response.Generic{
-					Code:    http.StatusBadRequest,
-					Message: "invalid id",
-				},
-			)
+	id, ok, err := parseID(ctx)
+	if !ok {
+		return err
 	}
 
-	res, err := c.feiralivreRepo.GetByID(id)
-	if err == sql.ErrNoRows {
-		logrus.Errorf("could not get by id, feiralivre %d does not exist: %v", id, err)
-		return ctx.
-			Status(http.StatusNotFound).
-			JSON(response.Generic{
-				Code:    http.StatusNotFound,
-				Message: "not found",
-			})
-	}
+	res, err := c.feiralivreRepo.GetByID(ctx.UserContext(), id)
 	if err != nil {
-		logrus.Errorf("could not get feiralivre %d: %v", id, err)
-		return ctx.
-			Status(http.StatusInternalServerError).
-			JSON(response.Generic{
-				Code:    http.StatusInternalServerError,
-				Message: "could not get by id",
-			})
+		logging.From(ctx).Errorf("could not get feiralivre %d: %v", id, err)
+		return response.Error(ctx, err, fmt.Sprintf("could not get the feira livre %d", id))
 	}
 
-	return ctx.JSON(res)
+	return ctx.JSON(c.version.Encode(*res))
 }
 
 // Create implements a controller to create a feiralivre
 func (c Controller) Create(ctx *fiber.Ctx) error {
-	var fl entity.FeiraLivre
-	if err := json.Unmarshal(ctx.Body(), &fl); err != nil {
-		logrus.Errorf("could not parse request body %s: %v", ctx.Body(), err)
-		return ctx.
-			Status(http.StatusBadRequest).
-			JSON(response.Generic{
-				Code:    http.StatusBadRequest,
-				Message: "invalid body",
-			})
-	}
-
-	res, err := c.feiralivreRepo.Create(fl)
+	fl, ok, err := c.parseBody(ctx)
+	if !ok {
+		return err
+	}
+
+	if ok, err := c.authorize(ctx, 0, &fl); !ok {
+		return err
+	}
+
+	fl.CreatedBy = auth.SubjectOf(ctx)
+	fl.UpdatedBy = fl.CreatedBy
+
+	res, err := c.feiralivreRepo.Create(ctx.UserContext(), fl)
+	var ce *feiralivre.ConstraintError
+	if errors.As(err, &ce) {
+		logging.From(ctx).Warnf("could not create a new feiralivre: %v", err)
+		return violation(ctx, ce, "could not create the feira livre")
+	}
 	if err != nil {
-		logrus.Errorf("could not create a new feiralivre: %v", err)
-		return ctx.
-			Status(http.StatusInternalServerError).
-			JSON(response.Generic{
-				Code:    http.StatusInternalServerError,
-				Message: "could not create",
-			})
+		logging.From(ctx).Errorf("could not create a new feiralivre: %v", err)
+		return response.Error(ctx, err, "could not create the feira livre")
 	}
 
 	return ctx.
 		Status(http.StatusCreated).
-		JSON(res)
+		JSON(c.version.Encode(*res))
 }
 
 // Update implements a controller to update a feiralivre
 func (c Controller) Update(ctx *fiber.Ctx) error {
-	idParam := ctx.Params("id")
-	id, err := strconv.Atoi(idParam)
-	if err != nil {
-		logrus.Errorf("could not parse '%v' as id: %v", idParam, err)
-		return ctx.
-			Status(http.StatusBadRequest).
-			JSON(
-				response.Generic{
-					Code:    http.StatusBadRequest,
-					Message: "invalid id",
-				},
-			)
+	id, ok, err := parseID(ctx)
+	if !ok {
+		return err
 	}
 
-	var fl entity.FeiraLivre
-	if err := json.Unmarshal(ctx.Body(), &fl); err != nil {
-		logrus.Errorf("could not parse request body %s: %v", ctx.Body(), err)
-		return ctx.
-			Status(http.StatusBadRequest).
-			JSON(response.Generic{
-				Code:    http.StatusBadRequest,
-				Message: "invalid body",
-			})
+	fl, ok, err := c.parseBody(ctx)
+	if !ok {
+		return err
 	}
 
-	res, err := c.feiralivreRepo.Update(id, fl)
-	if err == sql.ErrNoRows {
-		logrus.Errorf("could not update, feiralivre %d does not exist: %v", id, err)
-		return ctx.
-			Status(http.StatusNotFound).
-			JSON(response.Generic{
-				Code:    http.StatusNotFound,
-				Message: "not found",
-			})
+	if ok, err := c.authorize(ctx, id, &fl); !ok {
+		return err
+	}
+
+	fl.CreatedBy = ""
+	fl.UpdatedBy = auth.SubjectOf(ctx)
+
+	res, err := c.feiralivreRepo.Update(ctx.UserContext(), id, fl)
+	var ce *feiralivre.ConstraintError
+	if errors.As(err, &ce) {
+		logging.From(ctx).Warnf("could not update feiralivre %d: %v", id, err)
+		return violation(ctx, ce, fmt.Sprintf("could not update the feira livre %d", id))
 	}
 	if err != nil {
-		logrus.Errorf("could not update feiralivre %d: %v", id, err)
-		return ctx.
-			Status(http.StatusInternalServerError).
-			JSON(response.Generic{
-				Code:    http.StatusInternalServerError,
-				Message: "could not update",
-			})
+		logging.From(ctx).Errorf("could not update feiralivre %d: %v", id, err)
+		return response.Error(ctx, err, fmt.Sprintf("could not update the feira livre %d", id))
 	}
 
 	return ctx.
 		Status(http.StatusOK).
-		JSON(res)
+		JSON(c.version.Encode(*res))
 }
 
 // Remove implements a controller to remove a feiralivre
 func (c Controller) Remove(ctx *fiber.Ctx) error {
+	id, ok, err := parseID(ctx)
+	if !ok {
+		return err
+	}
+
+	if ok, err := c.authorize(ctx, id, nil); !ok {
+		return err
+	}
+
+	if err := c.feiralivreRepo.Remove(ctx.UserContext(), id); err != nil {
+		logging.From(ctx).Errorf("could not remove the feiralivre %d: %v", id, err)
+		return response.Error(ctx, err, fmt.Sprintf("could not remove the feira livre %d", id))
+	}
+
+	return ctx.SendStatus(http.StatusNoContent)
+}
+
+// parseID parses the id param, when it is invalid the response is sent and ok is false
+func parseID(ctx *fiber.Ctx) (id int, ok bool, err error) {
 	idParam := ctx.Params("id")
-	id, err := strconv.Atoi(idParam)
+	id, err = strconv.Atoi(idParam)
 	if err != nil {
-		logrus.Errorf("could not parse '%v' as id: %v", idParam, err)
-		return ctx.
-			Status(http.StatusBadRequest).
-			JSON(
-				response.Generic{
-					Code:    http.StatusBadRequest,
-					Message: "invalid id",
-				},
-			)
+		logging.From(ctx).Errorf("could not parse '%v' as id: %v", idParam, err)
+		return 0, false, response.Send(ctx, response.NewProblem(
+			response.TypeInvalidRequest,
+			fmt.Sprintf("the id '%s' is not an integer", idParam),
+		))
 	}
+	return id, true, nil
+}
 
-	if err := c.feiralivreRepo.Remove(id); err != nil {
-		logrus.Errorf("could not remove the feiralivre %d: %v", id, err)
-		return ctx.
-			Status(http.StatusInternalServerError).
-			JSON(response.Generic{
-				Code:    http.StatusInternalServerError,
-				Message: "could not remove",
-			})
+// parseBody parses the feiralivre of the body as the version does, when it is invalid the response is
+// sent and ok is false
+func (c Controller) parseBody(ctx *fiber.Ctx) (fl entity.FeiraLivre, ok bool, err error) {
+	fl, err = c.version.Decode(ctx.Body())
+	if err != nil {
+		logging.From(ctx).Errorf("could not parse request body %s: %v", ctx.Body(), err)
+		return fl, false, response.Send(ctx, response.NewProblem(
+			response.TypeInvalidRequest,
+			"the body is not a valid feira livre: "+err.Error(),
+		))
 	}
+	return fl, true, nil
+}
 
-	return ctx.SendStatus(http.StatusNoContent)
+// violation answers a feiralivre violating a constraint of the repository, with 409 when a field
+// must be unique and 422 otherwise, naming the field when it is known
+func violation(ctx *fiber.Ctx, ce *feiralivre.ConstraintError, detail string) error {
+	t := response.TypeValidation
+	if errors.Is(ce, feiralivre.ErrDuplicated) {
+		t = response.TypeConflict
+	}
+	return response.Send(ctx, response.NewProblem(
+		t,
+		detail+": "+ce.Error(),
+		response.Violation{In: response.InBody, Field: ce.Field, Message: ce.Reason.Error()},
+	))
+}
+
+// authorize checks if who did the request could write the feiralivre fl and, when id is informed,
+// the stored feiralivre, when it is not allowed the response is sent and ok is false
+func (c Controller) authorize(ctx *fiber.Ctx, id int, fl *entity.FeiraLivre) (ok bool, err error) {
+	areas, err := auth.AreasOf(c.authorizer, ctx, entity.ScopeWrite)
+	if err != nil {
+		logging.From(ctx).Errorf("could not get the areas granted: %v", err)
+		return false, response.Error(ctx, err, "could not authorize")
+	}
+	if areas.All {
+		return true, nil
+	}
+
+	targets := []entity.FeiraLivre{}
+	if fl != nil {
+		targets = append(targets, *fl)
+	}
+	if id != 0 {
+		stored, err := c.feiralivreRepo.GetByID(ctx.UserContext(), id)
+		if err != nil {
+			logging.From(ctx).Errorf("could not get feiralivre %d to authorize: %v", id, err)
+			return false, response.Error(ctx, err, fmt.Sprintf("could not authorize the feira livre %d", id))
+		}
+		targets = append(targets, *stored)
+	}
+
+	for _, target := range targets {
+		if !areas.Covers(target) {
+			logging.From(ctx).Warnf(
+				"%s could not write in the subprefeitura %d of the regiao5 %s",
+				auth.SubjectOf(ctx),
+				target.CodigoSubprefeitura,
+				target.Regiao5,
+			)
+			return false, response.Send(ctx, response.NewProblem(
+				response.TypeForbidden,
+				fmt.Sprintf(
+					"the subprefeitura %d of the regiao5 %s is outside the areas granted",
+					target.CodigoSubprefeitura,
+					target.Regiao5,
+				),
+			))
+		}
+	}
+
+	return true, nil
 }
@@ -2,6 +2,7 @@ package feiralivre
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -9,16 +10,23 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/golang/mock/gomock"
 	"github.com/sirupsen/logrus"
 
 	"github.com/bgildson/unico-challenge/entity"
 	"github.com/bgildson/unico-challenge/repository/feiralivre"
+	"github.com/bgildson/unico-challenge/server"
+	"github.com/bgildson/unico-challenge/server/auth"
+	"github.com/bgildson/unico-challenge/server/logging"
+	"github.com/bgildson/unico-challenge/server/openapi"
 	"github.com/bgildson/unico-challenge/server/parser"
+	"github.com/bgildson/unico-challenge/server/response"
 )
 
 func MatchRoute(app *fiber.App, method string, path string) bool {
@@ -69,11 +77,11 @@ func TestControllerRegister(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			controller := New(nil, nil)
+			controller := New(nil, nil, nil, nil, nil)
 
 			app := fiber.New()
 
-			controller.Register(app, path)
+			controller.Register(app, path, V1)
 
 			if !MatchRoute(app, tc.method, tc.path) {
 				t.Errorf("there's no route registered in method %s and path %s", tc.method, tc.path)
@@ -117,21 +125,18 @@ func TestControllerGetByQueryParams(t *testing.T) {
 			setupMocks: func(repo *feiralivre.MockRepository) {
 				repo.
 					EXPECT().
-					GetByQueryParams(gomock.Any()).
+					GetByQueryParams(gomock.Any(), gomock.Any()).
 					Return(nil, errors.New("unexpected error"))
 			},
 			outStatus: http.StatusInternalServerError,
-			outBody: map[string]interface{}{
-				"code":    http.StatusInternalServerError,
-				"message": "could not query",
-			},
+			outBody:   problem(response.TypeInternal, "could not query the feiras livres, try again later and inform the request id if it persists", "/feiras-livres"),
 		},
 		{
 			name: "when success",
 			setupMocks: func(repo *feiralivre.MockRepository) {
 				repo.
 					EXPECT().
-					GetByQueryParams(gomock.Any()).
+					GetByQueryParams(gomock.Any(), gomock.Any()).
 					Return([]entity.FeiraLivre{fl}, nil)
 			},
 			outStatus: http.StatusOK,
@@ -167,11 +172,11 @@ func TestControllerGetByQueryParams(t *testing.T) {
 			repo := feiralivre.NewMockRepository(ctrl)
 			tc.setupMocks(repo)
 			parser := parser.NewQueryParamsParser(10, 42)
-			controller := New(repo, parser)
+			controller := New(repo, parser, nil, nil, nil)
 
 			app := fiber.New()
 
-			controller.Register(app, path)
+			controller.Register(app, path, V1)
 
 			res, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
 			if err != nil {
@@ -186,7 +191,11 @@ func TestControllerGetByQueryParams(t *testing.T) {
 				t.Errorf("could not decode body: %v", err)
 			}
 
-			b1, _ := json.Marshal(tc.outBody)
+			// unmarshaling the expected body sorts the keys as in the returned one
+			var outBody interface{}
+			b0, _ := json.Marshal(tc.outBody)
+			json.Unmarshal(b0, &outBody)
+			b1, _ := json.Marshal(outBody)
 			b2, _ := json.Marshal(body)
 			if string(b1) != string(b2) {
 				t.Errorf("was expecting %s, but returns %s", b1, b2)
@@ -231,47 +240,38 @@ func TestControllerGetByID(t *testing.T) {
 			setupMocks: func(repo *feiralivre.MockRepository) {},
 			in:         "a",
 			outStatus:  http.StatusBadRequest,
-			outBody: map[string]interface{}{
-				"code":    http.StatusBadRequest,
-				"message": "invalid id",
-			},
+			outBody:    problem(response.TypeInvalidRequest, "the id 'a' is not an integer", "/feiras-livres/a"),
 		},
 		{
 			name: "when register does not exists",
 			setupMocks: func(repo *feiralivre.MockRepository) {
 				repo.
 					EXPECT().
-					GetByID(-1).
+					GetByID(gomock.Any(), -1).
 					Return(nil, sql.ErrNoRows)
 			},
 			in:        "-1",
 			outStatus: http.StatusNotFound,
-			outBody: map[string]interface{}{
-				"code":    http.StatusNotFound,
-				"message": "not found",
-			},
+			outBody:   problem(response.TypeNotFound, "could not get the feira livre -1: it does not exist", "/feiras-livres/-1"),
 		},
 		{
 			name: "when repository returns an unexpected error",
 			setupMocks: func(repo *feiralivre.MockRepository) {
 				repo.
 					EXPECT().
-					GetByID(1).
+					GetByID(gomock.Any(), 1).
 					Return(nil, errors.New("unexpected error"))
 			},
 			in:        "1",
 			outStatus: http.StatusInternalServerError,
-			outBody: map[string]interface{}{
-				"code":    http.StatusInternalServerError,
-				"message": "could not get by id",
-			},
+			outBody:   problem(response.TypeInternal, "could not get the feira livre 1, try again later and inform the request id if it persists", "/feiras-livres/1"),
 		},
 		{
 			name: "when success",
 			setupMocks: func(repo *feiralivre.MockRepository) {
 				repo.
 					EXPECT().
-					GetByID(1).
+					GetByID(gomock.Any(), 1).
 					Return(&fl, nil)
 			},
 			in:        "1",
@@ -305,11 +305,11 @@ func TestControllerGetByID(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			repo := feiralivre.NewMockRepository(ctrl)
 			tc.setupMocks(repo)
-			controller := New(repo, nil)
+			controller := New(repo, nil, nil, nil, nil)
 
 			app := fiber.New()
 
-			controller.Register(app, path)
+			controller.Register(app, path, V1)
 
 			res, err := app.Test(httptest.NewRequest(http.MethodGet, path+"/"+tc.in, nil))
 			if err != nil {
@@ -324,7 +324,11 @@ func TestControllerGetByID(t *testing.T) {
 				t.Errorf("could not decode body: %v", err)
 			}
 
-			b1, _ := json.Marshal(tc.outBody)
+			// unmarshaling the expected body sorts the keys as in the returned one
+			var outBody interface{}
+			b0, _ := json.Marshal(tc.outBody)
+			json.Unmarshal(b0, &outBody)
+			b1, _ := json.Marshal(outBody)
 			b2, _ := json.Marshal(body)
 			if string(b1) != string(b2) {
 				t.Errorf("was expecting %s, but returns %s", b1, b2)
@@ -333,6 +337,307 @@ func TestControllerGetByID(t *testing.T) {
 	}
 }
 
+func TestControllerRequestID(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/feiras-livres"
+
+	ctrl := gomock.NewController(t)
+	repo := feiralivre.NewMockRepository(ctrl)
+	repo.
+		EXPECT().
+		GetByID(gomock.Any(), 1).
+		Return(nil, sql.ErrNoRows)
+	controller := New(repo, nil, nil, nil, nil)
+
+	app := fiber.New()
+	app.Use(requestid.New(requestid.Config{ContextKey: server.RequestIDKey}))
+	app.Use(logging.Middleware(logrus.StandardLogger()))
+
+	controller.Register(app, path, V1)
+
+	req := httptest.NewRequest(http.MethodGet, path+"/1", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "caller-id")
+	res, err := app.Test(req)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if id := res.Header.Get(fiber.HeaderXRequestID); id != "caller-id" {
+		t.Errorf("was expecting %v, but returns %v", "caller-id", id)
+	}
+	var body response.Problem
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Errorf("could not decode body: %v", err)
+	}
+
+	expected := problem(response.TypeNotFound, "could not get the feira livre 1: it does not exist", path+"/1")
+	expected.RequestID = "caller-id"
+	b1, _ := json.Marshal(expected)
+	b2, _ := json.Marshal(body)
+	if string(b1) != string(b2) {
+		t.Errorf("was expecting %s, but returns %s", b1, b2)
+	}
+}
+
+type denyAll struct{}
+
+func (denyAll) Authenticate(context.Context, string) (*entity.Principal, error) {
+	return nil, entity.ErrInvalidCredential
+}
+
+func TestControllerGuard(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/feiras-livres"
+	validator, err := openapi.NewValidator(openapi.Spec)
+	if err != nil {
+		t.Fatalf("could not create the validator: %v", err)
+	}
+	testCases := []struct {
+		name      string
+		method    string
+		target    string
+		in        string
+		outStatus int
+	}{
+		{name: "when creating without credential", method: http.MethodPost, target: path, outStatus: http.StatusUnauthorized},
+		// the body is checked only after the guard, so it is not described to who is not allowed
+		{name: "when creating an invalid body without credential", method: http.MethodPost, target: path, in: `{"codigo_distrito": "87"}`, outStatus: http.StatusUnauthorized},
+		{name: "when updating without credential", method: http.MethodPut, target: path + "/1", outStatus: http.StatusUnauthorized},
+		{name: "when removing without credential", method: http.MethodDelete, target: path + "/1", outStatus: http.StatusUnauthorized},
+		{name: "when reading without credential", method: http.MethodGet, target: path + "/a", outStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := New(nil, nil, auth.New(denyAll{}, true), nil, validator)
+
+			app := fiber.New()
+
+			controller.Register(app, path, V1)
+
+			res, err := app.Test(httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.in)))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+		})
+	}
+}
+
+type writer struct{}
+
+func (writer) Authenticate(context.Context, string) (*entity.Principal, error) {
+	return &entity.Principal{Subject: "maria", Scopes: []string{entity.ScopeWrite}}, nil
+}
+
+func TestControllerActor(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/feiras-livres"
+	created := entity.FeiraLivre{NomeFeira: "PRAÇA LEÃO X", CreatedBy: "maria", UpdatedBy: "maria"}
+	updated := entity.FeiraLivre{NomeFeira: "PRAÇA LEÃO X", UpdatedBy: "maria"}
+	testCases := []struct {
+		name       string
+		setupMocks func(repo *feiralivre.MockRepository)
+		method     string
+		target     string
+		in         string
+		outStatus  int
+	}{
+		{
+			name: "when creating",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.EXPECT().Create(gomock.Any(), created).Return(&created, nil)
+			},
+			method:    http.MethodPost,
+			target:    path,
+			in:        `{"nome_feira": "PRAÇA LEÃO X", "created_by": "joao"}`,
+			outStatus: http.StatusCreated,
+		},
+		{
+			name: "when updating",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.EXPECT().Update(gomock.Any(), 1, updated).Return(&updated, nil)
+			},
+			method:    http.MethodPut,
+			target:    path + "/1",
+			in:        `{"nome_feira": "PRAÇA LEÃO X", "created_by": "joao", "updated_by": "joao"}`,
+			outStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := feiralivre.NewMockRepository(ctrl)
+			tc.setupMocks(repo)
+
+			controller := New(repo, nil, auth.New(writer{}, true), nil, nil)
+
+			app := fiber.New()
+
+			controller.Register(app, path, V1)
+
+			req := httptest.NewRequest(tc.method, tc.target, bytes.NewReader([]byte(tc.in)))
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer token")
+			res, err := app.Test(req)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+		})
+	}
+}
+
+type authorizerFunc func(ctx context.Context, principal *entity.Principal, scope string) (entity.Areas, error)
+
+func (f authorizerFunc) Areas(ctx context.Context, principal *entity.Principal, scope string) (entity.Areas, error) {
+	return f(ctx, principal, scope)
+}
+
+func TestControllerAuthorize(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/feiras-livres"
+	aricanduva := entity.Areas{Grants: []entity.Grant{{Subject: "maria", Scope: entity.ScopeWrite, CodigoSubprefeitura: 26}}}
+	inside := entity.FeiraLivre{ID: 1, NomeFeira: "PRAÇA LEÃO X", CodigoSubprefeitura: 26, Regiao5: "Leste"}
+	outside := entity.FeiraLivre{ID: 2, NomeFeira: "VILA LEOPOLDINA", CodigoSubprefeitura: 8, Regiao5: "Oeste"}
+	insideBody := `{"nome_feira": "PRAÇA LEÃO X", "codigo_subprefeitura": 26, "regiao5": "Leste"}`
+	outsideBody := `{"nome_feira": "VILA LEOPOLDINA", "codigo_subprefeitura": 8, "regiao5": "Oeste"}`
+	testCases := []struct {
+		name       string
+		setupMocks func(repo *feiralivre.MockRepository)
+		areasErr   error
+		method     string
+		target     string
+		in         string
+		outStatus  int
+	}{
+		{
+			name:       "when authorizer returns an error",
+			setupMocks: func(repo *feiralivre.MockRepository) {},
+			areasErr:   errors.New("unexpected error"),
+			method:     http.MethodPost,
+			target:     path,
+			in:         insideBody,
+			outStatus:  http.StatusInternalServerError,
+		},
+		{
+			name:       "when creating outside the areas",
+			setupMocks: func(repo *feiralivre.MockRepository) {},
+			method:     http.MethodPost,
+			target:     path,
+			in:         outsideBody,
+			outStatus:  http.StatusForbidden,
+		},
+		{
+			name: "when creating inside the areas",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(&inside, nil)
+			},
+			method:    http.MethodPost,
+			target:    path,
+			in:        insideBody,
+			outStatus: http.StatusCreated,
+		},
+		{
+			name: "when updating a feira that does not exist",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), 3).Return(nil, sql.ErrNoRows)
+			},
+			method:    http.MethodPut,
+			target:    path + "/3",
+			in:        insideBody,
+			outStatus: http.StatusNotFound,
+		},
+		{
+			name: "when updating a feira stored outside the areas",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), 2).Return(&outside, nil)
+			},
+			method:    http.MethodPut,
+			target:    path + "/2",
+			in:        insideBody,
+			outStatus: http.StatusForbidden,
+		},
+		{
+			name: "when moving a feira outside the areas",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), 1).Return(&inside, nil)
+			},
+			method:    http.MethodPut,
+			target:    path + "/1",
+			in:        outsideBody,
+			outStatus: http.StatusForbidden,
+		},
+		{
+			name: "when updating inside the areas",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), 1).Return(&inside, nil)
+				repo.EXPECT().Update(gomock.Any(), 1, gomock.Any()).Return(&inside, nil)
+			},
+			method:    http.MethodPut,
+			target:    path + "/1",
+			in:        insideBody,
+			outStatus: http.StatusOK,
+		},
+		{
+			name: "when removing outside the areas",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), 2).Return(&outside, nil)
+			},
+			method:    http.MethodDelete,
+			target:    path + "/2",
+			outStatus: http.StatusForbidden,
+		},
+		{
+			name: "when removing inside the areas",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.EXPECT().GetByID(gomock.Any(), 1).Return(&inside, nil)
+				repo.EXPECT().Remove(gomock.Any(), 1).Return(nil)
+			},
+			method:    http.MethodDelete,
+			target:    path + "/1",
+			outStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := feiralivre.NewMockRepository(ctrl)
+			tc.setupMocks(repo)
+
+			authorizer := authorizerFunc(func(_ context.Context, principal *entity.Principal, scope string) (entity.Areas, error) {
+				if principal == nil || principal.Subject != "maria" || scope != entity.ScopeWrite {
+					t.Errorf("was expecting maria and the write scope, but receives %+v and %s", principal, scope)
+				}
+				return aricanduva, tc.areasErr
+			})
+			controller := New(repo, nil, auth.New(writer{}, true), authorizer, nil)
+
+			app := fiber.New()
+
+			controller.Register(app, path, V1)
+
+			req := httptest.NewRequest(tc.method, tc.target, bytes.NewReader([]byte(tc.in)))
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer token")
+			res, err := app.Test(req)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+		})
+	}
+}
+
 func TestControllerCreate(t *testing.T) {
 	logrus.SetOutput(ioutil.Discard)
 	path := "/feiras-livres"
@@ -397,6 +702,13 @@ func TestControllerCreate(t *testing.T) {
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
+	outOfRange := flAsBody
+	outOfRange.Latitude = 4294967296
+	body["latitude"] = outOfRange.Latitude
+	outOfRangeJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
 	testCases := []struct {
 		name       string
 		setupMocks func(repo *feiralivre.MockRepository)
@@ -409,32 +721,77 @@ func TestControllerCreate(t *testing.T) {
 			setupMocks: func(repo *feiralivre.MockRepository) {},
 			in:         ":invalid:",
 			outStatus:  http.StatusBadRequest,
-			outBody: map[string]interface{}{
-				"code":    http.StatusBadRequest,
-				"message": "invalid body",
+			outBody:    problem(response.TypeInvalidRequest, "the body is not a valid feira livre: invalid character ':' looking for beginning of value", "/feiras-livres"),
+		},
+		{
+			name: "when a field is longer than its column",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.
+					EXPECT().
+					Create(gomock.Any(), flAsBody).
+					Return(nil, &feiralivre.ConstraintError{Field: "registro", Reason: feiralivre.ErrTooLong})
 			},
+			in:        string(bodyJSON),
+			outStatus: http.StatusUnprocessableEntity,
+			outBody: problem(
+				response.TypeValidation,
+				"could not create the feira livre: the registro is too long",
+				"/feiras-livres",
+				response.Violation{In: response.InBody, Field: "registro", Message: "is too long"},
+			),
+		},
+		{
+			name: "when a coordinate is out of the range of its column",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.
+					EXPECT().
+					Create(gomock.Any(), outOfRange).
+					Return(nil, &feiralivre.ConstraintError{Field: "latitude", Reason: feiralivre.ErrOutOfRange})
+			},
+			in:        string(outOfRangeJSON),
+			outStatus: http.StatusUnprocessableEntity,
+			outBody: problem(
+				response.TypeValidation,
+				"could not create the feira livre: the latitude is not an integer within the range of its column",
+				"/feiras-livres",
+				response.Violation{In: response.InBody, Field: "latitude", Message: "is not an integer within the range of its column"},
+			),
+		},
+		{
+			name: "when the id already exists",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.
+					EXPECT().
+					Create(gomock.Any(), flAsBody).
+					Return(nil, &feiralivre.ConstraintError{Field: "id", Reason: feiralivre.ErrDuplicated})
+			},
+			in:        string(bodyJSON),
+			outStatus: http.StatusConflict,
+			outBody: problem(
+				response.TypeConflict,
+				"could not create the feira livre: the id already exists",
+				"/feiras-livres",
+				response.Violation{In: response.InBody, Field: "id", Message: "already exists"},
+			),
 		},
 		{
 			name: "when repository returns an error",
 			setupMocks: func(repo *feiralivre.MockRepository) {
 				repo.
 					EXPECT().
-					Create(flAsBody).
+					Create(gomock.Any(), flAsBody).
 					Return(nil, errors.New("unexpected error"))
 			},
 			in:        string(bodyJSON),
 			outStatus: http.StatusInternalServerError,
-			outBody: map[string]interface{}{
-				"code":    http.StatusInternalServerError,
-				"message": "could not create",
-			},
+			outBody:   problem(response.TypeInternal, "could not create the feira livre, try again later and inform the request id if it persists", "/feiras-livres"),
 		},
 		{
 			name: "when success",
 			setupMocks: func(repo *feiralivre.MockRepository) {
 				repo.
 					EXPECT().
-					Create(flAsBody).
+					Create(gomock.Any(), flAsBody).
 					Return(&fl, nil)
 			},
 			in:        string(bodyJSON),
@@ -468,11 +825,11 @@ func TestControllerCreate(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			repo := feiralivre.NewMockRepository(ctrl)
 			tc.setupMocks(repo)
-			controller := New(repo, nil)
+			controller := New(repo, nil, nil, nil, nil)
 
 			app := fiber.New()
 
-			controller.Register(app, path)
+			controller.Register(app, path, V1)
 
 			res, err := app.Test(httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(tc.in))))
 			if err != nil {
@@ -487,7 +844,11 @@ func TestControllerCreate(t *testing.T) {
 				t.Errorf("could not decode body: %v", err)
 			}
 
-			b1, _ := json.Marshal(tc.outBody)
+			// unmarshaling the expected body sorts the keys as in the returned one
+			var outBody interface{}
+			b0, _ := json.Marshal(tc.outBody)
+			json.Unmarshal(b0, &outBody)
+			b1, _ := json.Marshal(outBody)
 			b2, _ := json.Marshal(body)
 			if string(b1) != string(b2) {
 				t.Errorf("was expecting %s, but returns %s", b1, b2)
@@ -574,10 +935,7 @@ func TestControllerUpdate(t *testing.T) {
 			inID:       "a",
 			inBody:     bodyJSON,
 			outStatus:  http.StatusBadRequest,
-			outBody: map[string]interface{}{
-				"code":    http.StatusBadRequest,
-				"message": "invalid id",
-			},
+			outBody:    problem(response.TypeInvalidRequest, "the id 'a' is not an integer", "/feiras-livres/a"),
 		},
 		{
 			name:       "when invalid body",
@@ -585,49 +943,58 @@ func TestControllerUpdate(t *testing.T) {
 			inID:       fmt.Sprint(fl.ID),
 			inBody:     []byte(":invalid:"),
 			outStatus:  http.StatusBadRequest,
-			outBody: map[string]interface{}{
-				"code":    http.StatusBadRequest,
-				"message": "invalid body",
-			},
+			outBody:    problem(response.TypeInvalidRequest, "the body is not a valid feira livre: invalid character ':' looking for beginning of value", "/feiras-livres/1"),
 		},
 		{
 			name: "when register does not exists",
 			setupMocks: func(repo *feiralivre.MockRepository) {
 				repo.
 					EXPECT().
-					Update(fl.ID, flAsBody).
+					Update(gomock.Any(), fl.ID, flAsBody).
 					Return(nil, sql.ErrNoRows)
 			},
 			inID:      fmt.Sprint(fl.ID),
 			inBody:    bodyJSON,
 			outStatus: http.StatusNotFound,
-			outBody: map[string]interface{}{
-				"code":    http.StatusNotFound,
-				"message": "not found",
+			outBody:   problem(response.TypeNotFound, "could not update the feira livre 1: it does not exist", "/feiras-livres/1"),
+		},
+		{
+			name: "when string is too long",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.
+					EXPECT().
+					Update(gomock.Any(), fl.ID, flAsBody).
+					Return(nil, &feiralivre.ConstraintError{Field: "nome_feira", Reason: feiralivre.ErrTooLong})
 			},
+			inID:      fmt.Sprint(fl.ID),
+			inBody:    bodyJSON,
+			outStatus: http.StatusUnprocessableEntity,
+			outBody: problem(
+				response.TypeValidation,
+				"could not update the feira livre 1: the nome_feira is too long",
+				"/feiras-livres/1",
+				response.Violation{In: response.InBody, Field: "nome_feira", Message: "is too long"},
+			),
 		},
 		{
 			name: "when repository returns an error",
 			setupMocks: func(repo *feiralivre.MockRepository) {
 				repo.
 					EXPECT().
-					Update(fl.ID, flAsBody).
+					Update(gomock.Any(), fl.ID, flAsBody).
 					Return(nil, errors.New("unexpected error"))
 			},
 			inID:      fmt.Sprint(fl.ID),
 			inBody:    bodyJSON,
 			outStatus: http.StatusInternalServerError,
-			outBody: map[string]interface{}{
-				"code":    http.StatusInternalServerError,
-				"message": "could not update",
-			},
+			outBody:   problem(response.TypeInternal, "could not update the feira livre 1, try again later and inform the request id if it persists", "/feiras-livres/1"),
 		},
 		{
 			name: "when success",
 			setupMocks: func(repo *feiralivre.MockRepository) {
 				repo.
 					EXPECT().
-					Update(fl.ID, flAsBody).
+					Update(gomock.Any(), fl.ID, flAsBody).
 					Return(&fl, nil)
 			},
 			inID:      fmt.Sprint(fl.ID),
@@ -662,11 +1029,11 @@ func TestControllerUpdate(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			repo := feiralivre.NewMockRepository(ctrl)
 			tc.setupMocks(repo)
-			controller := New(repo, nil)
+			controller := New(repo, nil, nil, nil, nil)
 
 			app := fiber.New()
 
-			controller.Register(app, path)
+			controller.Register(app, path, V1)
 
 			res, err := app.Test(httptest.NewRequest(http.MethodPut, path+"/"+tc.inID, bytes.NewReader(tc.inBody)))
 			if err != nil {
@@ -681,7 +1048,11 @@ func TestControllerUpdate(t *testing.T) {
 				t.Errorf("could not decode body: %v", err)
 			}
 
-			b1, _ := json.Marshal(tc.outBody)
+			// unmarshaling the expected body sorts the keys as in the returned one
+			var outBody interface{}
+			b0, _ := json.Marshal(tc.outBody)
+			json.Unmarshal(b0, &outBody)
+			b1, _ := json.Marshal(outBody)
 			b2, _ := json.Marshal(body)
 			if string(b1) != string(b2) {
 				t.Errorf("was expecting %s, but returns %s", b1, b2)
@@ -705,32 +1076,38 @@ func TestControllerRemove(t *testing.T) {
 			setupMocks: func(repo *feiralivre.MockRepository) {},
 			in:         "a",
 			outStatus:  http.StatusBadRequest,
-			outBody: map[string]interface{}{
-				"code":    http.StatusBadRequest,
-				"message": "invalid id",
-			},
+			outBody:    problem(response.TypeInvalidRequest, "the id 'a' is not an integer", "/feiras-livres/a"),
 		},
 		{
 			name: "when repository returns an error",
 			setupMocks: func(repo *feiralivre.MockRepository) {
 				repo.
 					EXPECT().
-					Remove(1).
+					Remove(gomock.Any(), 1).
 					Return(errors.New("unexpected error"))
 			},
 			in:        "1",
 			outStatus: http.StatusInternalServerError,
-			outBody: map[string]interface{}{
-				"code":    http.StatusInternalServerError,
-				"message": "could not remove",
+			outBody:   problem(response.TypeInternal, "could not remove the feira livre 1, try again later and inform the request id if it persists", "/feiras-livres/1"),
+		},
+		{
+			name: "when feiralivre does not exist",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.
+					EXPECT().
+					Remove(gomock.Any(), 1).
+					Return(sql.ErrNoRows)
 			},
+			in:        "1",
+			outStatus: http.StatusNotFound,
+			outBody:   problem(response.TypeNotFound, "could not remove the feira livre 1: it does not exist", "/feiras-livres/1"),
 		},
 		{
 			name: "when success",
 			setupMocks: func(repo *feiralivre.MockRepository) {
 				repo.
 					EXPECT().
-					Remove(1).
+					Remove(gomock.Any(), 1).
 					Return(nil)
 			},
 			in:        "1",
@@ -744,11 +1121,11 @@ func TestControllerRemove(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			repo := feiralivre.NewMockRepository(ctrl)
 			tc.setupMocks(repo)
-			controller := New(repo, nil)
+			controller := New(repo, nil, nil, nil, nil)
 
 			app := fiber.New()
 
-			controller.Register(app, path)
+			controller.Register(app, path, V1)
 
 			res, err := app.Test(httptest.NewRequest(http.MethodDelete, path+"/"+tc.in, nil))
 			if err != nil {
@@ -765,7 +1142,11 @@ func TestControllerRemove(t *testing.T) {
 				t.Errorf("could not decode body: %v", err)
 			}
 
-			b1, _ := json.Marshal(tc.outBody)
+			// unmarshaling the expected body sorts the keys as in the returned one
+			var outBody interface{}
+			b0, _ := json.Marshal(tc.outBody)
+			json.Unmarshal(b0, &outBody)
+			b1, _ := json.Marshal(outBody)
 			b2, _ := json.Marshal(body)
 			if string(b1) != string(b2) {
 				t.Errorf("was expecting %s, but returns %s", b1, b2)
@@ -773,3 +1154,10 @@ func TestControllerRemove(t *testing.T) {
 		})
 	}
 }
+
+// problem is the body expected for the errors, with the instance filled as response.Send does
+func problem(t response.Type, detail, instance string, violations ...response.Violation) response.Problem {
+	p := response.NewProblem(t, detail, violations...)
+	p.Instance = instance
+	return p
+}
@@ -0,0 +1,106 @@
+package feiralivre
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/repository/feiralivre"
+)
+
+// FeiraLivreV1 is the body of a feiralivre in the v1, the coordinates are in millionths of degree
+// as in the source files, the audit fields are ignored in the requests
+type FeiraLivreV1 struct {
+	ID                  int       `json:"id"`
+	Longitude           float64   `json:"longitude"`
+	Latitude            float64   `json:"latitude"`
+	SetorCensitario     int       `json:"setor_censitario"`
+	AreaPonderacao      int       `json:"area_ponderacao"`
+	CodigoDistrito      int       `json:"codigo_distrito"`
+	Distrito            string    `json:"distrito"`
+	CodigoSubprefeitura int       `json:"codigo_subprefeitura"`
+	Subprefeitura       string    `json:"subprefeitura"`
+	Regiao5             string    `json:"regiao5"`
+	Regiao8             string    `json:"regiao8"`
+	NomeFeira           string    `json:"nome_feira"`
+	Registro            string    `json:"registro"`
+	Logradouro          string    `json:"logradouro"`
+	Numero              string    `json:"numero"`
+	Bairro              string    `json:"bairro"`
+	Referencia          string    `json:"referencia"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+	CreatedBy           string    `json:"created_by,omitempty"`
+	UpdatedBy           string    `json:"updated_by,omitempty"`
+}
+
+type v1 struct{}
+
+// Decode implements how the v1 parses a feiralivre
+func (v1) Decode(body []byte) (entity.FeiraLivre, error) {
+	var dto FeiraLivreV1
+	if err := json.Unmarshal(body, &dto); err != nil {
+		return entity.FeiraLivre{}, err
+	}
+
+	return entity.FeiraLivre{
+		ID:                  dto.ID,
+		Longitude:           dto.Longitude,
+		Latitude:            dto.Latitude,
+		SetorCensitario:     dto.SetorCensitario,
+		AreaPonderacao:      dto.AreaPonderacao,
+		CodigoDistrito:      dto.CodigoDistrito,
+		Distrito:            dto.Distrito,
+		CodigoSubprefeitura: dto.CodigoSubprefeitura,
+		Subprefeitura:       dto.Subprefeitura,
+		Regiao5:             dto.Regiao5,
+		Regiao8:             dto.Regiao8,
+		NomeFeira:           dto.NomeFeira,
+		Registro:            dto.Registro,
+		Logradouro:          dto.Logradouro,
+		Numero:              dto.Numero,
+		Bairro:              dto.Bairro,
+		Referencia:          dto.Referencia,
+	}, nil
+}
+
+// Encode implements how the v1 answers a feiralivre
+func (v1) Encode(fl entity.FeiraLivre) interface{} {
+	return toV1(fl)
+}
+
+// EncodeList implements how the v1 answers a query, a list without envelope
+func (v1) EncodeList(fls []entity.FeiraLivre, pagination feiralivre.Pagination) interface{} {
+	res := make([]FeiraLivreV1, len(fls))
+	for i, fl := range fls {
+		res[i] = toV1(fl)
+	}
+	return res
+}
+
+// toV1 converts the entity to the body of the v1
+func toV1(fl entity.FeiraLivre) FeiraLivreV1 {
+	return FeiraLivreV1{
+		ID:                  fl.ID,
+		Longitude:           fl.Longitude,
+		Latitude:            fl.Latitude,
+		SetorCensitario:     fl.SetorCensitario,
+		AreaPonderacao:      fl.AreaPonderacao,
+		CodigoDistrito:      fl.CodigoDistrito,
+		Distrito:            fl.Distrito,
+		CodigoSubprefeitura: fl.CodigoSubprefeitura,
+		Subprefeitura:       fl.Subprefeitura,
+		Regiao5:             fl.Regiao5,
+		Regiao8:             fl.Regiao8,
+		NomeFeira:           fl.NomeFeira,
+		Registro:            fl.Registro,
+		Logradouro:          fl.Logradouro,
+		Numero:              fl.Numero,
+		Bairro:              fl.Bairro,
+		Referencia:          fl.Referencia,
+		CreatedAt:           fl.CreatedAt,
+		UpdatedAt:           fl.UpdatedAt,
+		CreatedBy:           fl.CreatedBy,
+		UpdatedBy:           fl.UpdatedBy,
+	}
+}
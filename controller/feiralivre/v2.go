@@ -0,0 +1,125 @@
+package feiralivre
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/repository/feiralivre"
+)
+
+// FeiraLivreV2 is the body of a feiralivre in the v2, the coordinates are in decimal degrees
+type FeiraLivreV2 struct {
+	ID                  int       `json:"id"`
+	Longitude           float64   `json:"longitude"`
+	Latitude            float64   `json:"latitude"`
+	SetorCensitario     int       `json:"setor_censitario"`
+	AreaPonderacao      int       `json:"area_ponderacao"`
+	CodigoDistrito      int       `json:"codigo_distrito"`
+	Distrito            string    `json:"distrito"`
+	CodigoSubprefeitura int       `json:"codigo_subprefeitura"`
+	Subprefeitura       string    `json:"subprefeitura"`
+	Regiao5             string    `json:"regiao5"`
+	Regiao8             string    `json:"regiao8"`
+	NomeFeira           string    `json:"nome_feira"`
+	Registro            string    `json:"registro"`
+	Logradouro          string    `json:"logradouro"`
+	Numero              string    `json:"numero"`
+	Bairro              string    `json:"bairro"`
+	Referencia          string    `json:"referencia"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+	CreatedBy           string    `json:"created_by,omitempty"`
+	UpdatedBy           string    `json:"updated_by,omitempty"`
+}
+
+// DataV2 is the envelope of a feiralivre answered by the v2
+type DataV2 struct {
+	Data FeiraLivreV2 `json:"data"`
+}
+
+// ListV2 is the envelope of a query answered by the v2, with the pagination used
+type ListV2 struct {
+	Data       []FeiraLivreV2 `json:"data"`
+	Pagination PaginationV2   `json:"pagination"`
+}
+
+// PaginationV2 is the pagination of a query answered by the v2
+type PaginationV2 struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type v2 struct{}
+
+// Decode implements how the v2 parses a feiralivre, the body is not wrapped in an envelope
+func (v2) Decode(body []byte) (entity.FeiraLivre, error) {
+	var dto FeiraLivreV2
+	if err := json.Unmarshal(body, &dto); err != nil {
+		return entity.FeiraLivre{}, err
+	}
+
+	return entity.FeiraLivre{
+		ID:                  dto.ID,
+		Longitude:           entity.FromDegrees(dto.Longitude),
+		Latitude:            entity.FromDegrees(dto.Latitude),
+		SetorCensitario:     dto.SetorCensitario,
+		AreaPonderacao:      dto.AreaPonderacao,
+		CodigoDistrito:      dto.CodigoDistrito,
+		Distrito:            dto.Distrito,
+		CodigoSubprefeitura: dto.CodigoSubprefeitura,
+		Subprefeitura:       dto.Subprefeitura,
+		Regiao5:             dto.Regiao5,
+		Regiao8:             dto.Regiao8,
+		NomeFeira:           dto.NomeFeira,
+		Registro:            dto.Registro,
+		Logradouro:          dto.Logradouro,
+		Numero:              dto.Numero,
+		Bairro:              dto.Bairro,
+		Referencia:          dto.Referencia,
+	}, nil
+}
+
+// Encode implements how the v2 answers a feiralivre
+func (v2) Encode(fl entity.FeiraLivre) interface{} {
+	return DataV2{Data: toV2(fl)}
+}
+
+// EncodeList implements how the v2 answers a query
+func (v2) EncodeList(fls []entity.FeiraLivre, pagination feiralivre.Pagination) interface{} {
+	res := ListV2{
+		Data:       make([]FeiraLivreV2, len(fls)),
+		Pagination: PaginationV2{Limit: pagination.Limit, Offset: pagination.Offset},
+	}
+	for i, fl := range fls {
+		res.Data[i] = toV2(fl)
+	}
+	return res
+}
+
+// toV2 converts the entity to the body of the v2
+func toV2(fl entity.FeiraLivre) FeiraLivreV2 {
+	return FeiraLivreV2{
+		ID:                  fl.ID,
+		Longitude:           entity.ToDegrees(fl.Longitude),
+		Latitude:            entity.ToDegrees(fl.Latitude),
+		SetorCensitario:     fl.SetorCensitario,
+		AreaPonderacao:      fl.AreaPonderacao,
+		CodigoDistrito:      fl.CodigoDistrito,
+		Distrito:            fl.Distrito,
+		CodigoSubprefeitura: fl.CodigoSubprefeitura,
+		Subprefeitura:       fl.Subprefeitura,
+		Regiao5:             fl.Regiao5,
+		Regiao8:             fl.Regiao8,
+		NomeFeira:           fl.NomeFeira,
+		Registro:            fl.Registro,
+		Logradouro:          fl.Logradouro,
+		Numero:              fl.Numero,
+		Bairro:              fl.Bairro,
+		Referencia:          fl.Referencia,
+		CreatedAt:           fl.CreatedAt,
+		UpdatedAt:           fl.UpdatedAt,
+		CreatedBy:           fl.CreatedBy,
+		UpdatedBy:           fl.UpdatedBy,
+	}
+}
@@ -0,0 +1,24 @@
+package feiralivre
+
+import (
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/repository/feiralivre"
+)
+
+// Version converts the feiralivre between the entity and the bodies of a version of the api, so
+// the handlers are shared by the versions and the entity could change without breaking the clients
+type Version interface {
+	// Decode parses the body of a create or an update
+	Decode(body []byte) (entity.FeiraLivre, error)
+	// Encode creates the body answering a feiralivre
+	Encode(fl entity.FeiraLivre) interface{}
+	// EncodeList creates the body answering a query, pagination is the one used by the query
+	EncodeList(fls []entity.FeiraLivre, pagination feiralivre.Pagination) interface{}
+}
+
+var (
+	// V1 is the first version of the api, answering the feiralivre as it is stored, deprecated by V2
+	V1 Version = v1{}
+	// V2 answers the coordinates in decimal degrees and wraps the bodies in an envelope
+	V2 Version = v2{}
+)
@@ -0,0 +1,125 @@
+package feiralivre
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/repository/feiralivre"
+)
+
+func TestVersionDecode(t *testing.T) {
+	fl := entity.FeiraLivre{
+		ID:                  1,
+		Longitude:           -46550164,
+		Latitude:            -23558733,
+		CodigoSubprefeitura: 26,
+		Regiao5:             "Leste",
+		NomeFeira:           "VILA FORMOSA",
+	}
+	testCases := []struct {
+		name     string
+		version  Version
+		in       string
+		out      entity.FeiraLivre
+		hasError bool
+	}{
+		{
+			name:     "when body is invalid",
+			version:  V1,
+			in:       ":invalid:",
+			hasError: true,
+		},
+		{
+			name:    "when v1 ignores the audit fields",
+			version: V1,
+			in:      `{"id": 1, "longitude": -46550164, "latitude": -23558733, "codigo_subprefeitura": 26, "regiao5": "Leste", "nome_feira": "VILA FORMOSA", "created_by": "someone"}`,
+			out:     fl,
+		},
+		{
+			name:    "when v2 converts the decimal degrees",
+			version: V2,
+			in:      `{"id": 1, "longitude": -46.550164, "latitude": -23.558733, "codigo_subprefeitura": 26, "regiao5": "Leste", "nome_feira": "VILA FORMOSA"}`,
+			out:     fl,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := tc.version.Decode([]byte(tc.in))
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if !reflect.DeepEqual(out, tc.out) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, out)
+			}
+		})
+	}
+}
+
+func TestVersionEncode(t *testing.T) {
+	now := time.Now()
+	fl := entity.FeiraLivre{
+		ID:        1,
+		Longitude: -46550164,
+		Latitude:  -23558733,
+		NomeFeira: "VILA FORMOSA",
+		CreatedAt: now,
+		UpdatedAt: now,
+		CreatedBy: "role:sub-aricanduva",
+	}
+	flV1 := FeiraLivreV1{
+		ID:        1,
+		Longitude: -46550164,
+		Latitude:  -23558733,
+		NomeFeira: "VILA FORMOSA",
+		CreatedAt: now,
+		UpdatedAt: now,
+		CreatedBy: "role:sub-aricanduva",
+	}
+	flV2 := FeiraLivreV2{
+		ID:        1,
+		Longitude: -46.550164,
+		Latitude:  -23.558733,
+		NomeFeira: "VILA FORMOSA",
+		CreatedAt: now,
+		UpdatedAt: now,
+		CreatedBy: "role:sub-aricanduva",
+	}
+	pagination := feiralivre.Pagination{Limit: 10, Offset: 20}
+
+	testCases := []struct {
+		name    string
+		version Version
+		outOne  interface{}
+		outList interface{}
+	}{
+		{
+			name:    "when v1",
+			version: V1,
+			outOne:  flV1,
+			outList: []FeiraLivreV1{flV1},
+		},
+		{
+			name:    "when v2",
+			version: V2,
+			outOne:  DataV2{Data: flV2},
+			outList: ListV2{Data: []FeiraLivreV2{flV2}, Pagination: PaginationV2{Limit: 10, Offset: 20}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if out := tc.version.Encode(fl); !reflect.DeepEqual(out, tc.outOne) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.outOne, out)
+			}
+			if out := tc.version.EncodeList([]entity.FeiraLivre{fl}, pagination); !reflect.DeepEqual(out, tc.outList) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.outList, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package grant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/server/auth"
+	"github.com/bgildson/unico-challenge/server/logging"
+	"github.com/bgildson/unico-challenge/server/openapi"
+	"github.com/bgildson/unico-challenge/server/response"
+	"github.com/bgildson/unico-challenge/service/grant"
+)
+
+// Controller implements a grant controller, used by the admins to manage who could change the
+// feiras of each area
+type Controller struct {
+	grantServ grant.Service
+	guard     auth.Guard
+	validator *openapi.Validator
+}
+
+// New creates a new Controller struct, the routes are open when guard is nil and the requests are
+// not checked against the OpenAPI document when validator is nil
+func New(grantServ grant.Service, guard auth.Guard, validator *openapi.Validator) *Controller {
+	return &Controller{
+		grantServ: grantServ,
+		guard:     guard,
+		validator: validator,
+	}
+}
+
+// Register attachs the controller routes to the fiber app
+func (c Controller) Register(app *fiber.App, path string) {
+	admin := auth.Require(c.guard, entity.ScopeAdmin)
+	validate := openapi.Validate(c.validator)
+
+	app.Get(path, admin, validate, c.List)
+	app.Post(path, admin, validate, c.Create)
+	app.Delete(path+"/:id", admin, validate, c.Remove)
+}
+
+// List implements a controller to list every grant
+func (c Controller) List(ctx *fiber.Ctx) error {
+	res, err := c.grantServ.List(ctx.UserContext())
+	if err != nil {
+		logging.From(ctx).Errorf("could not list the grants: %v", err)
+		return response.Error(ctx, err, "could not list the grants")
+	}
+
+	return ctx.JSON(res)
+}
+
+// Create implements a controller to create a grant
+func (c Controller) Create(ctx *fiber.Ctx) error {
+	var g entity.Grant
+	if err := json.Unmarshal(ctx.Body(), &g); err != nil {
+		logging.From(ctx).Errorf("could not parse request body %s: %v", ctx.Body(), err)
+		return response.Send(ctx, response.NewProblem(
+			response.TypeInvalidRequest,
+			"the body is not a valid grant: "+err.Error(),
+		))
+	}
+
+	res, err := c.grantServ.Create(ctx.UserContext(), g)
+	if errors.Is(err, grant.ErrInvalidGrant) {
+		logging.From(ctx).Errorf("could not create the grant: %v", err)
+		return response.Send(ctx, response.NewProblem(response.TypeValidation, err.Error()))
+	}
+	if err != nil {
+		logging.From(ctx).Errorf("could not create a new grant: %v", err)
+		return response.Error(ctx, err, "could not create the grant")
+	}
+
+	logging.From(ctx).Infof("%s granted %s to %s", auth.SubjectOf(ctx), res.Scope, res.Subject)
+
+	return ctx.
+		Status(http.StatusCreated).
+		JSON(res)
+}
+
+// Remove implements a controller to remove a grant
+func (c Controller) Remove(ctx *fiber.Ctx) error {
+	idParam := ctx.Params("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		logging.From(ctx).Errorf("could not parse '%v' as id: %v", idParam, err)
+		return response.Send(ctx, response.NewProblem(
+			response.TypeInvalidRequest,
+			fmt.Sprintf("the id '%s' is not an integer", idParam),
+		))
+	}
+
+	err = c.grantServ.Remove(ctx.UserContext(), id)
+	if err == grant.ErrGrantNotFound {
+		logging.From(ctx).Errorf("could not remove, grant %d does not exist: %v", id, err)
+		return response.Send(ctx, response.NewProblem(
+			response.TypeNotFound,
+			fmt.Sprintf("could not remove the grant %d: it does not exist", id),
+		))
+	}
+	if err != nil {
+		logging.From(ctx).Errorf("could not remove the grant %d: %v", id, err)
+		return response.Error(ctx, err, fmt.Sprintf("could not remove the grant %d", id))
+	}
+
+	logging.From(ctx).Infof("%s removed the grant %d", auth.SubjectOf(ctx), id)
+
+	return ctx.SendStatus(http.StatusNoContent)
+}
@@ -0,0 +1,273 @@
+package grant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/server/auth"
+	"github.com/bgildson/unico-challenge/server/response"
+	"github.com/bgildson/unico-challenge/service/grant"
+)
+
+type editor struct{}
+
+func (editor) Authenticate(context.Context, string) (*entity.Principal, error) {
+	return &entity.Principal{Subject: "maria", Scopes: []string{entity.ScopeRead, entity.ScopeWrite}}, nil
+}
+
+func TestControllerGuard(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/admin/grants"
+	testCases := []struct {
+		name   string
+		method string
+		target string
+	}{
+		{name: "when listing", method: http.MethodGet, target: path},
+		{name: "when creating", method: http.MethodPost, target: path},
+		{name: "when removing", method: http.MethodDelete, target: path + "/1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := New(nil, auth.New(editor{}, true), nil)
+
+			app := fiber.New()
+
+			controller.Register(app, path)
+
+			req := httptest.NewRequest(tc.method, tc.target, nil)
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer token")
+			res, err := app.Test(req)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if res.StatusCode != http.StatusForbidden {
+				t.Errorf("was expecting %v, but returns %v", http.StatusForbidden, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestControllerList(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/admin/grants"
+	grants := []entity.Grant{{ID: 1, Subject: "role:sub-aricanduva", Scope: entity.ScopeWrite, CodigoSubprefeitura: 26}}
+	testCases := []struct {
+		name       string
+		setupMocks func(serv *grant.MockService)
+		outStatus  int
+		outBody    interface{}
+	}{
+		{
+			name: "when service returns an error",
+			setupMocks: func(serv *grant.MockService) {
+				serv.EXPECT().List(gomock.Any()).Return(nil, errors.New("unexpected error"))
+			},
+			outStatus: http.StatusInternalServerError,
+			outBody:   problem(response.TypeInternal, "could not list the grants, try again later and inform the request id if it persists", "/admin/grants"),
+		},
+		{
+			name: "when success",
+			setupMocks: func(serv *grant.MockService) {
+				serv.EXPECT().List(gomock.Any()).Return(grants, nil)
+			},
+			outStatus: http.StatusOK,
+			outBody:   grants,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serv := grant.NewMockService(ctrl)
+			tc.setupMocks(serv)
+			controller := New(serv, nil, nil)
+
+			app := fiber.New()
+
+			controller.Register(app, path)
+
+			res, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+			assertBody(t, tc.outBody, res)
+		})
+	}
+}
+
+func TestControllerCreate(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/admin/grants"
+	in := entity.Grant{Subject: "role:sub-aricanduva", Scope: entity.ScopeWrite, CodigoSubprefeitura: 26}
+	created := in
+	created.ID = 1
+	testCases := []struct {
+		name       string
+		setupMocks func(serv *grant.MockService)
+		in         string
+		outStatus  int
+		outBody    interface{}
+	}{
+		{
+			name:       "when invalid body",
+			setupMocks: func(serv *grant.MockService) {},
+			in:         ":invalid:",
+			outStatus:  http.StatusBadRequest,
+			outBody:    problem(response.TypeInvalidRequest, "the body is not a valid grant: invalid character ':' looking for beginning of value", "/admin/grants"),
+		},
+		{
+			name: "when grant is invalid",
+			setupMocks: func(serv *grant.MockService) {
+				serv.EXPECT().Create(gomock.Any(), in).Return(nil, fmt.Errorf("%w: the subject is required", grant.ErrInvalidGrant))
+			},
+			in:        `{"subject": "role:sub-aricanduva", "scope": "write", "codigo_subprefeitura": 26}`,
+			outStatus: http.StatusUnprocessableEntity,
+			outBody:   problem(response.TypeValidation, "invalid grant: the subject is required", "/admin/grants"),
+		},
+		{
+			name: "when service returns an error",
+			setupMocks: func(serv *grant.MockService) {
+				serv.EXPECT().Create(gomock.Any(), in).Return(nil, errors.New("unexpected error"))
+			},
+			in:        `{"subject": "role:sub-aricanduva", "scope": "write", "codigo_subprefeitura": 26}`,
+			outStatus: http.StatusInternalServerError,
+			outBody:   problem(response.TypeInternal, "could not create the grant, try again later and inform the request id if it persists", "/admin/grants"),
+		},
+		{
+			name: "when success",
+			setupMocks: func(serv *grant.MockService) {
+				serv.EXPECT().Create(gomock.Any(), in).Return(&created, nil)
+			},
+			in:        `{"subject": "role:sub-aricanduva", "scope": "write", "codigo_subprefeitura": 26}`,
+			outStatus: http.StatusCreated,
+			outBody:   created,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serv := grant.NewMockService(ctrl)
+			tc.setupMocks(serv)
+			controller := New(serv, nil, nil)
+
+			app := fiber.New()
+
+			controller.Register(app, path)
+
+			res, err := app.Test(httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(tc.in))))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+			assertBody(t, tc.outBody, res)
+		})
+	}
+}
+
+func TestControllerRemove(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/admin/grants"
+	testCases := []struct {
+		name       string
+		setupMocks func(serv *grant.MockService)
+		inID       string
+		outStatus  int
+	}{
+		{
+			name:       "when invalid id",
+			setupMocks: func(serv *grant.MockService) {},
+			inID:       "a",
+			outStatus:  http.StatusBadRequest,
+		},
+		{
+			name: "when does not exist",
+			setupMocks: func(serv *grant.MockService) {
+				serv.EXPECT().Remove(gomock.Any(), 1).Return(grant.ErrGrantNotFound)
+			},
+			inID:      "1",
+			outStatus: http.StatusNotFound,
+		},
+		{
+			name: "when service returns an error",
+			setupMocks: func(serv *grant.MockService) {
+				serv.EXPECT().Remove(gomock.Any(), 1).Return(errors.New("unexpected error"))
+			},
+			inID:      "1",
+			outStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "when success",
+			setupMocks: func(serv *grant.MockService) {
+				serv.EXPECT().Remove(gomock.Any(), 1).Return(nil)
+			},
+			inID:      "1",
+			outStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serv := grant.NewMockService(ctrl)
+			tc.setupMocks(serv)
+			controller := New(serv, nil, nil)
+
+			app := fiber.New()
+
+			controller.Register(app, path)
+
+			res, err := app.Test(httptest.NewRequest(http.MethodDelete, path+"/"+tc.inID, nil))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+		})
+	}
+}
+
+// assertBody compares the response body with the expected one, marshaling both to sort the keys
+func assertBody(t *testing.T, expected interface{}, res *http.Response) {
+	var body interface{}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Errorf("could not decode body: %v", err)
+	}
+
+	var outBody interface{}
+	b0, _ := json.Marshal(expected)
+	json.Unmarshal(b0, &outBody)
+	b1, _ := json.Marshal(outBody)
+	b2, _ := json.Marshal(body)
+	if string(b1) != string(b2) {
+		t.Errorf("was expecting %s, but returns %s", b1, b2)
+	}
+}
+
+// problem is the body expected for the errors, with the instance filled as response.Send does
+func problem(t response.Type, detail, instance string) response.Problem {
+	p := response.NewProblem(t, detail)
+	p.Instance = instance
+	return p
+}
@@ -0,0 +1,154 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bgildson/unico-challenge/repository/migration"
+)
+
+const (
+	// StatusOK is used when the process or a check is working
+	StatusOK = "ok"
+	// StatusFailing is used when a check is not working
+	StatusFailing = "failing"
+	// StatusShuttingDown is used when the server stopped accepting new work
+	StatusShuttingDown = "shutting_down"
+)
+
+// DefaultPingTimeout is the maximum time waited for the database ping
+const DefaultPingTimeout = 2 * time.Second
+
+// Pinger represents how the database is checked, implemented by *sql.DB
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// Check represents the result of one readiness check
+type Check struct {
+	Status          string `json:"status"`
+	Error           string `json:"error,omitempty"`
+	Version         *int64 `json:"version,omitempty"`
+	ExpectedVersion *int64 `json:"expected_version,omitempty"`
+}
+
+// Response represents the health and readiness responses
+type Response struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks,omitempty"`
+}
+
+// Controller implements the health and readiness controller
+type Controller struct {
+	db              Pinger
+	migrator        migration.Migrator
+	expectedVersion int64
+	pingTimeout     time.Duration
+	shuttingDown    *int32
+}
+
+// New creates a new Controller struct, expectedVersion is the migration version the database must be at
+func New(db Pinger, migrator migration.Migrator, expectedVersion int64) *Controller {
+	return &Controller{
+		db:              db,
+		migrator:        migrator,
+		expectedVersion: expectedVersion,
+		pingTimeout:     DefaultPingTimeout,
+		shuttingDown:    new(int32),
+	}
+}
+
+// Register attachs the controller routes to the fiber app
+func (c Controller) Register(app *fiber.App) {
+	app.Get("/healthz", c.Health)
+	app.Get("/readyz", c.Ready)
+}
+
+// ShuttingDown makes the readiness fail, so the orchestrator stops sending requests while the server drains
+func (c Controller) ShuttingDown() {
+	atomic.StoreInt32(c.shuttingDown, 1)
+}
+
+// Health implements a controller that answers while the process is alive
+func (c Controller) Health(ctx *fiber.Ctx) error {
+	return ctx.JSON(Response{Status: StatusOK})
+}
+
+// Ready implements a controller that answers if the server could receive requests
+func (c Controller) Ready(ctx *fiber.Ctx) error {
+	if atomic.LoadInt32(c.shuttingDown) == 1 {
+		return ctx.
+			Status(http.StatusServiceUnavailable).
+			JSON(Response{Status: StatusShuttingDown})
+	}
+
+	res := Response{
+		Status: StatusOK,
+		Checks: map[string]Check{
+			"database":   c.checkDatabase(),
+			"migrations": c.checkMigrations(),
+		},
+	}
+	for _, check := range res.Checks {
+		if check.Status != StatusOK {
+			res.Status = StatusFailing
+		}
+	}
+
+	if res.Status != StatusOK {
+		return ctx.
+			Status(http.StatusServiceUnavailable).
+			JSON(res)
+	}
+
+	return ctx.JSON(res)
+}
+
+func (c Controller) checkDatabase() Check {
+	ctx, cancel := context.WithTimeout(context.Background(), c.pingTimeout)
+	defer cancel()
+
+	if err := c.db.PingContext(ctx); err != nil {
+		logrus.Errorf("could not ping the database: %v", err)
+		return Check{Status: StatusFailing, Error: "could not ping the database"}
+	}
+
+	return Check{Status: StatusOK}
+}
+
+func (c Controller) checkMigrations() Check {
+	expected := c.expectedVersion
+	check := Check{Status: StatusOK, ExpectedVersion: &expected}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.pingTimeout)
+	defer cancel()
+
+	version, dirty, err := c.migrator.Version(ctx)
+	if err != nil {
+		logrus.Errorf("could not get the migrations version: %v", err)
+		check.Status = StatusFailing
+		check.Error = "could not get the migrations version"
+		return check
+	}
+	check.Version = &version
+
+	switch {
+	case dirty:
+		check.Status = StatusFailing
+		check.Error = "the database is dirty"
+	case version > expected:
+		// a newer version is found while a rolling deploy runs, the newer instances migrate the
+		// database before the older ones stop, and the migrations must keep the older code working,
+		// so the older instances stay ready
+	case version < expected:
+		check.Status = StatusFailing
+		check.Error = "the database is behind the expected version"
+	}
+
+	return check
+}
@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bgildson/unico-challenge/repository/migration"
+)
+
+type pingerFunc func(ctx context.Context) error
+
+func (f pingerFunc) PingContext(ctx context.Context) error {
+	return f(ctx)
+}
+
+func TestControllerHealth(t *testing.T) {
+	controller := New(nil, nil, 0)
+
+	app := fiber.New()
+
+	controller.Register(app)
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("was expecting %v, but returns %v", http.StatusOK, res.StatusCode)
+	}
+}
+
+func TestControllerReady(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	okPinger := pingerFunc(func(context.Context) error { return nil })
+	testCases := []struct {
+		name         string
+		setupMocks   func(m *migration.MockMigrator)
+		pinger       Pinger
+		shuttingDown bool
+		outStatus    int
+		outBody      string
+	}{
+		{
+			name: "when everything is ok",
+			setupMocks: func(m *migration.MockMigrator) {
+				m.EXPECT().Version(gomock.Any()).Return(int64(2), false, nil)
+			},
+			pinger:    okPinger,
+			outStatus: http.StatusOK,
+			outBody:   `{"checks":{"database":{"status":"ok"},"migrations":{"expected_version":2,"status":"ok","version":2}},"status":"ok"}`,
+		},
+		{
+			name: "when the database is unreachable",
+			setupMocks: func(m *migration.MockMigrator) {
+				m.EXPECT().Version(gomock.Any()).Return(int64(2), false, nil)
+			},
+			pinger:    pingerFunc(func(context.Context) error { return errors.New("unexpected error") }),
+			outStatus: http.StatusServiceUnavailable,
+			outBody:   `{"checks":{"database":{"error":"could not ping the database","status":"failing"},"migrations":{"expected_version":2,"status":"ok","version":2}},"status":"failing"}`,
+		},
+		{
+			name: "when the migrations are behind",
+			setupMocks: func(m *migration.MockMigrator) {
+				m.EXPECT().Version(gomock.Any()).Return(int64(1), false, nil)
+			},
+			pinger:    okPinger,
+			outStatus: http.StatusServiceUnavailable,
+			outBody:   `{"checks":{"database":{"status":"ok"},"migrations":{"error":"the database is behind the expected version","expected_version":2,"status":"failing","version":1}},"status":"failing"}`,
+		},
+		{
+			name: "when the migrations are ahead",
+			setupMocks: func(m *migration.MockMigrator) {
+				m.EXPECT().Version(gomock.Any()).Return(int64(3), false, nil)
+			},
+			pinger:    okPinger,
+			outStatus: http.StatusOK,
+			outBody:   `{"checks":{"database":{"status":"ok"},"migrations":{"expected_version":2,"status":"ok","version":3}},"status":"ok"}`,
+		},
+		{
+			name: "when the database is dirty",
+			setupMocks: func(m *migration.MockMigrator) {
+				m.EXPECT().Version(gomock.Any()).Return(int64(2), true, nil)
+			},
+			pinger:    okPinger,
+			outStatus: http.StatusServiceUnavailable,
+			outBody:   `{"checks":{"database":{"status":"ok"},"migrations":{"error":"the database is dirty","expected_version":2,"status":"failing","version":2}},"status":"failing"}`,
+		},
+		{
+			name:         "when shutting down",
+			setupMocks:   func(m *migration.MockMigrator) {},
+			pinger:       okPinger,
+			shuttingDown: true,
+			outStatus:    http.StatusServiceUnavailable,
+			outBody:      `{"status":"shutting_down"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			m := migration.NewMockMigrator(ctrl)
+			tc.setupMocks(m)
+			controller := New(tc.pinger, m, 2)
+			if tc.shuttingDown {
+				controller.ShuttingDown()
+			}
+
+			app := fiber.New()
+
+			controller.Register(app)
+
+			res, err := app.Test(httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+			var body interface{}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Errorf("could not decode body: %v", err)
+			}
+			b, _ := json.Marshal(body)
+			if string(b) != tc.outBody {
+				t.Errorf("was expecting %s, but returns %s", tc.outBody, b)
+			}
+		})
+	}
+}
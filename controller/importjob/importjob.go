@@ -0,0 +1,189 @@
+package importjob
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/server/auth"
+	"github.com/bgildson/unico-challenge/server/logging"
+	"github.com/bgildson/unico-challenge/server/openapi"
+	"github.com/bgildson/unico-challenge/server/response"
+	feiralivreServ "github.com/bgildson/unico-challenge/service/feiralivre"
+	"github.com/bgildson/unico-challenge/service/importjob"
+)
+
+// Controller implements an importjob controller
+type Controller struct {
+	importjobServ importjob.Service
+	guard         auth.Guard
+	authorizer    auth.Authorizer
+	validator     *openapi.Validator
+}
+
+// New creates a new Controller struct, the routes are open when guard is nil, the imports are not
+// restricted by area when authorizer is nil and the requests are not checked against the OpenAPI
+// document when validator is nil
+func New(importjobServ importjob.Service, guard auth.Guard, authorizer auth.Authorizer, validator *openapi.Validator) *Controller {
+	return &Controller{
+		importjobServ: importjobServ,
+		guard:         guard,
+		authorizer:    authorizer,
+		validator:     validator,
+	}
+}
+
+// Register attachs the controller routes to the fiber app, every route requires the import scope,
+// even getting a job, which is not public since it tells who created it and its errors
+func (c Controller) Register(app *fiber.App, path string) {
+	imp := auth.Require(c.guard, entity.ScopeImport)
+	validate := openapi.Validate(c.validator)
+
+	app.Post(path, imp, validate, c.Create)
+	app.Get(path+"/:id", imp, validate, c.GetByID)
+	app.Delete(path+"/:id", imp, validate, c.Cancel)
+}
+
+// Create implements a controller to create an importjob from a multipart upload
+func (c Controller) Create(ctx *fiber.Ctx) error {
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		logging.From(ctx).Errorf("could not get the uploaded file: %v", err)
+		return response.Send(ctx, response.NewProblem(response.TypeInvalidRequest, "the file is required in the field 'file'"))
+	}
+
+	format := ctx.FormValue("format")
+	if format != "" {
+		if _, err := feiralivreServ.NewSourceReader(format); err != nil {
+			logging.From(ctx).Errorf("could not use the format '%s': %v", format, err)
+			return response.Send(ctx, response.NewProblem(
+				response.TypeInvalidRequest,
+				fmt.Sprintf("the format '%s' is not supported", format),
+			))
+		}
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		logging.From(ctx).Errorf("could not open the uploaded file: %v", err)
+		return response.Send(ctx, response.NewProblem(response.TypeInvalidRequest, "the file could not be read"))
+	}
+	defer content.Close()
+
+	areas, err := auth.AreasOf(c.authorizer, ctx, entity.ScopeImport)
+	if err != nil {
+		logging.From(ctx).Errorf("could not get the areas granted: %v", err)
+		return response.Error(ctx, err, "could not authorize")
+	}
+	if !areas.All && len(areas.Grants) == 0 {
+		logging.From(ctx).Warnf("%s has no area granted to import", auth.SubjectOf(ctx))
+		return response.Send(ctx, response.NewProblem(response.TypeForbidden, "no area is granted to import"))
+	}
+
+	res, err := c.importjobServ.Create(file.Filename, format, areas, auth.SubjectOf(ctx), content)
+	if err != nil {
+		logging.From(ctx).Errorf("could not create a new importjob: %v", err)
+		return response.Error(ctx, err, "could not create the import job")
+	}
+
+	ctx.Location(ctx.Path() + "/" + strconv.Itoa(res.ID))
+	return ctx.
+		Status(http.StatusAccepted).
+		JSON(res)
+}
+
+// GetByID implements a controller to get an importjob by id
+func (c Controller) GetByID(ctx *fiber.Ctx) error {
+	id, ok, err := parseID(ctx)
+	if !ok {
+		return err
+	}
+
+	res, err := c.importjobServ.GetByID(id)
+	if err != nil {
+		logging.From(ctx).Errorf("could not get importjob %d: %v", id, err)
+		return response.Error(ctx, err, fmt.Sprintf("could not get the import job %d", id))
+	}
+
+	if ok, err := c.authorize(ctx, res); !ok {
+		return err
+	}
+
+	return ctx.JSON(res)
+}
+
+// Cancel implements a controller to cancel a running importjob
+func (c Controller) Cancel(ctx *fiber.Ctx) error {
+	id, ok, err := parseID(ctx)
+	if !ok {
+		return err
+	}
+
+	job, err := c.importjobServ.GetByID(id)
+	if err != nil {
+		logging.From(ctx).Errorf("could not get importjob %d to cancel: %v", id, err)
+		return response.Error(ctx, err, fmt.Sprintf("could not cancel the import job %d", id))
+	}
+
+	if ok, err := c.authorize(ctx, job); !ok {
+		return err
+	}
+
+	res, err := c.importjobServ.Cancel(id)
+	if err == importjob.ErrImportJobNotRunning {
+		logging.From(ctx).Errorf("could not cancel importjob %d: %v", id, err)
+		return response.Send(ctx, response.NewProblem(
+			response.TypeConflict,
+			fmt.Sprintf("could not cancel the import job %d: it is not running", id),
+		))
+	}
+	if err != nil {
+		logging.From(ctx).Errorf("could not cancel importjob %d: %v", id, err)
+		return response.Error(ctx, err, fmt.Sprintf("could not cancel the import job %d", id))
+	}
+
+	return ctx.
+		Status(http.StatusAccepted).
+		JSON(res)
+}
+
+// authorize checks if the principal could reach the job, the principals with the import restricted
+// to some areas only reach the jobs they created, when it could not the response is sent
+func (c Controller) authorize(ctx *fiber.Ctx, job *entity.ImportJob) (ok bool, err error) {
+	areas, err := auth.AreasOf(c.authorizer, ctx, entity.ScopeImport)
+	if err != nil {
+		logging.From(ctx).Errorf("could not get the areas granted: %v", err)
+		return false, response.Error(ctx, err, "could not authorize")
+	}
+	if areas.All {
+		return true, nil
+	}
+
+	subject := auth.SubjectOf(ctx)
+	if job.CreatedBy == "" || job.CreatedBy != subject {
+		logging.From(ctx).Warnf("%s could not reach importjob %d created by %s", subject, job.ID, job.CreatedBy)
+		return false, response.Send(ctx, response.NewProblem(
+			response.TypeForbidden,
+			fmt.Sprintf("the import job %d was created by another principal", job.ID),
+		))
+	}
+
+	return true, nil
+}
+
+// parseID parses the id param, when it is invalid the response is sent and ok is false
+func parseID(ctx *fiber.Ctx) (id int, ok bool, err error) {
+	idParam := ctx.Params("id")
+	id, err = strconv.Atoi(idParam)
+	if err != nil {
+		logging.From(ctx).Errorf("could not parse '%v' as id: %v", idParam, err)
+		return 0, false, response.Send(ctx, response.NewProblem(
+			response.TypeInvalidRequest,
+			fmt.Sprintf("the id '%s' is not an integer", idParam),
+		))
+	}
+	return id, true, nil
+}
@@ -0,0 +1,650 @@
+package importjob
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/server/auth"
+	"github.com/bgildson/unico-challenge/server/response"
+	"github.com/bgildson/unico-challenge/service/importjob"
+)
+
+func MatchRoute(app *fiber.App, method string, path string) bool {
+	stack := app.Stack()
+	for m := range stack {
+		for _, r := range stack[m] {
+			if r.Method == method && r.Path == path {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newUploadRequest(path, field, filename, content string, values map[string]string) *http.Request {
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	if field != "" {
+		fw, _ := w.CreateFormFile(field, filename)
+		fw.Write([]byte(content))
+	}
+	for k, v := range values {
+		w.WriteField(k, v)
+	}
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	req.Header.Set(fiber.HeaderContentType, w.FormDataContentType())
+	return req
+}
+
+func TestControllerRegister(t *testing.T) {
+	path := "/imports"
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{
+			name:   "when looking for create route",
+			method: http.MethodPost,
+			path:   path,
+		},
+		{
+			name:   "when looking for get by id route",
+			method: http.MethodGet,
+			path:   path + "/:id",
+		},
+		{
+			name:   "when looking for cancel route",
+			method: http.MethodDelete,
+			path:   path + "/:id",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			controller := New(nil, nil, nil, nil)
+
+			app := fiber.New()
+
+			controller.Register(app, path)
+
+			if !MatchRoute(app, tc.method, tc.path) {
+				t.Errorf("there's no route registered in method %s and path %s", tc.method, tc.path)
+			}
+		})
+	}
+}
+
+func TestControllerCreate(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/imports"
+	job := entity.ImportJob{
+		ID:        1,
+		Status:    entity.ImportJobPending,
+		Filename:  "feiras.csv",
+		Format:    "csv",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	testCases := []struct {
+		name        string
+		setupMocks  func(serv *importjob.MockService)
+		in          *http.Request
+		outStatus   int
+		outLocation string
+		outBody     interface{}
+	}{
+		{
+			name:       "when without file",
+			setupMocks: func(serv *importjob.MockService) {},
+			in:         newUploadRequest(path, "", "", "", nil),
+			outStatus:  http.StatusBadRequest,
+			outBody:    problem(response.TypeInvalidRequest, "the file is required in the field 'file'", "/imports"),
+		},
+		{
+			name:       "when the format is invalid",
+			setupMocks: func(serv *importjob.MockService) {},
+			in:         newUploadRequest(path, "file", "feiras.csv", "content", map[string]string{"format": "xml"}),
+			outStatus:  http.StatusBadRequest,
+			outBody:    problem(response.TypeInvalidRequest, "the format 'xml' is not supported", "/imports"),
+		},
+		{
+			name: "when service returns an error",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.
+					EXPECT().
+					Create("feiras.csv", "csv", entity.AllAreas, "", gomock.Any()).
+					Return(nil, errors.New("unexpected error"))
+			},
+			in:        newUploadRequest(path, "file", "feiras.csv", "content", map[string]string{"format": "csv"}),
+			outStatus: http.StatusInternalServerError,
+			outBody:   problem(response.TypeInternal, "could not create the import job, try again later and inform the request id if it persists", "/imports"),
+		},
+		{
+			name: "when success",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.
+					EXPECT().
+					Create("feiras.csv", "csv", entity.AllAreas, "", gomock.Any()).
+					Return(&job, nil)
+			},
+			in:          newUploadRequest(path, "file", "feiras.csv", "content", map[string]string{"format": "csv"}),
+			outStatus:   http.StatusAccepted,
+			outLocation: path + "/1",
+			outBody:     job,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serv := importjob.NewMockService(ctrl)
+			tc.setupMocks(serv)
+			controller := New(serv, nil, nil, nil)
+
+			app := fiber.New()
+
+			controller.Register(app, path)
+
+			res, err := app.Test(tc.in)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+			if location := res.Header.Get(fiber.HeaderLocation); location != tc.outLocation {
+				t.Errorf("was expecting the location %s, but returns %s", tc.outLocation, location)
+			}
+			var body interface{}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Errorf("could not decode body: %v", err)
+			}
+
+			// unmarshaling the expected body sorts the keys as in the returned one
+			var outBody interface{}
+			b0, _ := json.Marshal(tc.outBody)
+			json.Unmarshal(b0, &outBody)
+			b1, _ := json.Marshal(outBody)
+			b2, _ := json.Marshal(body)
+			if string(b1) != string(b2) {
+				t.Errorf("was expecting %s, but returns %s", b1, b2)
+			}
+		})
+	}
+}
+
+type authorizerFunc func(ctx context.Context, principal *entity.Principal, scope string) (entity.Areas, error)
+
+func (f authorizerFunc) Areas(ctx context.Context, principal *entity.Principal, scope string) (entity.Areas, error) {
+	return f(ctx, principal, scope)
+}
+
+func TestControllerCreateAreas(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/imports"
+	leste := entity.Areas{Grants: []entity.Grant{{Subject: "role:leste", Scope: entity.ScopeImport, Regiao5: "Leste"}}}
+	testCases := []struct {
+		name       string
+		setupMocks func(serv *importjob.MockService)
+		areas      entity.Areas
+		areasErr   error
+		outStatus  int
+	}{
+		{
+			name:       "when authorizer returns an error",
+			setupMocks: func(serv *importjob.MockService) {},
+			areasErr:   errors.New("unexpected error"),
+			outStatus:  http.StatusInternalServerError,
+		},
+		{
+			name:       "when there is no area granted",
+			setupMocks: func(serv *importjob.MockService) {},
+			areas:      entity.Areas{Grants: []entity.Grant{}},
+			outStatus:  http.StatusForbidden,
+		},
+		{
+			name: "when the import is restricted to the areas granted",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.
+					EXPECT().
+					Create("feiras.csv", "csv", leste, "", gomock.Any()).
+					Return(&entity.ImportJob{ID: 1}, nil)
+			},
+			areas:     leste,
+			outStatus: http.StatusAccepted,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serv := importjob.NewMockService(ctrl)
+			tc.setupMocks(serv)
+			authorizer := authorizerFunc(func(_ context.Context, _ *entity.Principal, scope string) (entity.Areas, error) {
+				if scope != entity.ScopeImport {
+					t.Errorf("was expecting the import scope, but receives %s", scope)
+				}
+				return tc.areas, tc.areasErr
+			})
+			controller := New(serv, nil, authorizer, nil)
+
+			app := fiber.New()
+
+			controller.Register(app, path)
+
+			res, err := app.Test(newUploadRequest(path, "file", "feiras.csv", "content", map[string]string{"format": "csv"}))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestControllerGetByID(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/imports"
+	job := entity.ImportJob{
+		ID:            1,
+		Status:        entity.ImportJobDone,
+		Filename:      "feiras.csv",
+		RowsRead:      2,
+		RowsPersisted: 1,
+		RowsFailed:    1,
+		Errors:        []string{"could not parse"},
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	testCases := []struct {
+		name       string
+		setupMocks func(serv *importjob.MockService)
+		in         string
+		outStatus  int
+		outBody    interface{}
+	}{
+		{
+			name:       "when invalid id",
+			setupMocks: func(serv *importjob.MockService) {},
+			in:         "a",
+			outStatus:  http.StatusBadRequest,
+			outBody:    problem(response.TypeInvalidRequest, "the id 'a' is not an integer", "/imports/a"),
+		},
+		{
+			name: "when does not exist",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.
+					EXPECT().
+					GetByID(1).
+					Return(nil, sql.ErrNoRows)
+			},
+			in:        "1",
+			outStatus: http.StatusNotFound,
+			outBody:   problem(response.TypeNotFound, "could not get the import job 1: it does not exist", "/imports/1"),
+		},
+		{
+			name: "when service returns an error",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.
+					EXPECT().
+					GetByID(1).
+					Return(nil, errors.New("unexpected error"))
+			},
+			in:        "1",
+			outStatus: http.StatusInternalServerError,
+			outBody:   problem(response.TypeInternal, "could not get the import job 1, try again later and inform the request id if it persists", "/imports/1"),
+		},
+		{
+			name: "when success",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.
+					EXPECT().
+					GetByID(1).
+					Return(&job, nil)
+			},
+			in:        "1",
+			outStatus: http.StatusOK,
+			outBody:   job,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serv := importjob.NewMockService(ctrl)
+			tc.setupMocks(serv)
+			controller := New(serv, nil, nil, nil)
+
+			app := fiber.New()
+
+			controller.Register(app, path)
+
+			res, err := app.Test(httptest.NewRequest(http.MethodGet, path+"/"+tc.in, nil))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+			var body interface{}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Errorf("could not decode body: %v", err)
+			}
+
+			// unmarshaling the expected body sorts the keys as in the returned one
+			var outBody interface{}
+			b0, _ := json.Marshal(tc.outBody)
+			json.Unmarshal(b0, &outBody)
+			b1, _ := json.Marshal(outBody)
+			b2, _ := json.Marshal(body)
+			if string(b1) != string(b2) {
+				t.Errorf("was expecting %s, but returns %s", b1, b2)
+			}
+		})
+	}
+}
+
+func TestControllerCancel(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/imports"
+	job := entity.ImportJob{
+		ID:        1,
+		Status:    entity.ImportJobRunning,
+		Filename:  "feiras.csv",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	testCases := []struct {
+		name       string
+		setupMocks func(serv *importjob.MockService)
+		in         string
+		outStatus  int
+		outBody    interface{}
+	}{
+		{
+			name:       "when invalid id",
+			setupMocks: func(serv *importjob.MockService) {},
+			in:         "a",
+			outStatus:  http.StatusBadRequest,
+			outBody:    problem(response.TypeInvalidRequest, "the id 'a' is not an integer", "/imports/a"),
+		},
+		{
+			name: "when does not exist",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.
+					EXPECT().
+					GetByID(1).
+					Return(nil, sql.ErrNoRows)
+			},
+			in:        "1",
+			outStatus: http.StatusNotFound,
+			outBody:   problem(response.TypeNotFound, "could not cancel the import job 1: it does not exist", "/imports/1"),
+		},
+		{
+			name: "when is not running",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.
+					EXPECT().
+					GetByID(1).
+					Return(&job, nil)
+				serv.
+					EXPECT().
+					Cancel(1).
+					Return(&job, importjob.ErrImportJobNotRunning)
+			},
+			in:        "1",
+			outStatus: http.StatusConflict,
+			outBody:   problem(response.TypeConflict, "could not cancel the import job 1: it is not running", "/imports/1"),
+		},
+		{
+			name: "when service returns an error",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.
+					EXPECT().
+					GetByID(1).
+					Return(&job, nil)
+				serv.
+					EXPECT().
+					Cancel(1).
+					Return(nil, errors.New("unexpected error"))
+			},
+			in:        "1",
+			outStatus: http.StatusInternalServerError,
+			outBody:   problem(response.TypeInternal, "could not cancel the import job 1, try again later and inform the request id if it persists", "/imports/1"),
+		},
+		{
+			name: "when success",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.
+					EXPECT().
+					GetByID(1).
+					Return(&job, nil)
+				serv.
+					EXPECT().
+					Cancel(1).
+					Return(&job, nil)
+			},
+			in:        "1",
+			outStatus: http.StatusAccepted,
+			outBody:   job,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serv := importjob.NewMockService(ctrl)
+			tc.setupMocks(serv)
+			controller := New(serv, nil, nil, nil)
+
+			app := fiber.New()
+
+			controller.Register(app, path)
+
+			res, err := app.Test(httptest.NewRequest(http.MethodDelete, path+"/"+tc.in, nil))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+			var body interface{}
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Errorf("could not decode body: %v", err)
+			}
+
+			// unmarshaling the expected body sorts the keys as in the returned one
+			var outBody interface{}
+			b0, _ := json.Marshal(tc.outBody)
+			json.Unmarshal(b0, &outBody)
+			b1, _ := json.Marshal(outBody)
+			b2, _ := json.Marshal(body)
+			if string(b1) != string(b2) {
+				t.Errorf("was expecting %s, but returns %s", b1, b2)
+			}
+		})
+	}
+}
+
+type importer struct{}
+
+func (importer) Authenticate(context.Context, string) (*entity.Principal, error) {
+	return &entity.Principal{Subject: "maria", Scopes: []string{entity.ScopeRead, entity.ScopeImport}}, nil
+}
+
+func TestControllerAuthorize(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/imports"
+	leste := entity.Areas{Grants: []entity.Grant{{Subject: "maria", Scope: entity.ScopeImport, Regiao5: "Leste"}}}
+	own := entity.ImportJob{ID: 1, Status: entity.ImportJobRunning, CreatedBy: "maria"}
+	other := entity.ImportJob{ID: 2, Status: entity.ImportJobRunning, CreatedBy: "joao"}
+	testCases := []struct {
+		name       string
+		setupMocks func(serv *importjob.MockService)
+		method     string
+		target     string
+		areas      entity.Areas
+		areasErr   error
+		outStatus  int
+	}{
+		{
+			name: "when gets a job created by the principal",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.EXPECT().GetByID(1).Return(&own, nil)
+			},
+			method:    http.MethodGet,
+			target:    path + "/1",
+			areas:     leste,
+			outStatus: http.StatusOK,
+		},
+		{
+			name: "when gets a job created by another principal",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.EXPECT().GetByID(2).Return(&other, nil)
+			},
+			method:    http.MethodGet,
+			target:    path + "/2",
+			areas:     leste,
+			outStatus: http.StatusForbidden,
+		},
+		{
+			name: "when gets a job created by another principal with every area granted",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.EXPECT().GetByID(2).Return(&other, nil)
+			},
+			method:    http.MethodGet,
+			target:    path + "/2",
+			areas:     entity.AllAreas,
+			outStatus: http.StatusOK,
+		},
+		{
+			name: "when cancels a job created by the principal",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.EXPECT().GetByID(1).Return(&own, nil)
+				serv.EXPECT().Cancel(1).Return(&own, nil)
+			},
+			method:    http.MethodDelete,
+			target:    path + "/1",
+			areas:     leste,
+			outStatus: http.StatusAccepted,
+		},
+		{
+			name: "when cancels a job created by another principal",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.EXPECT().GetByID(2).Return(&other, nil)
+			},
+			method:    http.MethodDelete,
+			target:    path + "/2",
+			areas:     leste,
+			outStatus: http.StatusForbidden,
+		},
+		{
+			name: "when the areas could not be listed",
+			setupMocks: func(serv *importjob.MockService) {
+				serv.EXPECT().GetByID(1).Return(&own, nil)
+			},
+			method:    http.MethodDelete,
+			target:    path + "/1",
+			areasErr:  errors.New("unexpected error"),
+			outStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serv := importjob.NewMockService(ctrl)
+			tc.setupMocks(serv)
+			authorizer := authorizerFunc(func(_ context.Context, principal *entity.Principal, scope string) (entity.Areas, error) {
+				if principal == nil || principal.Subject != "maria" || scope != entity.ScopeImport {
+					t.Errorf("was expecting maria and the import scope, but receives %+v and %s", principal, scope)
+				}
+				return tc.areas, tc.areasErr
+			})
+			controller := New(serv, auth.New(importer{}, false), authorizer, nil)
+
+			app := fiber.New()
+
+			controller.Register(app, path)
+
+			req := httptest.NewRequest(tc.method, tc.target, nil)
+			req.Header.Set(fiber.HeaderAuthorization, "Bearer token")
+			res, err := app.Test(req)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+		})
+	}
+}
+
+type reader struct{}
+
+func (reader) Authenticate(context.Context, string) (*entity.Principal, error) {
+	return &entity.Principal{Subject: "joao", Scopes: []string{entity.ScopeRead}}, nil
+}
+
+// TestControllerGetByIDIsNotPublic fails when a job, telling who created it and its errors, could
+// be read without the import scope while the reads are public
+func TestControllerGetByIDIsNotPublic(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	testCases := []struct {
+		name       string
+		credential string
+		outStatus  int
+	}{
+		{name: "when anonymous", outStatus: http.StatusUnauthorized},
+		{name: "when the principal has only the read scope", credential: "Bearer token", outStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			serv := importjob.NewMockService(ctrl)
+			controller := New(serv, auth.New(reader{}, true), nil, nil)
+
+			app := fiber.New()
+
+			controller.Register(app, "/imports")
+
+			req := httptest.NewRequest(http.MethodGet, "/imports/1", nil)
+			if tc.credential != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tc.credential)
+			}
+			res, err := app.Test(req)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+		})
+	}
+}
+
+// problem is the body expected for the errors, with the instance filled as response.Send does
+func problem(t response.Type, detail, instance string) response.Problem {
+	p := response.NewProblem(t, detail)
+	p.Instance = instance
+	return p
+}
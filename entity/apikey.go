@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+const (
+	// ScopeRead allows to query the feiras livres
+	ScopeRead = "read"
+	// ScopeWrite allows to create, update and remove the feiras livres
+	ScopeWrite = "write"
+	// ScopeImport allows to create, query and cancel the import jobs
+	ScopeImport = "import"
+	// ScopeAdmin allows everything
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every valid scope
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeImport, ScopeAdmin}
+
+// APIKey represents a key used to authenticate the requests, only its hash is stored
+type APIKey struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Revoked indicates if the key could not be used anymore
+func (k APIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
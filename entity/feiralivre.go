@@ -1,6 +1,23 @@
 package entity
 
-import "time"
+import (
+	"math"
+	"time"
+)
+
+// Microdegrees is the number of millionths of degree in a degree, the unit of the coordinates of
+// a feiralivre
+const Microdegrees = 1e6
+
+// FromDegrees converts a coordinate in decimal degrees to millionths of degree
+func FromDegrees(degrees float64) float64 {
+	return math.Round(degrees * Microdegrees)
+}
+
+// ToDegrees converts a coordinate in millionths of degree to decimal degrees
+func ToDegrees(microdegrees float64) float64 {
+	return microdegrees / Microdegrees
+}
 
 // FeiraLivre represents a feiralivre
 type FeiraLivre struct {
@@ -23,4 +40,6 @@ type FeiraLivre struct {
 	Referencia          string    `json:"referencia"`
 	CreatedAt           time.Time `json:"created_at"`
 	UpdatedAt           time.Time `json:"updated_at"`
+	CreatedBy           string    `json:"created_by,omitempty"`
+	UpdatedBy           string    `json:"updated_by,omitempty"`
 }
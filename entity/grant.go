@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+// Grant allows a subject to use a scope in an area, the subject could be a principal subject or a
+// role prefixed by RolePrefix, the zero CodigoSubprefeitura and the empty Regiao5 match any area
+type Grant struct {
+	ID                  int       `json:"id"`
+	Subject             string    `json:"subject"`
+	Scope               string    `json:"scope"`
+	CodigoSubprefeitura int       `json:"codigo_subprefeitura,omitempty"`
+	Regiao5             string    `json:"regiao5,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// Unrestricted indicates if the grant matches any area
+func (g Grant) Unrestricted() bool {
+	return g.CodigoSubprefeitura == 0 && g.Regiao5 == ""
+}
+
+// Covers indicates if the feiralivre is in the area of the grant
+func (g Grant) Covers(fl FeiraLivre) bool {
+	return (g.CodigoSubprefeitura == 0 || g.CodigoSubprefeitura == fl.CodigoSubprefeitura) &&
+		(g.Regiao5 == "" || strings.EqualFold(g.Regiao5, fl.Regiao5))
+}
+
+// Areas are where a principal could use a scope, All allows every area
+type Areas struct {
+	All    bool
+	Grants []Grant
+}
+
+// AllAreas allows every area
+var AllAreas = Areas{All: true}
+
+// Covers indicates if the feiralivre is in one of the areas
+func (a Areas) Covers(fl FeiraLivre) bool {
+	if a.All {
+		return true
+	}
+	for _, g := range a.Grants {
+		if g.Covers(fl) {
+			return true
+		}
+	}
+	return false
+}
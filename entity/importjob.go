@@ -0,0 +1,40 @@
+package entity
+
+import "time"
+
+const (
+	// ImportJobPending indicates that the import job is waiting to start
+	ImportJobPending = "pending"
+	// ImportJobRunning indicates that the import job is running
+	ImportJobRunning = "running"
+	// ImportJobDone indicates that the import job finished
+	ImportJobDone = "done"
+	// ImportJobFailed indicates that the import job could not finish
+	ImportJobFailed = "failed"
+	// ImportJobCanceled indicates that the import job was canceled
+	ImportJobCanceled = "canceled"
+	// ImportJobInterrupted indicates that the server stopped while the import job was running
+	ImportJobInterrupted = "interrupted"
+)
+
+// ImportJob represents an import executed in background
+type ImportJob struct {
+	ID            int        `json:"id"`
+	Status        string     `json:"status"`
+	Filename      string     `json:"filename"`
+	Format        string     `json:"format"`
+	RowsRead      int64      `json:"rows_read"`
+	RowsPersisted int64      `json:"rows_persisted"`
+	RowsFailed    int64      `json:"rows_failed"`
+	Errors        []string   `json:"errors"`
+	StartedAt     *time.Time `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	CreatedBy     string     `json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Finished indicates if the import job is not going to change anymore
+func (j ImportJob) Finished() bool {
+	return j.Status != ImportJobPending && j.Status != ImportJobRunning
+}
@@ -0,0 +1,35 @@
+package entity
+
+import "errors"
+
+// ErrInvalidCredential is wrapped by the authenticators when the credential is not accepted
+var ErrInvalidCredential = errors.New("invalid credential")
+
+// RolePrefix prefixes the role names when they are used as grant subjects
+const RolePrefix = "role:"
+
+// Principal represents who is doing the request, Roles are the roles informed by the token issuer
+type Principal struct {
+	Subject string   `json:"subject"`
+	Scopes  []string `json:"scopes"`
+	Roles   []string `json:"roles,omitempty"`
+}
+
+// Subjects returns the grant subjects matching the principal, its subject and its prefixed roles
+func (p Principal) Subjects() []string {
+	subjects := []string{p.Subject}
+	for _, r := range p.Roles {
+		subjects = append(subjects, RolePrefix+r)
+	}
+	return subjects
+}
+
+// HasScope indicates if the principal has the scope, the admin scope allows everything
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS import_job (
+  id SERIAL PRIMARY KEY,
+  status VARCHAR(16) NOT NULL,
+  filename VARCHAR(255) NOT NULL,
+  format VARCHAR(16),
+  rows_read INT NOT NULL DEFAULT 0,
+  rows_persisted INT NOT NULL DEFAULT 0,
+  rows_failed INT NOT NULL DEFAULT 0,
+  errors TEXT[] NOT NULL DEFAULT '{}',
+  started_at TIMESTAMPTZ(0),
+  finished_at TIMESTAMPTZ(0),
+  created_at TIMESTAMPTZ(0) DEFAULT NOW(),
+  updated_at TIMESTAMPTZ(0) DEFAULT NOW()
+);
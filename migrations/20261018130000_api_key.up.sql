@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS api_key (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  prefix VARCHAR(16) NOT NULL,
+  hash CHAR(64) NOT NULL UNIQUE,
+  scopes TEXT[] NOT NULL DEFAULT '{}',
+  revoked_at TIMESTAMPTZ(0),
+  created_at TIMESTAMPTZ(0) DEFAULT NOW()
+);
@@ -0,0 +1,3 @@
+ALTER TABLE feira_livre
+  ADD COLUMN IF NOT EXISTS created_by VARCHAR(255),
+  ADD COLUMN IF NOT EXISTS updated_by VARCHAR(255);
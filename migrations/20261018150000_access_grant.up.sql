@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS access_grant (
+  id SERIAL PRIMARY KEY,
+  subject VARCHAR(255) NOT NULL,
+  scope VARCHAR(16) NOT NULL,
+  codigo_subprefeitura INT,
+  regiao5 VARCHAR(255),
+  created_at TIMESTAMPTZ(0) DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS access_grant_subject_idx ON access_grant (subject);
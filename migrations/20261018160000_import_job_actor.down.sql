@@ -0,0 +1,2 @@
+ALTER TABLE import_job
+  DROP COLUMN IF EXISTS created_by;
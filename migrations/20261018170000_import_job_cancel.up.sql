@@ -0,0 +1,2 @@
+ALTER TABLE import_job
+  ADD COLUMN IF NOT EXISTS cancel_requested_at TIMESTAMPTZ(0);
@@ -0,0 +1,9 @@
+// Package migrations embeds the database migrations into the binary
+package migrations
+
+import "embed"
+
+// FS contains the migrations files, named as <version>_<title>.<up|down>.sql
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,15 @@
+package apikey
+
+import (
+	"context"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+// Repository represents how an apikey repository should be implemented
+type Repository interface {
+	Create(ctx context.Context, key entity.APIKey, hash string) (*entity.APIKey, error)
+	GetByHash(ctx context.Context, hash string) (*entity.APIKey, error)
+	List(ctx context.Context) ([]entity.APIKey, error)
+	Revoke(ctx context.Context, id int) error
+}
@@ -0,0 +1,53 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+type instrumentedRepository struct {
+	repo    Repository
+	observe func(method string, duration time.Duration, err error)
+}
+
+// NewInstrumentedRepository wraps repo calling observe with the duration and the result of every call
+func NewInstrumentedRepository(repo Repository, observe func(method string, duration time.Duration, err error)) Repository {
+	return &instrumentedRepository{
+		repo:    repo,
+		observe: observe,
+	}
+}
+
+// Create implements the instrumented Create
+func (r instrumentedRepository) Create(ctx context.Context, key entity.APIKey, hash string) (*entity.APIKey, error) {
+	start := time.Now()
+	res, err := r.repo.Create(ctx, key, hash)
+	r.observe("Create", time.Since(start), err)
+	return res, err
+}
+
+// GetByHash implements the instrumented GetByHash
+func (r instrumentedRepository) GetByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	start := time.Now()
+	res, err := r.repo.GetByHash(ctx, hash)
+	r.observe("GetByHash", time.Since(start), err)
+	return res, err
+}
+
+// List implements the instrumented List
+func (r instrumentedRepository) List(ctx context.Context) ([]entity.APIKey, error) {
+	start := time.Now()
+	res, err := r.repo.List(ctx)
+	r.observe("List", time.Since(start), err)
+	return res, err
+}
+
+// Revoke implements the instrumented Revoke
+func (r instrumentedRepository) Revoke(ctx context.Context, id int) error {
+	start := time.Now()
+	err := r.repo.Revoke(ctx, id)
+	r.observe("Revoke", time.Since(start), err)
+	return err
+}
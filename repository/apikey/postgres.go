@@ -0,0 +1,146 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+const (
+	// QueryCreate is the query used to create an apikey
+	QueryCreate = `
+INSERT INTO api_key
+    (name, prefix, hash, scopes)
+VALUES
+    ($1, $2, $3, $4)
+RETURNING id, created_at;`
+	// QueryByHash is the query used to get one apikey by the hash of the key
+	QueryByHash = `
+SELECT
+    id,
+    name,
+    prefix,
+    scopes,
+    revoked_at,
+    created_at
+FROM api_key
+WHERE hash = $1;`
+	// QueryList is the query used to list every apikey
+	QueryList = `
+SELECT
+    id,
+    name,
+    prefix,
+    scopes,
+    revoked_at,
+    created_at
+FROM api_key
+ORDER BY id;`
+	// QueryRevoke is the query used to revoke an apikey
+	QueryRevoke = `
+UPDATE
+    api_key
+SET
+    revoked_at = NOW()
+WHERE
+    id = $1
+    AND revoked_at IS NULL;`
+)
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a postgres repository for apikey
+func NewPostgresRepository(db *sql.DB) Repository {
+	return &postgresRepository{
+		db: db,
+	}
+}
+
+// Create implements how to query to create an apikey
+func (r postgresRepository) Create(ctx context.Context, key entity.APIKey, hash string) (*entity.APIKey, error) {
+	err := r.db.
+		QueryRowContext(
+			ctx,
+			QueryCreate,
+			key.Name,
+			key.Prefix,
+			hash,
+			pq.Array(key.Scopes),
+		).
+		Scan(
+			&key.ID,
+			&key.CreatedAt,
+		)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// GetByHash implements how to query to get an apikey by the hash of the key
+func (r postgresRepository) GetByHash(ctx context.Context, hash string) (*entity.APIKey, error) {
+	return scan(r.db.QueryRowContext(ctx, QueryByHash, hash))
+}
+
+// List implements how to query to list every apikey
+func (r postgresRepository) List(ctx context.Context) ([]entity.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, QueryList)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []entity.APIKey{}
+	for rows.Next() {
+		k, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+
+	return keys, rows.Err()
+}
+
+// Revoke implements how to revoke an apikey, sql.ErrNoRows is returned when it does not
+// exist or is already revoked
+func (r postgresRepository) Revoke(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, QueryRevoke, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scan(row scanner) (*entity.APIKey, error) {
+	var k entity.APIKey
+	err := row.Scan(
+		&k.ID,
+		&k.Name,
+		&k.Prefix,
+		pq.Array(&k.Scopes),
+		&k.RevokedAt,
+		&k.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
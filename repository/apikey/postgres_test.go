@@ -0,0 +1,244 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+var cols = []string{"id", "name", "prefix", "scopes", "revoked_at", "created_at"}
+
+func TestPostgresRepositoryCreate(t *testing.T) {
+	now := time.Now()
+	key := entity.APIKey{
+		Name:   "ci",
+		Prefix: "abcd1234",
+		Scopes: []string{entity.ScopeRead, entity.ScopeWrite},
+	}
+	created := key
+	created.ID = 1
+	created.CreatedAt = now
+	args := []driver.Value{key.Name, key.Prefix, "hash", pq.Array(key.Scopes)}
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		out        *entity.APIKey
+		hasError   bool
+	}{
+		{
+			name: "when db returns an error",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryCreate)).WithArgs(args...).WillReturnError(sql.ErrConnDone)
+			},
+			hasError: true,
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now)
+				mock.ExpectQuery(regexp.QuoteMeta(QueryCreate)).WithArgs(args...).WillReturnRows(rows)
+			},
+			out: &created,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			res, err := repo.Create(context.Background(), key, "hash")
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestPostgresRepositoryGetByHash(t *testing.T) {
+	now := time.Now()
+	key := entity.APIKey{
+		ID:        1,
+		Name:      "ci",
+		Prefix:    "abcd1234",
+		Scopes:    []string{entity.ScopeRead},
+		RevokedAt: &now,
+		CreatedAt: now,
+	}
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		out        *entity.APIKey
+		outErr     error
+	}{
+		{
+			name: "when does not exist",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryByHash)).WithArgs("hash").WillReturnError(sql.ErrNoRows)
+			},
+			outErr: sql.ErrNoRows,
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(cols).AddRow(1, "ci", "abcd1234", "{read}", now, now)
+				mock.ExpectQuery(regexp.QuoteMeta(QueryByHash)).WithArgs("hash").WillReturnRows(rows)
+			},
+			out: &key,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			res, err := repo.GetByHash(context.Background(), "hash")
+			if err != tc.outErr {
+				t.Errorf("was expecting %v, but returns %v", tc.outErr, err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestPostgresRepositoryList(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		out        []entity.APIKey
+		hasError   bool
+	}{
+		{
+			name: "when db returns an error",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryList)).WillReturnError(sql.ErrConnDone)
+			},
+			hasError: true,
+		},
+		{
+			name: "when there is no key",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryList)).WillReturnRows(sqlmock.NewRows(cols))
+			},
+			out: []entity.APIKey{},
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(cols).
+					AddRow(1, "ci", "abcd1234", "{read,write}", nil, now).
+					AddRow(2, "admin", "efgh5678", "{admin}", nil, now)
+				mock.ExpectQuery(regexp.QuoteMeta(QueryList)).WillReturnRows(rows)
+			},
+			out: []entity.APIKey{
+				{ID: 1, Name: "ci", Prefix: "abcd1234", Scopes: []string{"read", "write"}, CreatedAt: now},
+				{ID: 2, Name: "admin", Prefix: "efgh5678", Scopes: []string{"admin"}, CreatedAt: now},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			res, err := repo.List(context.Background())
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestPostgresRepositoryRevoke(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		outErr     error
+	}{
+		{
+			name: "when does not exist or is already revoked",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(QueryRevoke)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			outErr: sql.ErrNoRows,
+		},
+		{
+			name: "when db returns an error",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(QueryRevoke)).WithArgs(1).WillReturnError(sql.ErrConnDone)
+			},
+			outErr: sql.ErrConnDone,
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(QueryRevoke)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			if err := repo.Revoke(context.Background(), 1); err != tc.outErr {
+				t.Errorf("was expecting %v, but returns %v", tc.outErr, err)
+			}
+		})
+	}
+}
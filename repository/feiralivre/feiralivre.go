@@ -1,16 +1,57 @@
 package feiralivre
 
-import "github.com/bgildson/unico-challenge/entity"
+import (
+	"context"
+	"errors"
 
-// Repository represents how a feiralivre repository should be implemented
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+var (
+	// ErrDuplicated is the reason of a ConstraintError when the field must be unique
+	ErrDuplicated = errors.New("already exists")
+	// ErrTooLong is the reason of a ConstraintError when the field is longer than its column
+	ErrTooLong = errors.New("is too long")
+	// ErrRequired is the reason of a ConstraintError when the field must not be null
+	ErrRequired = errors.New("is required")
+	// ErrInvalid is the reason of a ConstraintError when the field fails a check of the table
+	ErrInvalid = errors.New("is invalid")
+	// ErrOutOfRange is the reason of a ConstraintError when the field is not an integer within the
+	// range of its column
+	ErrOutOfRange = errors.New("is not an integer within the range of its column")
+)
+
+// ConstraintError is returned when a feiralivre violates a constraint of the table, Field is the
+// json name of the field violating it, empty when it could not be found, and Reason is one of
+// ErrDuplicated, ErrTooLong, ErrRequired, ErrInvalid or ErrOutOfRange
+type ConstraintError struct {
+	Field  string
+	Reason error
+}
+
+func (e *ConstraintError) Error() string {
+	if e.Field == "" {
+		return "the feira livre " + e.Reason.Error()
+	}
+	return "the " + e.Field + " " + e.Reason.Error()
+}
+
+func (e *ConstraintError) Unwrap() error {
+	return e.Reason
+}
+
+// Repository represents how a feiralivre repository should be implemented, ctx carries the
+// cancellation and the trace of the caller
 type Repository interface {
-	GetByID(int) (*entity.FeiraLivre, error)
-	GetByQueryParams(QueryParams) ([]entity.FeiraLivre, error)
-	Create(entity.FeiraLivre) (*entity.FeiraLivre, error)
-	CreateOrUpdate(feiraLive entity.FeiraLivre) (*entity.FeiraLivre, error)
-	Update(int, entity.FeiraLivre) (*entity.FeiraLivre, error)
-	Remove(int) error
-	SyncPK() error
+	GetByID(ctx context.Context, id int) (*entity.FeiraLivre, error)
+	GetByQueryParams(ctx context.Context, qp QueryParams) ([]entity.FeiraLivre, error)
+	ForEach(ctx context.Context, qp QueryParams, fn func(entity.FeiraLivre) error) error
+	Create(ctx context.Context, feiraLive entity.FeiraLivre) (*entity.FeiraLivre, error)
+	CreateOrUpdate(ctx context.Context, feiraLive entity.FeiraLivre) (*entity.FeiraLivre, error)
+	CreateOrUpdateBatch(ctx context.Context, feirasLivres []entity.FeiraLivre) error
+	Update(ctx context.Context, id int, feiraLive entity.FeiraLivre) (*entity.FeiraLivre, error)
+	Remove(ctx context.Context, id int) error
+	SyncPK(ctx context.Context) error
 }
 
 // QueryParams contains the fields that could be used to query a feiralivre
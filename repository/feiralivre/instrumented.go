@@ -0,0 +1,93 @@
+package feiralivre
+
+import (
+	"context"
+	"time"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+type instrumentedRepository struct {
+	repo    Repository
+	observe func(method string, duration time.Duration, err error)
+}
+
+// NewInstrumentedRepository wraps repo calling observe with the duration and the result of every call
+func NewInstrumentedRepository(repo Repository, observe func(method string, duration time.Duration, err error)) Repository {
+	return &instrumentedRepository{
+		repo:    repo,
+		observe: observe,
+	}
+}
+
+// GetByID implements the instrumented GetByID
+func (r instrumentedRepository) GetByID(ctx context.Context, id int) (*entity.FeiraLivre, error) {
+	start := time.Now()
+	res, err := r.repo.GetByID(ctx, id)
+	r.observe("GetByID", time.Since(start), err)
+	return res, err
+}
+
+// GetByQueryParams implements the instrumented GetByQueryParams
+func (r instrumentedRepository) GetByQueryParams(ctx context.Context, qp QueryParams) ([]entity.FeiraLivre, error) {
+	start := time.Now()
+	res, err := r.repo.GetByQueryParams(ctx, qp)
+	r.observe("GetByQueryParams", time.Since(start), err)
+	return res, err
+}
+
+// ForEach implements the instrumented ForEach, the duration includes the time spent by fn
+func (r instrumentedRepository) ForEach(ctx context.Context, qp QueryParams, fn func(entity.FeiraLivre) error) error {
+	start := time.Now()
+	err := r.repo.ForEach(ctx, qp, fn)
+	r.observe("ForEach", time.Since(start), err)
+	return err
+}
+
+// Create implements the instrumented Create
+func (r instrumentedRepository) Create(ctx context.Context, fl entity.FeiraLivre) (*entity.FeiraLivre, error) {
+	start := time.Now()
+	res, err := r.repo.Create(ctx, fl)
+	r.observe("Create", time.Since(start), err)
+	return res, err
+}
+
+// CreateOrUpdate implements the instrumented CreateOrUpdate
+func (r instrumentedRepository) CreateOrUpdate(ctx context.Context, fl entity.FeiraLivre) (*entity.FeiraLivre, error) {
+	start := time.Now()
+	res, err := r.repo.CreateOrUpdate(ctx, fl)
+	r.observe("CreateOrUpdate", time.Since(start), err)
+	return res, err
+}
+
+// CreateOrUpdateBatch implements the instrumented CreateOrUpdateBatch
+func (r instrumentedRepository) CreateOrUpdateBatch(ctx context.Context, fls []entity.FeiraLivre) error {
+	start := time.Now()
+	err := r.repo.CreateOrUpdateBatch(ctx, fls)
+	r.observe("CreateOrUpdateBatch", time.Since(start), err)
+	return err
+}
+
+// Update implements the instrumented Update
+func (r instrumentedRepository) Update(ctx context.Context, id int, fl entity.FeiraLivre) (*entity.FeiraLivre, error) {
+	start := time.Now()
+	res, err := r.repo.Update(ctx, id, fl)
+	r.observe("Update", time.Since(start), err)
+	return res, err
+}
+
+// Remove implements the instrumented Remove
+func (r instrumentedRepository) Remove(ctx context.Context, id int) error {
+	start := time.Now()
+	err := r.repo.Remove(ctx, id)
+	r.observe("Remove", time.Since(start), err)
+	return err
+}
+
+// SyncPK implements the instrumented SyncPK
+func (r instrumentedRepository) SyncPK(ctx context.Context) error {
+	start := time.Now()
+	err := r.repo.SyncPK(ctx)
+	r.observe("SyncPK", time.Since(start), err)
+	return err
+}
@@ -0,0 +1,46 @@
+package feiralivre
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+func TestInstrumentedRepository(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := NewMockRepository(ctrl)
+	repo.
+		EXPECT().
+		GetByID(gomock.Any(), 1).
+		Return(&entity.FeiraLivre{ID: 1}, nil)
+	repo.
+		EXPECT().
+		Remove(gomock.Any(), 1).
+		Return(errors.New("unexpected error"))
+
+	var methods []string
+	var errs []error
+	r := NewInstrumentedRepository(repo, func(method string, _ time.Duration, err error) {
+		methods = append(methods, method)
+		errs = append(errs, err)
+	})
+
+	if res, err := r.GetByID(context.Background(), 1); err != nil || res.ID != 1 {
+		t.Errorf("was expecting the feiralivre 1, but returns %+v and %v", res, err)
+	}
+	if err := r.Remove(context.Background(), 1); err == nil {
+		t.Errorf("was expecting an error, but returns nil")
+	}
+
+	if len(methods) != 2 || methods[0] != "GetByID" || methods[1] != "Remove" {
+		t.Errorf("was expecting GetByID and Remove observed, but returns %v", methods)
+	}
+	if errs[0] != nil || errs[1] == nil {
+		t.Errorf("was expecting only the Remove error observed, but returns %v", errs)
+	}
+}
@@ -5,6 +5,7 @@
 package feiralivre
 
 import (
+	context "context"
 	reflect "reflect"
 
 	entity "github.com/bgildson/unico-challenge/entity"
@@ -35,104 +36,132 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 }
 
 // Create mocks base method.
-func (m *MockRepository) Create(arg0 entity.FeiraLivre) (*entity.FeiraLivre, error) {
+func (m *MockRepository) Create(ctx context.Context, feiraLive entity.FeiraLivre) (*entity.FeiraLivre, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", arg0)
+	ret := m.ctrl.Call(m, "Create", ctx, feiraLive)
 	ret0, _ := ret[0].(*entity.FeiraLivre)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockRepositoryMockRecorder) Create(arg0 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Create(ctx, feiraLive interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), ctx, feiraLive)
 }
 
 // CreateOrUpdate mocks base method.
-func (m *MockRepository) CreateOrUpdate(feiraLive entity.FeiraLivre) (*entity.FeiraLivre, error) {
+func (m *MockRepository) CreateOrUpdate(ctx context.Context, feiraLive entity.FeiraLivre) (*entity.FeiraLivre, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateOrUpdate", feiraLive)
+	ret := m.ctrl.Call(m, "CreateOrUpdate", ctx, feiraLive)
 	ret0, _ := ret[0].(*entity.FeiraLivre)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateOrUpdate indicates an expected call of CreateOrUpdate.
-func (mr *MockRepositoryMockRecorder) CreateOrUpdate(feiraLive interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) CreateOrUpdate(ctx, feiraLive interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrUpdate", reflect.TypeOf((*MockRepository)(nil).CreateOrUpdate), feiraLive)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrUpdate", reflect.TypeOf((*MockRepository)(nil).CreateOrUpdate), ctx, feiraLive)
+}
+
+// CreateOrUpdateBatch mocks base method.
+func (m *MockRepository) CreateOrUpdateBatch(ctx context.Context, feirasLivres []entity.FeiraLivre) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOrUpdateBatch", ctx, feirasLivres)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOrUpdateBatch indicates an expected call of CreateOrUpdateBatch.
+func (mr *MockRepositoryMockRecorder) CreateOrUpdateBatch(ctx, feirasLivres interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOrUpdateBatch", reflect.TypeOf((*MockRepository)(nil).CreateOrUpdateBatch), ctx, feirasLivres)
+}
+
+// ForEach mocks base method.
+func (m *MockRepository) ForEach(ctx context.Context, qp QueryParams, fn func(entity.FeiraLivre) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForEach", ctx, qp, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForEach indicates an expected call of ForEach.
+func (mr *MockRepositoryMockRecorder) ForEach(ctx, qp, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForEach", reflect.TypeOf((*MockRepository)(nil).ForEach), ctx, qp, fn)
 }
 
 // GetByID mocks base method.
-func (m *MockRepository) GetByID(arg0 int) (*entity.FeiraLivre, error) {
+func (m *MockRepository) GetByID(ctx context.Context, id int) (*entity.FeiraLivre, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByID", arg0)
+	ret := m.ctrl.Call(m, "GetByID", ctx, id)
 	ret0, _ := ret[0].(*entity.FeiraLivre)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByID indicates an expected call of GetByID.
-func (mr *MockRepositoryMockRecorder) GetByID(arg0 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetByID(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockRepository)(nil).GetByID), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockRepository)(nil).GetByID), ctx, id)
 }
 
 // GetByQueryParams mocks base method.
-func (m *MockRepository) GetByQueryParams(arg0 QueryParams) ([]entity.FeiraLivre, error) {
+func (m *MockRepository) GetByQueryParams(ctx context.Context, qp QueryParams) ([]entity.FeiraLivre, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetByQueryParams", arg0)
+	ret := m.ctrl.Call(m, "GetByQueryParams", ctx, qp)
 	ret0, _ := ret[0].([]entity.FeiraLivre)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetByQueryParams indicates an expected call of GetByQueryParams.
-func (mr *MockRepositoryMockRecorder) GetByQueryParams(arg0 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) GetByQueryParams(ctx, qp interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByQueryParams", reflect.TypeOf((*MockRepository)(nil).GetByQueryParams), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByQueryParams", reflect.TypeOf((*MockRepository)(nil).GetByQueryParams), ctx, qp)
 }
 
 // Remove mocks base method.
-func (m *MockRepository) Remove(arg0 int) error {
+func (m *MockRepository) Remove(ctx context.Context, id int) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Remove", arg0)
+	ret := m.ctrl.Call(m, "Remove", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Remove indicates an expected call of Remove.
-func (mr *MockRepositoryMockRecorder) Remove(arg0 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Remove(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockRepository)(nil).Remove), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockRepository)(nil).Remove), ctx, id)
 }
 
 // SyncPK mocks base method.
-func (m *MockRepository) SyncPK() error {
+func (m *MockRepository) SyncPK(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SyncPK")
+	ret := m.ctrl.Call(m, "SyncPK", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SyncPK indicates an expected call of SyncPK.
-func (mr *MockRepositoryMockRecorder) SyncPK() *gomock.Call {
+func (mr *MockRepositoryMockRecorder) SyncPK(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncPK", reflect.TypeOf((*MockRepository)(nil).SyncPK))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SyncPK", reflect.TypeOf((*MockRepository)(nil).SyncPK), ctx)
 }
 
 // Update mocks base method.
-func (m *MockRepository) Update(arg0 int, arg1 entity.FeiraLivre) (*entity.FeiraLivre, error) {
+func (m *MockRepository) Update(ctx context.Context, id int, feiraLive entity.FeiraLivre) (*entity.FeiraLivre, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret := m.ctrl.Call(m, "Update", ctx, id, feiraLive)
 	ret0, _ := ret[0].(*entity.FeiraLivre)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockRepositoryMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Update(ctx, id, feiraLive interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), ctx, id, feiraLive)
 }
@@ -1,9 +1,21 @@
 package feiralivre
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"math"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/bgildson/unico-challenge/entity"
 )
@@ -30,22 +42,24 @@ SELECT
     bairro,
     referencia,
     created_at,
-    updated_at
+    updated_at,
+    COALESCE(created_by, '') AS created_by,
+    COALESCE(updated_by, '') AS updated_by
 FROM feira_livre
 WHERE id = $1;`
 	// QueryCreate is the query used to create a feiralivre
 	QueryCreate = `
 INSERT INTO feira_livre
-    (latitude, longitude, setor_censitario, area_ponderacao, codigo_distrito, distrito, codigo_subprefeitura, subprefeitura, regiao5, regiao8, nome_feira, registro, logradouro, numero, bairro, referencia)
+    (latitude, longitude, setor_censitario, area_ponderacao, codigo_distrito, distrito, codigo_subprefeitura, subprefeitura, regiao5, regiao8, nome_feira, registro, logradouro, numero, bairro, referencia, created_by, updated_by)
 VALUES
-    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $17)
 RETURNING id, created_at, updated_at;`
 	// QueryCreateOrUpdate is the query used to create or update a feiralivre
 	QueryCreateOrUpdate = `
 INSERT INTO feira_livre
-    (id, latitude, longitude, setor_censitario, area_ponderacao, codigo_distrito, distrito, codigo_subprefeitura, subprefeitura, regiao5, regiao8, nome_feira, registro, logradouro, numero, bairro, referencia)
+    (id, latitude, longitude, setor_censitario, area_ponderacao, codigo_distrito, distrito, codigo_subprefeitura, subprefeitura, regiao5, regiao8, nome_feira, registro, logradouro, numero, bairro, referencia, created_by, updated_by)
 VALUES
-    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+    ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $34, $35)
 ON CONFLICT (id)
 DO
     UPDATE SET
@@ -65,6 +79,7 @@ DO
         numero = $31,
         bairro = $32,
         referencia = $33,
+        updated_by = $35,
         updated_at = NOW()
 RETURNING id, created_at, updated_at;`
 	// QueryUpdate is the query used to update a feiralivre
@@ -88,9 +103,10 @@ SET
     numero = $14,
     bairro = $15,
     referencia = $16,
+    updated_by = $17,
 	updated_at = NOW()
 WHERE
-    id = $17
+    id = $18
 RETURNING updated_at;`
 	// QueryRemove is the query used to remove a feiralivre
 	QueryRemove = `
@@ -124,7 +140,9 @@ SELECT
     bairro,
     referencia,
     created_at,
-    updated_at
+    updated_at,
+    COALESCE(created_by, '') AS created_by,
+    COALESCE(updated_by, '') AS updated_by
 FROM feira_livre`
 
 	where := `
@@ -192,6 +210,176 @@ func ParseQueryParamsToArgs(qp QueryParams) (args []interface{}) {
 	return
 }
 
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanFeiraLivre reads a feiralivre selected with all the columns
+func scanFeiraLivre(row scanner) (*entity.FeiraLivre, error) {
+	var f entity.FeiraLivre
+	err := row.Scan(
+		&f.ID,
+		&f.Latitude,
+		&f.Longitude,
+		&f.SetorCensitario,
+		&f.AreaPonderacao,
+		&f.CodigoDistrito,
+		&f.Distrito,
+		&f.CodigoSubprefeitura,
+		&f.Subprefeitura,
+		&f.Regiao5,
+		&f.Regiao8,
+		&f.NomeFeira,
+		&f.Registro,
+		&f.Logradouro,
+		&f.Numero,
+		&f.Bairro,
+		&f.Referencia,
+		&f.CreatedAt,
+		&f.UpdatedAt,
+		&f.CreatedBy,
+		&f.UpdatedBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// ColumnLengths are the lengths of the VARCHAR columns of feira_livre, the same declared by the
+// migrations and by the maxLength of the OpenAPI schema, kept in step by the tests
+var ColumnLengths = map[string]int{
+	"distrito":      50,
+	"subprefeitura": 100,
+	"regiao5":       16,
+	"regiao8":       16,
+	"nome_feira":    50,
+	"registro":      10,
+	"logradouro":    80,
+	"numero":        30,
+	"bairro":        40,
+	"referencia":    80,
+	"created_by":    255,
+	"updated_by":    255,
+}
+
+// translateError translates the constraint violations and the invalid values of fl into a
+// ConstraintError, the other errors are returned as they are
+func translateError(err error, fl entity.FeiraLivre) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code.Name() {
+	case "unique_violation":
+		return &ConstraintError{Field: constraintField(pqErr.Constraint), Reason: ErrDuplicated}
+	case "string_data_right_truncation":
+		return &ConstraintError{Field: tooLong(fl), Reason: ErrTooLong}
+	case "not_null_violation":
+		return &ConstraintError{Field: pqErr.Column, Reason: ErrRequired}
+	case "check_violation":
+		return &ConstraintError{Field: constraintField(pqErr.Constraint), Reason: ErrInvalid}
+	case "invalid_text_representation", "numeric_value_out_of_range":
+		return &ConstraintError{Field: outOfRange(fl), Reason: ErrOutOfRange}
+	}
+	return err
+}
+
+// constraintField returns the column of a constraint named as postgres does by default, as
+// feira_livre_pkey or feira_livre_registro_key, or empty when it is named otherwise
+func constraintField(constraint string) string {
+	if constraint == "feira_livre_pkey" {
+		return "id"
+	}
+	if !strings.HasPrefix(constraint, "feira_livre_") {
+		return ""
+	}
+	for _, suffix := range []string{"_key", "_check"} {
+		if strings.HasSuffix(constraint, suffix) {
+			return strings.TrimSuffix(strings.TrimPrefix(constraint, "feira_livre_"), suffix)
+		}
+	}
+	return ""
+}
+
+// outOfRange returns the first field of fl stored in an INT column that is fractional or outside
+// its range, since postgres does not tell the column of an invalid_text_representation or of a
+// numeric_value_out_of_range error, the BIGINT columns fit any int
+func outOfRange(fl entity.FeiraLivre) string {
+	fields := []struct {
+		name  string
+		value float64
+	}{
+		{"latitude", fl.Latitude},
+		{"longitude", fl.Longitude},
+		{"codigo_distrito", float64(fl.CodigoDistrito)},
+		{"codigo_subprefeitura", float64(fl.CodigoSubprefeitura)},
+	}
+	for _, f := range fields {
+		if f.value != math.Trunc(f.value) || f.value < math.MinInt32 || f.value > math.MaxInt32 {
+			return f.name
+		}
+	}
+	return ""
+}
+
+// tooLong returns the first field of fl longer than its column, since postgres does not tell the
+// column of a string_data_right_truncation error
+func tooLong(fl entity.FeiraLivre) string {
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"distrito", fl.Distrito},
+		{"subprefeitura", fl.Subprefeitura},
+		{"regiao5", fl.Regiao5},
+		{"regiao8", fl.Regiao8},
+		{"nome_feira", fl.NomeFeira},
+		{"registro", fl.Registro},
+		{"logradouro", fl.Logradouro},
+		{"numero", fl.Numero},
+		{"bairro", fl.Bairro},
+		{"referencia", fl.Referencia},
+		{"created_by", fl.CreatedBy},
+		{"updated_by", fl.UpdatedBy},
+	}
+	for _, f := range fields {
+		if utf8.RuneCountInString(f.value) > ColumnLengths[f.name] {
+			return f.name
+		}
+	}
+	return ""
+}
+
+var tracer = otel.Tracer("github.com/bgildson/unico-challenge/repository/feiralivre")
+
+// startSpan starts the span of a repository call, statement is the name of the query used
+func startSpan(ctx context.Context, method, statement string) (context.Context, trace.Span) {
+	return tracer.Start(
+		ctx,
+		"feiralivre."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBSQLTableKey.String("feira_livre"),
+			attribute.String("db.statement.name", statement),
+		),
+	)
+}
+
+// endSpan records the rows affected and the error of a repository call
+func endSpan(span trace.Span, rows int64, err error) {
+	span.SetAttributes(attribute.Int64("db.rows", rows))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
 type postgresRepository struct {
 	db *sql.DB
 }
@@ -204,84 +392,88 @@ func NewPostgresRepository(db *sql.DB) Repository {
 }
 
 // GetByQueryParams implements how to query to get feiralivre based on query params
-func (r postgresRepository) GetByQueryParams(qp QueryParams) ([]entity.FeiraLivre, error) {
+func (r postgresRepository) GetByQueryParams(ctx context.Context, qp QueryParams) (result []entity.FeiraLivre, err error) {
+	ctx, span := startSpan(ctx, "GetByQueryParams", "ParseQueryParamsToQuery")
+	defer func() { endSpan(span, int64(len(result)), err) }()
+
 	q := ParseQueryParamsToQuery(qp)
 	a := ParseQueryParamsToArgs(qp)
-	res, err := r.db.Query(q, a...)
+	res, err := r.db.QueryContext(ctx, q, a...)
 	if err != nil {
 		return nil, err
 	}
 	defer res.Close()
 
-	result := []entity.FeiraLivre{}
+	result = []entity.FeiraLivre{}
 	for res.Next() {
-		f := entity.FeiraLivre{}
-		err := res.Scan(
-			&f.ID,
-			&f.Latitude,
-			&f.Longitude,
-			&f.SetorCensitario,
-			&f.AreaPonderacao,
-			&f.CodigoDistrito,
-			&f.Distrito,
-			&f.CodigoSubprefeitura,
-			&f.Subprefeitura,
-			&f.Regiao5,
-			&f.Regiao8,
-			&f.NomeFeira,
-			&f.Registro,
-			&f.Logradouro,
-			&f.Numero,
-			&f.Bairro,
-			&f.Referencia,
-			&f.CreatedAt,
-			&f.UpdatedAt,
-		)
+		f, err := scanFeiraLivre(res)
 		if err != nil {
 			return nil, err
 		}
-		result = append(result, f)
+		result = append(result, *f)
 	}
 
 	return result, nil
 }
 
-// GetByID implements how to query to get a feiralivre by id
-func (r postgresRepository) GetByID(id int) (*entity.FeiraLivre, error) {
-	row := r.db.QueryRow(QueryByID, id)
-	var f entity.FeiraLivre
-	err := row.Scan(
-		&f.ID,
-		&f.Latitude,
-		&f.Longitude,
-		&f.SetorCensitario,
-		&f.AreaPonderacao,
-		&f.CodigoDistrito,
-		&f.Distrito,
-		&f.CodigoSubprefeitura,
-		&f.Subprefeitura,
-		&f.Regiao5,
-		&f.Regiao8,
-		&f.NomeFeira,
-		&f.Registro,
-		&f.Logradouro,
-		&f.Numero,
-		&f.Bairro,
-		&f.Referencia,
-		&f.CreatedAt,
-		&f.UpdatedAt,
-	)
+// ForEach implements how to iterate over every feiralivre matching the query params without
+// keeping them in memory, a zero limit means no limit
+func (r postgresRepository) ForEach(ctx context.Context, qp QueryParams, fn func(entity.FeiraLivre) error) (err error) {
+	var rows int64
+	ctx, span := startSpan(ctx, "ForEach", "ParseQueryParamsToQuery")
+	defer func() { endSpan(span, rows, err) }()
+
+	q := ParseQueryParamsToQuery(qp)
+	a := ParseQueryParamsToArgs(qp)
+	if qp.Pagination.Limit == 0 {
+		// LIMIT NULL is the same as omitting the LIMIT clause
+		a[len(a)-1] = nil
+	}
+
+	res, err := r.db.QueryContext(ctx, q, a...)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer res.Close()
 
-	return &f, nil
+	for res.Next() {
+		f, err := scanFeiraLivre(res)
+		if err != nil {
+			return err
+		}
+		if err := fn(*f); err != nil {
+			return err
+		}
+		rows++
+	}
+
+	return res.Err()
+}
+
+// GetByID implements how to query to get a feiralivre by id
+func (r postgresRepository) GetByID(ctx context.Context, id int) (res *entity.FeiraLivre, err error) {
+	ctx, span := startSpan(ctx, "GetByID", "QueryByID")
+	defer func() { endSpan(span, rowsOf(res), err) }()
+
+	return scanFeiraLivre(r.db.QueryRowContext(ctx, QueryByID, id))
+}
+
+// rowsOf returns the number of rows represented by a single result
+func rowsOf(fl *entity.FeiraLivre) int64 {
+	if fl == nil {
+		return 0
+	}
+	return 1
 }
 
 // Create implements how to query to create a feiralivre
-func (r postgresRepository) Create(feiraLive entity.FeiraLivre) (*entity.FeiraLivre, error) {
-	err := r.db.
-		QueryRow(
+func (r postgresRepository) Create(ctx context.Context, feiraLive entity.FeiraLivre) (res *entity.FeiraLivre, err error) {
+	ctx, span := startSpan(ctx, "Create", "QueryCreate")
+	defer func() { endSpan(span, rowsOf(res), err) }()
+
+	err = r.db.
+		QueryRowContext(
+			ctx,
 			QueryCreate,
 			feiraLive.Latitude,
 			feiraLive.Longitude,
@@ -299,6 +491,7 @@ func (r postgresRepository) Create(feiraLive entity.FeiraLivre) (*entity.FeiraLi
 			feiraLive.Numero,
 			feiraLive.Bairro,
 			feiraLive.Referencia,
+			actor(feiraLive.CreatedBy),
 		).
 		Scan(
 			&feiraLive.ID,
@@ -306,67 +499,118 @@ func (r postgresRepository) Create(feiraLive entity.FeiraLivre) (*entity.FeiraLi
 			&feiraLive.UpdatedAt,
 		)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err, feiraLive)
 	}
 
 	return &feiraLive, nil
 }
 
+// actor returns the subject recorded as who changed a feiralivre, NULL when it is unknown
+func actor(subject string) sql.NullString {
+	return sql.NullString{String: subject, Valid: subject != ""}
+}
+
+// createOrUpdateArgs creates the args used by QueryCreateOrUpdate
+func createOrUpdateArgs(feiraLive entity.FeiraLivre) []interface{} {
+	return []interface{}{
+		feiraLive.ID,
+		feiraLive.Latitude,
+		feiraLive.Longitude,
+		feiraLive.SetorCensitario,
+		feiraLive.AreaPonderacao,
+		feiraLive.CodigoDistrito,
+		feiraLive.Distrito,
+		feiraLive.CodigoSubprefeitura,
+		feiraLive.Subprefeitura,
+		feiraLive.Regiao5,
+		feiraLive.Regiao8,
+		feiraLive.NomeFeira,
+		feiraLive.Registro,
+		feiraLive.Logradouro,
+		feiraLive.Numero,
+		feiraLive.Bairro,
+		feiraLive.Referencia,
+		feiraLive.Latitude,
+		feiraLive.Longitude,
+		feiraLive.SetorCensitario,
+		feiraLive.AreaPonderacao,
+		feiraLive.CodigoDistrito,
+		feiraLive.Distrito,
+		feiraLive.CodigoSubprefeitura,
+		feiraLive.Subprefeitura,
+		feiraLive.Regiao5,
+		feiraLive.Regiao8,
+		feiraLive.NomeFeira,
+		feiraLive.Registro,
+		feiraLive.Logradouro,
+		feiraLive.Numero,
+		feiraLive.Bairro,
+		feiraLive.Referencia,
+		actor(feiraLive.CreatedBy),
+		actor(feiraLive.UpdatedBy),
+	}
+}
+
 // CreateOrUpdate implements how to create or update a feiralivre
-func (r postgresRepository) CreateOrUpdate(feiraLive entity.FeiraLivre) (*entity.FeiraLivre, error) {
-	err := r.db.
-		QueryRow(
-			QueryCreateOrUpdate,
-			feiraLive.ID,
-			feiraLive.Latitude,
-			feiraLive.Longitude,
-			feiraLive.SetorCensitario,
-			feiraLive.AreaPonderacao,
-			feiraLive.CodigoDistrito,
-			feiraLive.Distrito,
-			feiraLive.CodigoSubprefeitura,
-			feiraLive.Subprefeitura,
-			feiraLive.Regiao5,
-			feiraLive.Regiao8,
-			feiraLive.NomeFeira,
-			feiraLive.Registro,
-			feiraLive.Logradouro,
-			feiraLive.Numero,
-			feiraLive.Bairro,
-			feiraLive.Referencia,
-			feiraLive.Latitude,
-			feiraLive.Longitude,
-			feiraLive.SetorCensitario,
-			feiraLive.AreaPonderacao,
-			feiraLive.CodigoDistrito,
-			feiraLive.Distrito,
-			feiraLive.CodigoSubprefeitura,
-			feiraLive.Subprefeitura,
-			feiraLive.Regiao5,
-			feiraLive.Regiao8,
-			feiraLive.NomeFeira,
-			feiraLive.Registro,
-			feiraLive.Logradouro,
-			feiraLive.Numero,
-			feiraLive.Bairro,
-			feiraLive.Referencia,
-		).
+func (r postgresRepository) CreateOrUpdate(ctx context.Context, feiraLive entity.FeiraLivre) (res *entity.FeiraLivre, err error) {
+	ctx, span := startSpan(ctx, "CreateOrUpdate", "QueryCreateOrUpdate")
+	defer func() { endSpan(span, rowsOf(res), err) }()
+
+	err = r.db.
+		QueryRowContext(ctx, QueryCreateOrUpdate, createOrUpdateArgs(feiraLive)...).
 		Scan(
 			&feiraLive.ID,
 			&feiraLive.CreatedAt,
 			&feiraLive.UpdatedAt,
 		)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err, feiraLive)
 	}
 
 	return &feiraLive, nil
 }
 
+// CreateOrUpdateBatch implements how to create or update many feiralivre in a single transaction
+func (r postgresRepository) CreateOrUpdateBatch(ctx context.Context, feirasLivres []entity.FeiraLivre) (err error) {
+	var rows int64
+	ctx, span := startSpan(ctx, "CreateOrUpdateBatch", "QueryCreateOrUpdate")
+	defer func() { endSpan(span, rows, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, QueryCreateOrUpdate)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, fl := range feirasLivres {
+		if _, err := stmt.ExecContext(ctx, createOrUpdateArgs(fl)...); err != nil {
+			tx.Rollback()
+			return translateError(err, fl)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	rows = int64(len(feirasLivres))
+
+	return nil
+}
+
 // Update implements how to update a feiralivre
-func (r postgresRepository) Update(id int, feiraLive entity.FeiraLivre) (*entity.FeiraLivre, error) {
-	err := r.db.
-		QueryRow(
+func (r postgresRepository) Update(ctx context.Context, id int, feiraLive entity.FeiraLivre) (res *entity.FeiraLivre, err error) {
+	ctx, span := startSpan(ctx, "Update", "QueryUpdate")
+	defer func() { endSpan(span, rowsOf(res), err) }()
+
+	err = r.db.
+		QueryRowContext(
+			ctx,
 			QueryUpdate,
 			feiraLive.Latitude,
 			feiraLive.Longitude,
@@ -384,26 +628,44 @@ func (r postgresRepository) Update(id int, feiraLive entity.FeiraLivre) (*entity
 			feiraLive.Numero,
 			feiraLive.Bairro,
 			feiraLive.Referencia,
+			actor(feiraLive.UpdatedBy),
 			id,
 		).
 		Scan(
 			&feiraLive.UpdatedAt,
 		)
 	if err != nil {
-		return nil, err
+		return nil, translateError(err, feiraLive)
 	}
 
 	return &feiraLive, nil
 }
 
-// Remove implements how to remove a feiralivre
-func (r postgresRepository) Remove(id int) error {
-	_, err := r.db.Exec(QueryRemove, id)
-	return err
+// Remove implements how to remove a feiralivre, returning sql.ErrNoRows when it does not exist
+func (r postgresRepository) Remove(ctx context.Context, id int) (err error) {
+	var rows int64
+	ctx, span := startSpan(ctx, "Remove", "QueryRemove")
+	defer func() { endSpan(span, rows, err) }()
+
+	res, err := r.db.ExecContext(ctx, QueryRemove, id)
+	if err != nil {
+		return err
+	}
+	if rows, err = res.RowsAffected(); err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
 }
 
 // SyncPK implements how to sync the feiralivre table pk
-func (r postgresRepository) SyncPK() error {
-	_, err := r.db.Exec(QuerySyncPK)
+func (r postgresRepository) SyncPK(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, "SyncPK", "QuerySyncPK")
+	defer func() { endSpan(span, 0, err) }()
+
+	_, err = r.db.ExecContext(ctx, QuerySyncPK)
 	return err
 }
@@ -1,17 +1,23 @@
 package feiralivre
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"errors"
+	"io/fs"
 	"reflect"
 	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 
 	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/migrations"
 )
 
 func TestParseQueryParamsToQuery(t *testing.T) {
@@ -43,7 +49,9 @@ SELECT
     bairro,
     referencia,
     created_at,
-    updated_at
+    updated_at,
+    COALESCE(created_by, '') AS created_by,
+    COALESCE(updated_by, '') AS updated_by
 FROM feira_livre
 OFFSET $1
 LIMIT $2;`,
@@ -73,7 +81,9 @@ SELECT
     bairro,
     referencia,
     created_at,
-    updated_at
+    updated_at,
+    COALESCE(created_by, '') AS created_by,
+    COALESCE(updated_by, '') AS updated_by
 FROM feira_livre
 WHERE
     distrito ILIKE '%' || $1 || '%'
@@ -105,7 +115,9 @@ SELECT
     bairro,
     referencia,
     created_at,
-    updated_at
+    updated_at,
+    COALESCE(created_by, '') AS created_by,
+    COALESCE(updated_by, '') AS updated_by
 FROM feira_livre
 WHERE
     regiao5 ILIKE '%' || $1 || '%'
@@ -137,7 +149,9 @@ SELECT
     bairro,
     referencia,
     created_at,
-    updated_at
+    updated_at,
+    COALESCE(created_by, '') AS created_by,
+    COALESCE(updated_by, '') AS updated_by
 FROM feira_livre
 WHERE
     nome_feira ILIKE '%' || $1 || '%'
@@ -169,7 +183,9 @@ SELECT
     bairro,
     referencia,
     created_at,
-    updated_at
+    updated_at,
+    COALESCE(created_by, '') AS created_by,
+    COALESCE(updated_by, '') AS updated_by
 FROM feira_livre
 WHERE
     bairro ILIKE '%' || $1 || '%'
@@ -202,7 +218,9 @@ SELECT
     bairro,
     referencia,
     created_at,
-    updated_at
+    updated_at,
+    COALESCE(created_by, '') AS created_by,
+    COALESCE(updated_by, '') AS updated_by
 FROM feira_livre
 WHERE
     distrito ILIKE '%' || $1 || '%' AND
@@ -238,7 +256,9 @@ SELECT
     bairro,
     referencia,
     created_at,
-    updated_at
+    updated_at,
+    COALESCE(created_by, '') AS created_by,
+    COALESCE(updated_by, '') AS updated_by
 FROM feira_livre
 WHERE
     distrito ILIKE '%' || $1 || '%' AND
@@ -365,9 +385,9 @@ func TestPostgresRepositoryGetByQueryParams(t *testing.T) {
 	for _, v := range args {
 		argsDriverValue = append(argsDriverValue, v)
 	}
-	cols := []string{"id", "latitude", "longitude", "setor_censitario", "area_ponderacao", "codigo_distrito", "distrito", "codigo_subprefeitura", "subprefeitura", "regiao5", "regiao8", "nome_feira", "registro", "logradouro", "numero", "bairro", "referencia", "created_at", "updated_at"}
-	vals := []driver.Value{fl.ID, fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia, fl.CreatedAt, fl.UpdatedAt}
-	valsErr := []driver.Value{"", -46548146, -23568390, 355030885000019, 3550308005040, 87, "VILA FORMOSA", 26, "ARICANDUVA", "Leste", "Leste 1", "PRAÇA LEÃO X", "7216-8", "RUA CODAJÁS", 45, "VILA FORMOSA", "PRAÇA MARECHAL LEITE BANDEIRA", time.Now().String(), time.Now().String()}
+	cols := []string{"id", "latitude", "longitude", "setor_censitario", "area_ponderacao", "codigo_distrito", "distrito", "codigo_subprefeitura", "subprefeitura", "regiao5", "regiao8", "nome_feira", "registro", "logradouro", "numero", "bairro", "referencia", "created_at", "updated_at", "created_by", "updated_by"}
+	vals := []driver.Value{fl.ID, fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia, fl.CreatedAt, fl.UpdatedAt, fl.CreatedBy, fl.UpdatedBy}
+	valsErr := []driver.Value{"", -46548146, -23568390, 355030885000019, 3550308005040, 87, "VILA FORMOSA", 26, "ARICANDUVA", "Leste", "Leste 1", "PRAÇA LEÃO X", "7216-8", "RUA CODAJÁS", 45, "VILA FORMOSA", "PRAÇA MARECHAL LEITE BANDEIRA", time.Now().String(), time.Now().String(), "", ""}
 	testCases := []struct {
 		name       string
 		setupMocks func(mock sqlmock.Sqlmock)
@@ -418,7 +438,103 @@ func TestPostgresRepositoryGetByQueryParams(t *testing.T) {
 
 			repo := NewPostgresRepository(db)
 
-			res, err := repo.GetByQueryParams(tc.in)
+			res, err := repo.GetByQueryParams(context.Background(), tc.in)
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestPostgresRepositoryForEach(t *testing.T) {
+	fl := entity.FeiraLivre{
+		ID:                  1,
+		Latitude:            -23568390,
+		Longitude:           -46548146,
+		SetorCensitario:     355030885000019,
+		AreaPonderacao:      3550308005040,
+		CodigoDistrito:      87,
+		Distrito:            "VILA FORMOSA",
+		CodigoSubprefeitura: 26,
+		Subprefeitura:       "ARICANDUVA",
+		Regiao5:             "Leste",
+		Regiao8:             "Leste 1",
+		NomeFeira:           "PRAÇA LEÃO X",
+		Registro:            "7216-8",
+		Logradouro:          "RUA CODAJÁS",
+		Numero:              "45",
+		Bairro:              "VILA FORMOSA",
+		Referencia:          "PRAÇA MARECHAL LEITE BANDEIRA",
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
+	}
+	queryParams := QueryParams{
+		Distrito: "any",
+	}
+	query := regexp.QuoteMeta(ParseQueryParamsToQuery(queryParams))
+	argsDriverValue := []driver.Value{"any", 0, nil}
+	cols := []string{"id", "latitude", "longitude", "setor_censitario", "area_ponderacao", "codigo_distrito", "distrito", "codigo_subprefeitura", "subprefeitura", "regiao5", "regiao8", "nome_feira", "registro", "logradouro", "numero", "bairro", "referencia", "created_at", "updated_at", "created_by", "updated_by"}
+	vals := []driver.Value{fl.ID, fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia, fl.CreatedAt, fl.UpdatedAt, fl.CreatedBy, fl.UpdatedBy}
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		inFn       func(entity.FeiraLivre) error
+		out        []entity.FeiraLivre
+		hasError   bool
+	}{
+		{
+			name: "when occur an error to apply query",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(query).WithArgs(argsDriverValue...).WillReturnError(errors.New("unexpected error"))
+			},
+			inFn:     func(entity.FeiraLivre) error { return nil },
+			hasError: true,
+		},
+		{
+			name: "when fn returns an error",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(cols).AddRow(vals...).AddRow(vals...)
+				mock.ExpectQuery(query).WithArgs(argsDriverValue...).WillReturnRows(rows)
+			},
+			inFn:     func(entity.FeiraLivre) error { return errors.New("unexpected error") },
+			out:      []entity.FeiraLivre{fl},
+			hasError: true,
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(cols).AddRow(vals...).AddRow(vals...)
+				mock.ExpectQuery(query).WithArgs(argsDriverValue...).WillReturnRows(rows)
+			},
+			inFn:     func(entity.FeiraLivre) error { return nil },
+			out:      []entity.FeiraLivre{fl, fl},
+			hasError: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			var res []entity.FeiraLivre
+			err = repo.ForEach(context.Background(), queryParams, func(f entity.FeiraLivre) error {
+				res = append(res, f)
+				return tc.inFn(f)
+			})
 			if tc.hasError && err == nil {
 				t.Errorf("was expecting an error, but returns nil")
 			}
@@ -454,8 +570,8 @@ func TestPostgresRepositoryGetByID(t *testing.T) {
 		CreatedAt:           time.Now(),
 		UpdatedAt:           time.Now(),
 	}
-	cols := []string{"id", "latitude", "longitude", "setor_censitario", "area_ponderacao", "codigo_distrito", "distrito", "codigo_subprefeitura", "subprefeitura", "regiao5", "regiao8", "nome_feira", "registro", "logradouro", "numero", "bairro", "referencia", "created_at", "updated_at"}
-	vals := []driver.Value{fl.ID, fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia, fl.CreatedAt, fl.UpdatedAt}
+	cols := []string{"id", "latitude", "longitude", "setor_censitario", "area_ponderacao", "codigo_distrito", "distrito", "codigo_subprefeitura", "subprefeitura", "regiao5", "regiao8", "nome_feira", "registro", "logradouro", "numero", "bairro", "referencia", "created_at", "updated_at", "created_by", "updated_by"}
+	vals := []driver.Value{fl.ID, fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia, fl.CreatedAt, fl.UpdatedAt, fl.CreatedBy, fl.UpdatedBy}
 	testCases := []struct {
 		name       string
 		setupMocks func(mock sqlmock.Sqlmock)
@@ -496,7 +612,7 @@ func TestPostgresRepositoryGetByID(t *testing.T) {
 
 			repo := NewPostgresRepository(db)
 
-			res, err := repo.GetByID(tc.in)
+			res, err := repo.GetByID(context.Background(), tc.in)
 			if tc.hasError && err == nil {
 				t.Errorf("was expecting an error, but returns nil")
 			}
@@ -534,7 +650,7 @@ func TestPostgresRepositoryCreate(t *testing.T) {
 	}
 	cols := []string{"id", "created_at", "updated_at"}
 	vals := []driver.Value{fl.ID, fl.CreatedAt, fl.UpdatedAt}
-	argsDriverValue := []driver.Value{fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia}
+	argsDriverValue := []driver.Value{fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia, nil}
 	testCases := []struct {
 		name       string
 		setupMocks func(mock sqlmock.Sqlmock)
@@ -575,7 +691,7 @@ func TestPostgresRepositoryCreate(t *testing.T) {
 
 			repo := NewPostgresRepository(db)
 
-			res, err := repo.Create(tc.in)
+			res, err := repo.Create(context.Background(), tc.in)
 			if tc.hasError && err == nil {
 				t.Errorf("was expecting an error, but returns nil")
 			}
@@ -608,10 +724,12 @@ func TestPostgresRepositoryCreateOrUpdate(t *testing.T) {
 		Numero:              "45",
 		Bairro:              "VILA FORMOSA",
 		Referencia:          "PRAÇA MARECHAL LEITE BANDEIRA",
+		CreatedBy:           "maria",
+		UpdatedBy:           "maria",
 	}
 	cols := []string{"id", "created_at", "updated_at"}
 	vals := []driver.Value{fl.ID, fl.CreatedAt, fl.UpdatedAt}
-	argsDriverValue := []driver.Value{fl.ID, fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia, fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia}
+	argsDriverValue := []driver.Value{fl.ID, fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia, fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia, fl.CreatedBy, fl.UpdatedBy}
 	testCases := []struct {
 		name       string
 		setupMocks func(mock sqlmock.Sqlmock)
@@ -652,7 +770,7 @@ func TestPostgresRepositoryCreateOrUpdate(t *testing.T) {
 
 			repo := NewPostgresRepository(db)
 
-			res, err := repo.CreateOrUpdate(tc.in)
+			res, err := repo.CreateOrUpdate(context.Background(), tc.in)
 			if tc.hasError && err == nil {
 				t.Errorf("was expecting an error, but returns nil")
 			}
@@ -666,6 +784,103 @@ func TestPostgresRepositoryCreateOrUpdate(t *testing.T) {
 	}
 }
 
+func TestPostgresRepositoryCreateOrUpdateBatch(t *testing.T) {
+	fl := entity.FeiraLivre{
+		ID:                  1,
+		Latitude:            -23568390,
+		Longitude:           -46548146,
+		SetorCensitario:     355030885000019,
+		AreaPonderacao:      3550308005040,
+		CodigoDistrito:      87,
+		Distrito:            "VILA FORMOSA",
+		CodigoSubprefeitura: 26,
+		Subprefeitura:       "ARICANDUVA",
+		Regiao5:             "Leste",
+		Regiao8:             "Leste 1",
+		NomeFeira:           "PRAÇA LEÃO X",
+		Registro:            "7216-8",
+		Logradouro:          "RUA CODAJÁS",
+		Numero:              "45",
+		Bairro:              "VILA FORMOSA",
+		Referencia:          "PRAÇA MARECHAL LEITE BANDEIRA",
+	}
+	argsDriverValue := []driver.Value{fl.ID, fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia, fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia, nil, nil}
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		in         []entity.FeiraLivre
+		hasError   bool
+	}{
+		{
+			name: "when db returns an error when begin",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin().WillReturnError(sql.ErrConnDone)
+			},
+			in:       []entity.FeiraLivre{fl},
+			hasError: true,
+		},
+		{
+			name: "when db returns an error when prepare",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectPrepare(regexp.QuoteMeta(QueryCreateOrUpdate)).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			in:       []entity.FeiraLivre{fl},
+			hasError: true,
+		},
+		{
+			name: "when db returns an error when exec",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				prepare := mock.ExpectPrepare(regexp.QuoteMeta(QueryCreateOrUpdate))
+				prepare.ExpectExec().WithArgs(argsDriverValue...).WillReturnResult(sqlmock.NewResult(1, 1))
+				prepare.ExpectExec().WithArgs(argsDriverValue...).WillReturnError(sql.ErrConnDone)
+				mock.ExpectRollback()
+			},
+			in:       []entity.FeiraLivre{fl, fl},
+			hasError: true,
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				prepare := mock.ExpectPrepare(regexp.QuoteMeta(QueryCreateOrUpdate))
+				prepare.ExpectExec().WithArgs(argsDriverValue...).WillReturnResult(sqlmock.NewResult(1, 1))
+				prepare.ExpectExec().WithArgs(argsDriverValue...).WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+			in:       []entity.FeiraLivre{fl, fl},
+			hasError: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			err = repo.CreateOrUpdateBatch(context.Background(), tc.in)
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
 func TestPostgresRepositoryUpdate(t *testing.T) {
 	fl := entity.FeiraLivre{
 		ID:                  1,
@@ -692,7 +907,7 @@ func TestPostgresRepositoryUpdate(t *testing.T) {
 	newFl.UpdatedAt = newFl.UpdatedAt.Add(10 * time.Minute)
 	cols := []string{"updated_at"}
 	vals := []driver.Value{newFl.UpdatedAt}
-	argsDriverValue := []driver.Value{fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia, fl.ID}
+	argsDriverValue := []driver.Value{fl.Latitude, fl.Longitude, fl.SetorCensitario, fl.AreaPonderacao, fl.CodigoDistrito, fl.Distrito, fl.CodigoSubprefeitura, fl.Subprefeitura, fl.Regiao5, fl.Regiao8, fl.NomeFeira, fl.Registro, fl.Logradouro, fl.Numero, fl.Bairro, fl.Referencia, nil, fl.ID}
 	testCases := []struct {
 		name       string
 		setupMocks func(mock sqlmock.Sqlmock)
@@ -733,7 +948,7 @@ func TestPostgresRepositoryUpdate(t *testing.T) {
 
 			repo := NewPostgresRepository(db)
 
-			res, err := repo.Update(tc.in.ID, tc.in)
+			res, err := repo.Update(context.Background(), tc.in.ID, tc.in)
 			if tc.hasError && err == nil {
 				t.Errorf("was expecting an error, but returns nil")
 			}
@@ -754,6 +969,7 @@ func TestPostgresRepositoryRemove(t *testing.T) {
 		setupMocks func(mock sqlmock.Sqlmock)
 		in         int
 		hasError   bool
+		outErr     error
 	}{
 		{
 			name: "when db returns an error",
@@ -763,6 +979,15 @@ func TestPostgresRepositoryRemove(t *testing.T) {
 			in:       flID,
 			hasError: true,
 		},
+		{
+			name: "when feiralivre does not exist",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(QueryRemove)).WithArgs(flID).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			in:       flID,
+			hasError: true,
+			outErr:   sql.ErrNoRows,
+		},
 		{
 			name: "when success",
 			setupMocks: func(mock sqlmock.Sqlmock) {
@@ -785,10 +1010,13 @@ func TestPostgresRepositoryRemove(t *testing.T) {
 
 			repo := NewPostgresRepository(db)
 
-			err = repo.Remove(tc.in)
+			err = repo.Remove(context.Background(), tc.in)
 			if tc.hasError && err == nil {
 				t.Errorf("was expecting an error, but returns nil")
 			}
+			if tc.outErr != nil && !errors.Is(err, tc.outErr) {
+				t.Errorf("was expecting %v, but returns %v", tc.outErr, err)
+			}
 			if !tc.hasError && err != nil {
 				t.Errorf("was not expecting an error, but returns %v", err)
 			}
@@ -830,7 +1058,7 @@ func TestPostgresRepositorySyncPK(t *testing.T) {
 
 			repo := NewPostgresRepository(db)
 
-			err = repo.SyncPK()
+			err = repo.SyncPK(context.Background())
 			if tc.hasError && err == nil {
 				t.Errorf("was expecting an error, but returns nil")
 			}
@@ -840,3 +1068,98 @@ func TestPostgresRepositorySyncPK(t *testing.T) {
 		})
 	}
 }
+
+func TestTranslateError(t *testing.T) {
+	fl := entity.FeiraLivre{
+		Latitude:  -23558733,
+		Longitude: 4294967296,
+		NomeFeira: "PRAÇA LEÃO X",
+		Registro:  "7216-8-ABCDE",
+	}
+	testCases := []struct {
+		name string
+		in   error
+		out  error
+	}{
+		{
+			name: "when id is duplicated",
+			in:   &pq.Error{Code: "23505", Constraint: "feira_livre_pkey"},
+			out:  &ConstraintError{Field: "id", Reason: ErrDuplicated},
+		},
+		{
+			name: "when unique constraint is named otherwise",
+			in:   &pq.Error{Code: "23505", Constraint: "uq_feira"},
+			out:  &ConstraintError{Reason: ErrDuplicated},
+		},
+		{
+			name: "when string is too long",
+			in:   &pq.Error{Code: "22001"},
+			out:  &ConstraintError{Field: "registro", Reason: ErrTooLong},
+		},
+		{
+			name: "when field is null",
+			in:   &pq.Error{Code: "23502", Column: "latitude"},
+			out:  &ConstraintError{Field: "latitude", Reason: ErrRequired},
+		},
+		{
+			name: "when check fails",
+			in:   &pq.Error{Code: "23514", Constraint: "feira_livre_latitude_check"},
+			out:  &ConstraintError{Field: "latitude", Reason: ErrInvalid},
+		},
+		{
+			name: "when integer is out of range",
+			in:   &pq.Error{Code: "22003"},
+			out:  &ConstraintError{Field: "longitude", Reason: ErrOutOfRange},
+		},
+		{
+			name: "when integer is fractional",
+			in:   &pq.Error{Code: "22P02"},
+			out:  &ConstraintError{Field: "longitude", Reason: ErrOutOfRange},
+		},
+		{
+			name: "when error is not a violation",
+			in:   sql.ErrConnDone,
+			out:  sql.ErrConnDone,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if out := translateError(tc.in, fl); !reflect.DeepEqual(out, tc.out) {
+				t.Errorf("was expecting %v, but returns %v", tc.out, out)
+			}
+		})
+	}
+}
+
+// TestColumnLengths fails when ColumnLengths is not the same as the VARCHAR columns of feira_livre
+// declared by the migrations
+func TestColumnLengths(t *testing.T) {
+	files, err := fs.Glob(migrations.FS, "*.up.sql")
+	if err != nil {
+		t.Fatalf("could not list the migrations: %v", err)
+	}
+
+	comment := regexp.MustCompile(`--[^\n]*`)
+	table := regexp.MustCompile(`TABLE (IF NOT EXISTS )?feira_livre\b`)
+	column := regexp.MustCompile(`(\w+) VARCHAR\((\d+)\)`)
+	lengths := map[string]int{}
+	for _, file := range files {
+		content, err := fs.ReadFile(migrations.FS, file)
+		if err != nil {
+			t.Fatalf("could not read %s: %v", file, err)
+		}
+		for _, statement := range strings.Split(comment.ReplaceAllString(string(content), ""), ";") {
+			if !table.MatchString(statement) {
+				continue
+			}
+			for _, match := range column.FindAllStringSubmatch(statement, -1) {
+				lengths[match[1]], _ = strconv.Atoi(match[2])
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(lengths, ColumnLengths) {
+		t.Errorf("was expecting %v, but the migrations declare %v", ColumnLengths, lengths)
+	}
+}
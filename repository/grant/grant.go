@@ -0,0 +1,15 @@
+package grant
+
+import (
+	"context"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+// Repository represents how a grant repository should be implemented
+type Repository interface {
+	Create(ctx context.Context, grant entity.Grant) (*entity.Grant, error)
+	List(ctx context.Context) ([]entity.Grant, error)
+	ListBySubjects(ctx context.Context, subjects []string, scope string) ([]entity.Grant, error)
+	Remove(ctx context.Context, id int) error
+}
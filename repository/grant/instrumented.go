@@ -0,0 +1,53 @@
+package grant
+
+import (
+	"context"
+	"time"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+type instrumentedRepository struct {
+	repo    Repository
+	observe func(method string, duration time.Duration, err error)
+}
+
+// NewInstrumentedRepository wraps repo calling observe with the duration and the result of every call
+func NewInstrumentedRepository(repo Repository, observe func(method string, duration time.Duration, err error)) Repository {
+	return &instrumentedRepository{
+		repo:    repo,
+		observe: observe,
+	}
+}
+
+// Create implements the instrumented Create
+func (r instrumentedRepository) Create(ctx context.Context, grant entity.Grant) (*entity.Grant, error) {
+	start := time.Now()
+	res, err := r.repo.Create(ctx, grant)
+	r.observe("Create", time.Since(start), err)
+	return res, err
+}
+
+// List implements the instrumented List
+func (r instrumentedRepository) List(ctx context.Context) ([]entity.Grant, error) {
+	start := time.Now()
+	res, err := r.repo.List(ctx)
+	r.observe("List", time.Since(start), err)
+	return res, err
+}
+
+// ListBySubjects implements the instrumented ListBySubjects
+func (r instrumentedRepository) ListBySubjects(ctx context.Context, subjects []string, scope string) ([]entity.Grant, error) {
+	start := time.Now()
+	res, err := r.repo.ListBySubjects(ctx, subjects, scope)
+	r.observe("ListBySubjects", time.Since(start), err)
+	return res, err
+}
+
+// Remove implements the instrumented Remove
+func (r instrumentedRepository) Remove(ctx context.Context, id int) error {
+	start := time.Now()
+	err := r.repo.Remove(ctx, id)
+	r.observe("Remove", time.Since(start), err)
+	return err
+}
@@ -0,0 +1,137 @@
+package grant
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+const (
+	// QueryCreate is the query used to create a grant
+	QueryCreate = `
+INSERT INTO access_grant
+    (subject, scope, codigo_subprefeitura, regiao5)
+VALUES
+    ($1, $2, NULLIF($3, 0), NULLIF($4, ''))
+RETURNING id, created_at;`
+	// QueryList is the query used to list every grant
+	QueryList = `
+SELECT
+    id,
+    subject,
+    scope,
+    COALESCE(codigo_subprefeitura, 0),
+    COALESCE(regiao5, ''),
+    created_at
+FROM access_grant
+ORDER BY id;`
+	// QueryListBySubjects is the query used to list the grants of the subjects for a scope
+	QueryListBySubjects = `
+SELECT
+    id,
+    subject,
+    scope,
+    COALESCE(codigo_subprefeitura, 0),
+    COALESCE(regiao5, ''),
+    created_at
+FROM access_grant
+WHERE
+    subject = ANY($1)
+    AND scope = $2
+ORDER BY id;`
+	// QueryRemove is the query used to remove a grant
+	QueryRemove = `
+DELETE FROM
+    access_grant
+WHERE
+    id = $1;`
+)
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a postgres repository for grant
+func NewPostgresRepository(db *sql.DB) Repository {
+	return &postgresRepository{
+		db: db,
+	}
+}
+
+// Create implements how to query to create a grant
+func (r postgresRepository) Create(ctx context.Context, grant entity.Grant) (*entity.Grant, error) {
+	err := r.db.
+		QueryRowContext(
+			ctx,
+			QueryCreate,
+			grant.Subject,
+			grant.Scope,
+			grant.CodigoSubprefeitura,
+			grant.Regiao5,
+		).
+		Scan(
+			&grant.ID,
+			&grant.CreatedAt,
+		)
+	if err != nil {
+		return nil, err
+	}
+
+	return &grant, nil
+}
+
+// List implements how to query to list every grant
+func (r postgresRepository) List(ctx context.Context) ([]entity.Grant, error) {
+	return r.query(ctx, QueryList)
+}
+
+// ListBySubjects implements how to query to list the grants of the subjects for a scope
+func (r postgresRepository) ListBySubjects(ctx context.Context, subjects []string, scope string) ([]entity.Grant, error) {
+	return r.query(ctx, QueryListBySubjects, pq.Array(subjects), scope)
+}
+
+func (r postgresRepository) query(ctx context.Context, query string, args ...interface{}) ([]entity.Grant, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []entity.Grant{}
+	for rows.Next() {
+		var g entity.Grant
+		err := rows.Scan(
+			&g.ID,
+			&g.Subject,
+			&g.Scope,
+			&g.CodigoSubprefeitura,
+			&g.Regiao5,
+			&g.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+
+	return grants, rows.Err()
+}
+
+// Remove implements how to remove a grant, sql.ErrNoRows is returned when it does not exist
+func (r postgresRepository) Remove(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, QueryRemove, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package grant
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+var cols = []string{"id", "subject", "scope", "codigo_subprefeitura", "regiao5", "created_at"}
+
+func TestPostgresRepositoryCreate(t *testing.T) {
+	now := time.Now()
+	grant := entity.Grant{
+		Subject:             "role:sub-aricanduva",
+		Scope:               entity.ScopeWrite,
+		CodigoSubprefeitura: 26,
+	}
+	created := grant
+	created.ID = 1
+	created.CreatedAt = now
+	args := []driver.Value{grant.Subject, grant.Scope, grant.CodigoSubprefeitura, grant.Regiao5}
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		out        *entity.Grant
+		hasError   bool
+	}{
+		{
+			name: "when db returns an error",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryCreate)).WithArgs(args...).WillReturnError(sql.ErrConnDone)
+			},
+			hasError: true,
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "created_at"}).AddRow(1, now)
+				mock.ExpectQuery(regexp.QuoteMeta(QueryCreate)).WithArgs(args...).WillReturnRows(rows)
+			},
+			out: &created,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			res, err := repo.Create(context.Background(), grant)
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestPostgresRepositoryList(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		out        []entity.Grant
+		hasError   bool
+	}{
+		{
+			name: "when db returns an error",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryList)).WillReturnError(sql.ErrConnDone)
+			},
+			hasError: true,
+		},
+		{
+			name: "when there is no grant",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryList)).WillReturnRows(sqlmock.NewRows(cols))
+			},
+			out: []entity.Grant{},
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(cols).
+					AddRow(1, "maria", "write", 26, "", now).
+					AddRow(2, "role:leste", "import", 0, "Leste", now)
+				mock.ExpectQuery(regexp.QuoteMeta(QueryList)).WillReturnRows(rows)
+			},
+			out: []entity.Grant{
+				{ID: 1, Subject: "maria", Scope: "write", CodigoSubprefeitura: 26, CreatedAt: now},
+				{ID: 2, Subject: "role:leste", Scope: "import", Regiao5: "Leste", CreatedAt: now},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			res, err := repo.List(context.Background())
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestPostgresRepositoryListBySubjects(t *testing.T) {
+	now := time.Now()
+	subjects := []string{"maria", "role:sub-aricanduva"}
+	args := []driver.Value{pq.Array(subjects), entity.ScopeWrite}
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		out        []entity.Grant
+		hasError   bool
+	}{
+		{
+			name: "when db returns an error",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryListBySubjects)).WithArgs(args...).WillReturnError(sql.ErrConnDone)
+			},
+			hasError: true,
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(cols).AddRow(1, "role:sub-aricanduva", "write", 26, "", now)
+				mock.ExpectQuery(regexp.QuoteMeta(QueryListBySubjects)).WithArgs(args...).WillReturnRows(rows)
+			},
+			out: []entity.Grant{
+				{ID: 1, Subject: "role:sub-aricanduva", Scope: "write", CodigoSubprefeitura: 26, CreatedAt: now},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			res, err := repo.ListBySubjects(context.Background(), subjects, entity.ScopeWrite)
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestPostgresRepositoryRemove(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		outErr     error
+	}{
+		{
+			name: "when does not exist",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(QueryRemove)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			outErr: sql.ErrNoRows,
+		},
+		{
+			name: "when db returns an error",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(QueryRemove)).WithArgs(1).WillReturnError(sql.ErrConnDone)
+			},
+			outErr: sql.ErrConnDone,
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(QueryRemove)).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			if err := repo.Remove(context.Background(), 1); err != tc.outErr {
+				t.Errorf("was expecting %v, but returns %v", tc.outErr, err)
+			}
+		})
+	}
+}
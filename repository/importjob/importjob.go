@@ -0,0 +1,17 @@
+package importjob
+
+import (
+	"time"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+// Repository represents how an importjob repository should be implemented
+type Repository interface {
+	GetByID(int) (*entity.ImportJob, error)
+	Create(entity.ImportJob) (*entity.ImportJob, error)
+	Update(entity.ImportJob) (*entity.ImportJob, error)
+	Touch(id int) (canceled bool, err error)
+	RequestCancel(id int) (bool, error)
+	InterruptUnfinished(staleAfter time.Duration) (int64, error)
+}
@@ -0,0 +1,68 @@
+package importjob
+
+import (
+	"time"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+type instrumentedRepository struct {
+	repo    Repository
+	observe func(method string, duration time.Duration, err error)
+}
+
+// NewInstrumentedRepository wraps repo calling observe with the duration and the result of every call
+func NewInstrumentedRepository(repo Repository, observe func(method string, duration time.Duration, err error)) Repository {
+	return &instrumentedRepository{
+		repo:    repo,
+		observe: observe,
+	}
+}
+
+// GetByID implements the instrumented GetByID
+func (r instrumentedRepository) GetByID(id int) (*entity.ImportJob, error) {
+	start := time.Now()
+	res, err := r.repo.GetByID(id)
+	r.observe("GetByID", time.Since(start), err)
+	return res, err
+}
+
+// Create implements the instrumented Create
+func (r instrumentedRepository) Create(j entity.ImportJob) (*entity.ImportJob, error) {
+	start := time.Now()
+	res, err := r.repo.Create(j)
+	r.observe("Create", time.Since(start), err)
+	return res, err
+}
+
+// Update implements the instrumented Update
+func (r instrumentedRepository) Update(j entity.ImportJob) (*entity.ImportJob, error) {
+	start := time.Now()
+	res, err := r.repo.Update(j)
+	r.observe("Update", time.Since(start), err)
+	return res, err
+}
+
+// Touch implements the instrumented Touch
+func (r instrumentedRepository) Touch(id int) (bool, error) {
+	start := time.Now()
+	res, err := r.repo.Touch(id)
+	r.observe("Touch", time.Since(start), err)
+	return res, err
+}
+
+// RequestCancel implements the instrumented RequestCancel
+func (r instrumentedRepository) RequestCancel(id int) (bool, error) {
+	start := time.Now()
+	res, err := r.repo.RequestCancel(id)
+	r.observe("RequestCancel", time.Since(start), err)
+	return res, err
+}
+
+// InterruptUnfinished implements the instrumented InterruptUnfinished
+func (r instrumentedRepository) InterruptUnfinished(staleAfter time.Duration) (int64, error) {
+	start := time.Now()
+	res, err := r.repo.InterruptUnfinished(staleAfter)
+	r.observe("InterruptUnfinished", time.Since(start), err)
+	return res, err
+}
@@ -0,0 +1,212 @@
+package importjob
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+const (
+	// QueryByID is the query used to get one importjob by id
+	QueryByID = `
+SELECT
+    id,
+    status,
+    filename,
+    format,
+    rows_read,
+    rows_persisted,
+    rows_failed,
+    errors,
+    started_at,
+    finished_at,
+    COALESCE(created_by, '') AS created_by,
+    created_at,
+    updated_at
+FROM import_job
+WHERE id = $1;`
+	// QueryCreate is the query used to create an importjob
+	QueryCreate = `
+INSERT INTO import_job
+    (status, filename, format, created_by)
+VALUES
+    ($1, $2, $3, $4)
+RETURNING id, created_at, updated_at;`
+	// QueryUpdate is the query used to update an importjob
+	QueryUpdate = `
+UPDATE
+    import_job
+SET
+    status = $1,
+    format = $2,
+    rows_read = $3,
+    rows_persisted = $4,
+    rows_failed = $5,
+    errors = $6,
+    started_at = $7,
+    finished_at = $8,
+    updated_at = NOW()
+WHERE
+    id = $9
+RETURNING created_at, updated_at;`
+	// QueryTouch is the query used to record that an importjob is still alive and to check if its
+	// cancel was requested
+	QueryTouch = `
+UPDATE
+    import_job
+SET
+    updated_at = NOW()
+WHERE
+    id = $1
+RETURNING cancel_requested_at IS NOT NULL;`
+	// QueryRequestCancel is the query used to request the cancel of an importjob still pending or
+	// running, which is stopped by the instance running it
+	QueryRequestCancel = `
+UPDATE
+    import_job
+SET
+    cancel_requested_at = NOW()
+WHERE
+    id = $1 AND
+    status IN ($2, $3);`
+	// QueryInterruptUnfinished is the query used to mark the unfinished importjob not updated for
+	// $4 seconds as interrupted
+	QueryInterruptUnfinished = `
+UPDATE
+    import_job
+SET
+    status = $1,
+    finished_at = NOW(),
+    updated_at = NOW()
+WHERE
+    status IN ($2, $3) AND
+    updated_at < NOW() - $4 * INTERVAL '1 second';`
+)
+
+type postgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a postgres repository for importjob
+func NewPostgresRepository(db *sql.DB) Repository {
+	return &postgresRepository{
+		db: db,
+	}
+}
+
+// GetByID implements how to query to get an importjob by id
+func (r postgresRepository) GetByID(id int) (*entity.ImportJob, error) {
+	row := r.db.QueryRow(QueryByID, id)
+	var j entity.ImportJob
+	var format sql.NullString
+	err := row.Scan(
+		&j.ID,
+		&j.Status,
+		&j.Filename,
+		&format,
+		&j.RowsRead,
+		&j.RowsPersisted,
+		&j.RowsFailed,
+		pq.Array(&j.Errors),
+		&j.StartedAt,
+		&j.FinishedAt,
+		&j.CreatedBy,
+		&j.CreatedAt,
+		&j.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	j.Format = format.String
+
+	return &j, nil
+}
+
+// Create implements how to query to create an importjob
+func (r postgresRepository) Create(job entity.ImportJob) (*entity.ImportJob, error) {
+	err := r.db.
+		QueryRow(
+			QueryCreate,
+			job.Status,
+			job.Filename,
+			job.Format,
+			sql.NullString{String: job.CreatedBy, Valid: job.CreatedBy != ""},
+		).
+		Scan(
+			&job.ID,
+			&job.CreatedAt,
+			&job.UpdatedAt,
+		)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Update implements how to update an importjob
+func (r postgresRepository) Update(job entity.ImportJob) (*entity.ImportJob, error) {
+	errs := job.Errors
+	if errs == nil {
+		errs = []string{}
+	}
+
+	err := r.db.
+		QueryRow(
+			QueryUpdate,
+			job.Status,
+			job.Format,
+			job.RowsRead,
+			job.RowsPersisted,
+			job.RowsFailed,
+			pq.Array(errs),
+			job.StartedAt,
+			job.FinishedAt,
+			job.ID,
+		).
+		Scan(
+			&job.CreatedAt,
+			&job.UpdatedAt,
+		)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// Touch implements how to record that an importjob is still alive, returning if its cancel was requested
+func (r postgresRepository) Touch(id int) (bool, error) {
+	var canceled bool
+	if err := r.db.QueryRow(QueryTouch, id).Scan(&canceled); err != nil {
+		return false, err
+	}
+	return canceled, nil
+}
+
+// RequestCancel implements how to request the cancel of an importjob, returning false when it is
+// not pending or running
+func (r postgresRepository) RequestCancel(id int) (bool, error) {
+	res, err := r.db.Exec(QueryRequestCancel, id, entity.ImportJobPending, entity.ImportJobRunning)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// InterruptUnfinished implements how to mark the pending and running importjob not updated since
+// staleAfter as interrupted
+func (r postgresRepository) InterruptUnfinished(staleAfter time.Duration) (int64, error) {
+	res, err := r.db.Exec(QueryInterruptUnfinished, entity.ImportJobInterrupted, entity.ImportJobPending, entity.ImportJobRunning, staleAfter.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
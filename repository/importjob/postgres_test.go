@@ -0,0 +1,365 @@
+package importjob
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+func TestPostgresRepositoryGetByID(t *testing.T) {
+	now := time.Now()
+	job := entity.ImportJob{
+		ID:            1,
+		Status:        entity.ImportJobDone,
+		Filename:      "feiras.csv",
+		Format:        "csv",
+		RowsRead:      3,
+		RowsPersisted: 2,
+		RowsFailed:    1,
+		Errors:        []string{"could not parse"},
+		StartedAt:     &now,
+		FinishedAt:    &now,
+		CreatedBy:     "maria",
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	cols := []string{"id", "status", "filename", "format", "rows_read", "rows_persisted", "rows_failed", "errors", "started_at", "finished_at", "created_by", "created_at", "updated_at"}
+	vals := []driver.Value{job.ID, job.Status, job.Filename, job.Format, job.RowsRead, job.RowsPersisted, job.RowsFailed, "{\"could not parse\"}", now, now, job.CreatedBy, now, now}
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		in         int
+		out        *entity.ImportJob
+		outErr     error
+	}{
+		{
+			name: "when does not exist",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryByID)).WithArgs(1).WillReturnError(sql.ErrNoRows)
+			},
+			in:     1,
+			outErr: sql.ErrNoRows,
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows(cols).AddRow(vals...)
+				mock.ExpectQuery(regexp.QuoteMeta(QueryByID)).WithArgs(1).WillReturnRows(rows)
+			},
+			in:  1,
+			out: &job,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			res, err := repo.GetByID(tc.in)
+			if err != tc.outErr {
+				t.Errorf("was expecting %v, but returns %v", tc.outErr, err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestPostgresRepositoryCreate(t *testing.T) {
+	now := time.Now()
+	job := entity.ImportJob{
+		Status:    entity.ImportJobPending,
+		Filename:  "feiras.csv",
+		Format:    "csv",
+		CreatedBy: "maria",
+	}
+	created := job
+	created.ID = 1
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		out        *entity.ImportJob
+		hasError   bool
+	}{
+		{
+			name: "when db returns an error",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryCreate)).WithArgs(job.Status, job.Filename, job.Format, job.CreatedBy).WillReturnError(sql.ErrConnDone)
+			},
+			hasError: true,
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(1, now, now)
+				mock.ExpectQuery(regexp.QuoteMeta(QueryCreate)).WithArgs(job.Status, job.Filename, job.Format, job.CreatedBy).WillReturnRows(rows)
+			},
+			out: &created,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			res, err := repo.Create(job)
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestPostgresRepositoryUpdate(t *testing.T) {
+	now := time.Now()
+	job := entity.ImportJob{
+		ID:        1,
+		Status:    entity.ImportJobRunning,
+		Filename:  "feiras.csv",
+		Format:    "csv",
+		RowsRead:  3,
+		StartedAt: &now,
+	}
+	updated := job
+	updated.CreatedAt = now
+	updated.UpdatedAt = now
+	args := []driver.Value{job.Status, job.Format, job.RowsRead, job.RowsPersisted, job.RowsFailed, pq.Array([]string{}), job.StartedAt, job.FinishedAt, job.ID}
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		out        *entity.ImportJob
+		outErr     error
+	}{
+		{
+			name: "when does not exist",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryUpdate)).WithArgs(args...).WillReturnError(sql.ErrNoRows)
+			},
+			outErr: sql.ErrNoRows,
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"created_at", "updated_at"}).AddRow(now, now)
+				mock.ExpectQuery(regexp.QuoteMeta(QueryUpdate)).WithArgs(args...).WillReturnRows(rows)
+			},
+			out: &updated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			res, err := repo.Update(job)
+			if err != tc.outErr {
+				t.Errorf("was expecting %v, but returns %v", tc.outErr, err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestPostgresRepositoryInterruptUnfinished(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		out        int64
+		hasError   bool
+	}{
+		{
+			name: "when db returns an error",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(QueryInterruptUnfinished)).WithArgs(entity.ImportJobInterrupted, entity.ImportJobPending, entity.ImportJobRunning, float64(90)).WillReturnError(sql.ErrConnDone)
+			},
+			hasError: true,
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(QueryInterruptUnfinished)).WithArgs(entity.ImportJobInterrupted, entity.ImportJobPending, entity.ImportJobRunning, float64(90)).WillReturnResult(sqlmock.NewResult(0, 2))
+			},
+			out: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			res, err := repo.InterruptUnfinished(90 * time.Second)
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if res != tc.out {
+				t.Errorf("was expecting %d, but returns %d", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestPostgresRepositoryTouch(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		out        bool
+		hasError   bool
+	}{
+		{
+			name: "when db returns an error",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryTouch)).WithArgs(1).WillReturnError(sql.ErrConnDone)
+			},
+			hasError: true,
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"canceled"}).AddRow(false)
+				mock.ExpectQuery(regexp.QuoteMeta(QueryTouch)).WithArgs(1).WillReturnRows(rows)
+			},
+		},
+		{
+			name: "when the cancel was requested",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"canceled"}).AddRow(true)
+				mock.ExpectQuery(regexp.QuoteMeta(QueryTouch)).WithArgs(1).WillReturnRows(rows)
+			},
+			out: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			res, err := repo.Touch(1)
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if res != tc.out {
+				t.Errorf("was expecting %v, but returns %v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestPostgresRepositoryRequestCancel(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		out        bool
+		hasError   bool
+	}{
+		{
+			name: "when db returns an error",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(QueryRequestCancel)).WithArgs(1, entity.ImportJobPending, entity.ImportJobRunning).WillReturnError(sql.ErrConnDone)
+			},
+			hasError: true,
+		},
+		{
+			name: "when is not pending or running",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(QueryRequestCancel)).WithArgs(1, entity.ImportJobPending, entity.ImportJobRunning).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+		{
+			name: "when success",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(QueryRequestCancel)).WithArgs(1, entity.ImportJobPending, entity.ImportJobRunning).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			out: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			repo := NewPostgresRepository(db)
+
+			res, err := repo.RequestCancel(1)
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if res != tc.out {
+				t.Errorf("was expecting %v, but returns %v", tc.out, res)
+			}
+		})
+	}
+}
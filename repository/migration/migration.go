@@ -0,0 +1,109 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var (
+	// ErrDirty is used when a previous migration failed and the database must be fixed and forced to a version
+	ErrDirty = errors.New("the database is dirty")
+	// ErrUnknownVersion is used when a version does not match any migration
+	ErrUnknownVersion = errors.New("unknown migration version")
+	// ErrNoDownMigration is used when a migration that should be reverted has no down file
+	ErrNoDownMigration = errors.New("the migration has no down file")
+)
+
+// fileNameRegex matches the migrations files, e.g. 20210727000611_initial.up.sql
+var fileNameRegex = regexp.MustCompile(`^([0-9]+)_(.*)\.(up|down)\.sql$`)
+
+// Migration represents one database migration
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status represents if a migration was applied to the database
+type Status struct {
+	Migration
+	Applied bool
+	Dirty   bool
+}
+
+// Migrator represents how a migrator should be implemented
+type Migrator interface {
+	Version(ctx context.Context) (version int64, dirty bool, err error)
+	Status() ([]Status, error)
+	Up(steps int) (applied int, err error)
+	Down(steps int) (reverted int, err error)
+	To(version int64) (changed int, err error)
+	Force(version int64) error
+}
+
+// Load reads the migrations inside fsys sorted by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("could not list the migrations: %v", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		matches := fileNameRegex.FindStringSubmatch(e.Name())
+		if matches == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %v", e.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %v", e.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		} else if m.Name != matches[2] {
+			return nil, fmt.Errorf("duplicated migration version %d: %s and %s", version, m.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("the migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// LatestVersion returns the version of the last migration, zero when there is no migration
+func LatestVersion(migrations []Migration) int64 {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
@@ -0,0 +1,81 @@
+package migration
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/bgildson/unico-challenge/migrations"
+)
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		name     string
+		in       fstest.MapFS
+		out      []Migration
+		hasError bool
+	}{
+		{
+			name: "when success",
+			in: fstest.MapFS{
+				"2_second.up.sql":   {Data: []byte("up 2")},
+				"1_first.up.sql":    {Data: []byte("up 1")},
+				"1_first.down.sql":  {Data: []byte("down 1")},
+				"2_second.down.sql": {Data: []byte("down 2")},
+				"README.md":         {Data: []byte("ignored")},
+			},
+			out: []Migration{
+				{Version: 1, Name: "first", Up: "up 1", Down: "down 1"},
+				{Version: 2, Name: "second", Up: "up 2", Down: "down 2"},
+			},
+			hasError: false,
+		},
+		{
+			name: "when a migration has no up file",
+			in: fstest.MapFS{
+				"1_first.down.sql": {Data: []byte("down 1")},
+			},
+			out:      nil,
+			hasError: true,
+		},
+		{
+			name: "when a version is duplicated",
+			in: fstest.MapFS{
+				"1_first.up.sql": {Data: []byte("up 1")},
+				"1_other.up.sql": {Data: []byte("up 1")},
+			},
+			out:      nil,
+			hasError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := Load(tc.in)
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestLoadEmbedded(t *testing.T) {
+	res, err := Load(migrations.FS)
+	if err != nil {
+		t.Fatalf("was not expecting an error, but returns %v", err)
+	}
+	if len(res) == 0 {
+		t.Fatalf("was expecting the embedded migrations, but returns none")
+	}
+	for _, m := range res {
+		if m.Down == "" {
+			t.Errorf("was expecting a down file for %d_%s", m.Version, m.Name)
+		}
+	}
+}
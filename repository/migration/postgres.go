@@ -0,0 +1,286 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// LockID is the postgres advisory lock key held while the migrations are applied,
+// so concurrent instances starting with auto migrate run them only once
+const LockID int64 = 7363646839271
+
+// undefinedTable is the postgres error code used when the schema_migrations table was not created yet
+const undefinedTable = "42P01"
+
+const (
+	// QueryLock is the query used to wait for the migrations advisory lock
+	QueryLock = `SELECT pg_advisory_lock($1);`
+	// QueryUnlock is the query used to release the migrations advisory lock
+	QueryUnlock = `SELECT pg_advisory_unlock($1);`
+	// QueryCreateTable is the query used to create the table that stores the current version,
+	// it uses the same layout of golang-migrate to keep the databases migrated by it working
+	QueryCreateTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT NOT NULL PRIMARY KEY,
+    dirty BOOLEAN NOT NULL
+);`
+	// QueryVersion is the query used to get the current version
+	QueryVersion = `
+SELECT
+    version,
+    dirty
+FROM schema_migrations
+LIMIT 1;`
+	// QueryTruncate is the query used to remove the current version
+	QueryTruncate = `TRUNCATE schema_migrations;`
+	// QuerySetVersion is the query used to set the current version
+	QuerySetVersion = `
+INSERT INTO schema_migrations
+    (version, dirty)
+VALUES
+    ($1, $2);`
+)
+
+type postgresMigrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewPostgresMigrator creates a postgres migrator, migrations must be sorted by version
+func NewPostgresMigrator(db *sql.DB, migrations []Migration) Migrator {
+	return &postgresMigrator{
+		db:         db,
+		migrations: migrations,
+	}
+}
+
+// withLock runs fn holding the advisory lock, the lock belongs to the session,
+// so everything runs in the same connection
+func (m postgresMigrator) withLock(fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx := context.Background()
+
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("could not connect to the database: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, QueryLock, LockID); err != nil {
+		return fmt.Errorf("could not acquire the migrations lock: %v", err)
+	}
+	defer conn.ExecContext(ctx, QueryUnlock, LockID)
+
+	if _, err := conn.ExecContext(ctx, QueryCreateTable); err != nil {
+		return fmt.Errorf("could not create the schema_migrations table: %v", err)
+	}
+
+	return fn(ctx, conn)
+}
+
+// queryRower is implemented by *sql.DB and *sql.Conn
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (m postgresMigrator) version(ctx context.Context, q queryRower) (int64, bool, error) {
+	var version int64
+	var dirty bool
+	err := q.QueryRowContext(ctx, QueryVersion).Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == undefinedTable {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("could not read the current version: %v", err)
+	}
+	return version, dirty, nil
+}
+
+// setVersion replaces the current version, zero means that no migration is applied
+func (m postgresMigrator) setVersion(ctx context.Context, conn *sql.Conn, version int64, dirty bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not set the version %d: %v", version, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, QueryTruncate); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("could not set the version %d: %v", version, err)
+	}
+	if version > 0 {
+		if _, err := tx.ExecContext(ctx, QuerySetVersion, version, dirty); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not set the version %d: %v", version, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("could not set the version %d: %v", version, err)
+	}
+	return nil
+}
+
+// index returns the position of version in the migrations, -1 for the version zero
+func (m postgresMigrator) index(version int64) (int, error) {
+	if version == 0 {
+		return -1, nil
+	}
+	for i, mig := range m.migrations {
+		if mig.Version == version {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+}
+
+// migrate applies or reverts the migrations until the target position, marking the
+// database as dirty while each migration runs
+func (m postgresMigrator) migrate(ctx context.Context, conn *sql.Conn, target int) (int, error) {
+	version, dirty, err := m.version(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w: version %d", ErrDirty, version)
+	}
+	current, err := m.index(version)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := 0
+	for ; current < target; current++ {
+		mig := m.migrations[current+1]
+		if err := m.setVersion(ctx, conn, mig.Version, true); err != nil {
+			return changed, err
+		}
+		if _, err := conn.ExecContext(ctx, mig.Up); err != nil {
+			return changed, fmt.Errorf("could not apply the migration %d_%s: %v", mig.Version, mig.Name, err)
+		}
+		if err := m.setVersion(ctx, conn, mig.Version, false); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+
+	for ; current > target; current-- {
+		mig := m.migrations[current]
+		if mig.Down == "" {
+			return changed, fmt.Errorf("%w: %d_%s", ErrNoDownMigration, mig.Version, mig.Name)
+		}
+		var previous int64
+		if current > 0 {
+			previous = m.migrations[current-1].Version
+		}
+		if err := m.setVersion(ctx, conn, mig.Version, true); err != nil {
+			return changed, err
+		}
+		if _, err := conn.ExecContext(ctx, mig.Down); err != nil {
+			return changed, fmt.Errorf("could not revert the migration %d_%s: %v", mig.Version, mig.Name, err)
+		}
+		if err := m.setVersion(ctx, conn, previous, false); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+
+	return changed, nil
+}
+
+// Version implements how to get the current version and if the last migration failed, it does not
+// wait for the migrations lock, so it could be used while another instance applies the migrations
+func (m postgresMigrator) Version(ctx context.Context) (int64, bool, error) {
+	return m.version(ctx, m.db)
+}
+
+// Status implements how to list the migrations and which of them were applied
+func (m postgresMigrator) Status() ([]Status, error) {
+	version, dirty, err := m.Version(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, mig := range m.migrations {
+		statuses[i] = Status{
+			Migration: mig,
+			Applied:   mig.Version <= version,
+			Dirty:     dirty && mig.Version == version,
+		}
+	}
+	return statuses, nil
+}
+
+// Up implements how to apply the next steps migrations, when steps is not positive every pending migration is applied
+func (m postgresMigrator) Up(steps int) (applied int, err error) {
+	err = m.withLock(func(ctx context.Context, conn *sql.Conn) error {
+		target := len(m.migrations) - 1
+		if steps > 0 {
+			version, _, err := m.version(ctx, conn)
+			if err != nil {
+				return err
+			}
+			current, err := m.index(version)
+			if err != nil {
+				return err
+			}
+			if current+steps < target {
+				target = current + steps
+			}
+		}
+		applied, err = m.migrate(ctx, conn, target)
+		return err
+	})
+	return
+}
+
+// Down implements how to revert the last steps migrations, when steps is not positive every migration is reverted
+func (m postgresMigrator) Down(steps int) (reverted int, err error) {
+	err = m.withLock(func(ctx context.Context, conn *sql.Conn) error {
+		version, _, err := m.version(ctx, conn)
+		if err != nil {
+			return err
+		}
+		current, err := m.index(version)
+		if err != nil {
+			return err
+		}
+		target := -1
+		if steps > 0 && current-steps > target {
+			target = current - steps
+		}
+		reverted, err = m.migrate(ctx, conn, target)
+		return err
+	})
+	return
+}
+
+// To implements how to apply or revert the migrations until version, zero reverts every migration
+func (m postgresMigrator) To(version int64) (changed int, err error) {
+	target, err := m.index(version)
+	if err != nil {
+		return 0, err
+	}
+	err = m.withLock(func(ctx context.Context, conn *sql.Conn) error {
+		changed, err = m.migrate(ctx, conn, target)
+		return err
+	})
+	return
+}
+
+// Force implements how to set the version without running any migration, used to fix a dirty database
+func (m postgresMigrator) Force(version int64) error {
+	if _, err := m.index(version); err != nil {
+		return err
+	}
+	return m.withLock(func(ctx context.Context, conn *sql.Conn) error {
+		return m.setVersion(ctx, conn, version, false)
+	})
+}
@@ -0,0 +1,386 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "first", Up: "CREATE TABLE first ();", Down: "DROP TABLE first;"},
+	{Version: 2, Name: "second", Up: "CREATE TABLE second ();", Down: "DROP TABLE second;"},
+}
+
+func expectLock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(QueryLock)).WithArgs(LockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(QueryCreateTable)).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(QueryUnlock)).WithArgs(LockID).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectVersion(mock sqlmock.Sqlmock, version int64, dirty bool) {
+	rows := sqlmock.NewRows([]string{"version", "dirty"})
+	if version > 0 {
+		rows.AddRow(version, dirty)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(QueryVersion)).WillReturnRows(rows)
+}
+
+func expectSetVersion(mock sqlmock.Sqlmock, version int64, dirty bool) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(QueryTruncate)).WillReturnResult(sqlmock.NewResult(0, 0))
+	if version > 0 {
+		mock.ExpectExec(regexp.QuoteMeta(QuerySetVersion)).WithArgs(version, dirty).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func expectMigration(mock sqlmock.Sqlmock, version int64, sql string, after int64) {
+	expectSetVersion(mock, version, true)
+	mock.ExpectExec(regexp.QuoteMeta(sql)).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSetVersion(mock, after, false)
+}
+
+func TestPostgresMigratorUp(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		in         int
+		out        int
+		err        error
+		hasError   bool
+	}{
+		{
+			name: "when the database is empty",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				expectLock(mock)
+				expectVersion(mock, 0, false)
+				expectMigration(mock, 1, testMigrations[0].Up, 1)
+				expectMigration(mock, 2, testMigrations[1].Up, 2)
+				expectUnlock(mock)
+			},
+			out:      2,
+			hasError: false,
+		},
+		{
+			name: "when applying one step",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				expectLock(mock)
+				expectVersion(mock, 0, false)
+				expectVersion(mock, 0, false)
+				expectMigration(mock, 1, testMigrations[0].Up, 1)
+				expectUnlock(mock)
+			},
+			in:       1,
+			out:      1,
+			hasError: false,
+		},
+		{
+			name: "when applying more steps than the pending migrations",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				expectLock(mock)
+				expectVersion(mock, 1, false)
+				expectVersion(mock, 1, false)
+				expectMigration(mock, 2, testMigrations[1].Up, 2)
+				expectUnlock(mock)
+			},
+			in:       5,
+			out:      1,
+			hasError: false,
+		},
+		{
+			name: "when the database is up to date",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				expectLock(mock)
+				expectVersion(mock, 2, false)
+				expectUnlock(mock)
+			},
+			out:      0,
+			hasError: false,
+		},
+		{
+			name: "when the database is dirty",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				expectLock(mock)
+				expectVersion(mock, 1, true)
+				expectUnlock(mock)
+			},
+			out:      0,
+			err:      ErrDirty,
+			hasError: true,
+		},
+		{
+			name: "when a migration fails",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				expectLock(mock)
+				expectVersion(mock, 1, false)
+				expectSetVersion(mock, 2, true)
+				mock.ExpectExec(regexp.QuoteMeta(testMigrations[1].Up)).WillReturnError(errors.New("unexpected error"))
+				expectUnlock(mock)
+			},
+			out:      0,
+			hasError: true,
+		},
+		{
+			name: "when could not acquire the lock",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(QueryLock)).WithArgs(LockID).WillReturnError(errors.New("unexpected error"))
+			},
+			out:      0,
+			hasError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			m := NewPostgresMigrator(db, testMigrations)
+
+			res, err := m.Up(tc.in)
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if tc.err != nil && !errors.Is(err, tc.err) {
+				t.Errorf("was expecting %v, but returns %v", tc.err, err)
+			}
+			if tc.out != res {
+				t.Errorf("was expecting %d, but returns %d", tc.out, res)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresMigratorDown(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		in         int
+		out        int
+		hasError   bool
+	}{
+		{
+			name: "when reverting one step",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				expectLock(mock)
+				expectVersion(mock, 2, false)
+				expectVersion(mock, 2, false)
+				expectMigration(mock, 2, testMigrations[1].Down, 1)
+				expectUnlock(mock)
+			},
+			in:       1,
+			out:      1,
+			hasError: false,
+		},
+		{
+			name: "when reverting every migration",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				expectLock(mock)
+				expectVersion(mock, 2, false)
+				expectVersion(mock, 2, false)
+				expectMigration(mock, 2, testMigrations[1].Down, 1)
+				expectMigration(mock, 1, testMigrations[0].Down, 0)
+				expectUnlock(mock)
+			},
+			in:       0,
+			out:      2,
+			hasError: false,
+		},
+		{
+			name: "when the current version is unknown",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				expectLock(mock)
+				expectVersion(mock, 3, false)
+				expectUnlock(mock)
+			},
+			in:       1,
+			out:      0,
+			hasError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			m := NewPostgresMigrator(db, testMigrations)
+
+			res, err := m.Down(tc.in)
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if tc.out != res {
+				t.Errorf("was expecting %d, but returns %d", tc.out, res)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresMigratorTo(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("could not mock sql: %v", err)
+	}
+	defer db.Close()
+
+	m := NewPostgresMigrator(db, testMigrations)
+
+	if _, err := m.To(3); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("was expecting %v, but returns %v", ErrUnknownVersion, err)
+	}
+
+	expectLock(mock)
+	expectVersion(mock, 0, false)
+	expectMigration(mock, 1, testMigrations[0].Up, 1)
+	expectUnlock(mock)
+
+	res, err := m.To(1)
+	if err != nil {
+		t.Errorf("was not expecting an error, but returns %v", err)
+	}
+	if res != 1 {
+		t.Errorf("was expecting %d, but returns %d", 1, res)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresMigratorForce(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("could not mock sql: %v", err)
+	}
+	defer db.Close()
+
+	expectLock(mock)
+	expectSetVersion(mock, 1, false)
+	expectUnlock(mock)
+
+	m := NewPostgresMigrator(db, testMigrations)
+
+	if err := m.Force(1); err != nil {
+		t.Errorf("was not expecting an error, but returns %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresMigratorVersion(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setupMocks func(mock sqlmock.Sqlmock)
+		outVersion int64
+		outDirty   bool
+		hasError   bool
+	}{
+		{
+			name: "when a version is stored",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				expectVersion(mock, 2, true)
+			},
+			outVersion: 2,
+			outDirty:   true,
+			hasError:   false,
+		},
+		{
+			name: "when the table does not exist",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryVersion)).WillReturnError(&pq.Error{Code: undefinedTable})
+			},
+			outVersion: 0,
+			outDirty:   false,
+			hasError:   false,
+		},
+		{
+			name: "when occur an unexpected error",
+			setupMocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(QueryVersion)).WillReturnError(errors.New("unexpected error"))
+			},
+			outVersion: 0,
+			outDirty:   false,
+			hasError:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Errorf("could not mock sql: %v", err)
+			}
+			defer db.Close()
+
+			tc.setupMocks(mock)
+
+			m := NewPostgresMigrator(db, testMigrations)
+
+			version, dirty, err := m.Version(context.Background())
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if tc.outVersion != version || tc.outDirty != dirty {
+				t.Errorf("was expecting %d and %t, but returns %d and %t", tc.outVersion, tc.outDirty, version, dirty)
+			}
+		})
+	}
+}
+
+func TestPostgresMigratorStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Errorf("could not mock sql: %v", err)
+	}
+	defer db.Close()
+
+	expectVersion(mock, 1, true)
+
+	m := NewPostgresMigrator(db, testMigrations)
+
+	res, err := m.Status()
+	if err != nil {
+		t.Errorf("was not expecting an error, but returns %v", err)
+	}
+	out := []Status{
+		{Migration: testMigrations[0], Applied: true, Dirty: true},
+		{Migration: testMigrations[1], Applied: false, Dirty: false},
+	}
+	if !reflect.DeepEqual(out, res) {
+		t.Errorf("was expecting %+v, but returns %+v", out, res)
+	}
+}
@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/server/logging"
+	"github.com/bgildson/unico-challenge/server/response"
+)
+
+// HeaderAPIKey is the header used to inform the api key, alternatively to the Authorization header
+const HeaderAPIKey = "X-API-Key"
+
+const (
+	// principalKey is the ctx.Locals key where the authenticated principal is stored
+	principalKey = "principal"
+	// authenticationKey is the ctx.Locals key where the authentication result is kept, so the
+	// credential is checked once per request
+	authenticationKey = "authentication"
+)
+
+// Authenticator represents how the credentials should be checked, the errors wrapping
+// entity.ErrInvalidCredential are answered with 401 and the others with 500
+type Authenticator interface {
+	Authenticate(ctx context.Context, credential string) (*entity.Principal, error)
+}
+
+// Chain tries the authenticators in order until one accepts the credential
+func Chain(authenticators ...Authenticator) Authenticator {
+	return chain(authenticators)
+}
+
+type chain []Authenticator
+
+func (c chain) Authenticate(ctx context.Context, credential string) (*entity.Principal, error) {
+	err := fmt.Errorf("no authenticator: %w", entity.ErrInvalidCredential)
+	for _, a := range c {
+		var principal *entity.Principal
+		principal, err = a.Authenticate(ctx, credential)
+		if err == nil || !errors.Is(err, entity.ErrInvalidCredential) {
+			return principal, err
+		}
+	}
+	return nil, err
+}
+
+// Authorizer represents how to find the areas where a principal could use a scope, the
+// principal is nil when the request was not authenticated
+type Authorizer interface {
+	Areas(ctx context.Context, principal *entity.Principal, scope string) (entity.Areas, error)
+}
+
+// AreasOf returns where who did the request could use the scope, every area when a is nil
+func AreasOf(a Authorizer, ctx *fiber.Ctx, scope string) (entity.Areas, error) {
+	if a == nil {
+		return entity.AllAreas, nil
+	}
+	return a.Areas(ctx.UserContext(), PrincipalOf(ctx), scope)
+}
+
+// Guard creates the handlers that identify who did the request and only allow the requests with the scope
+type Guard interface {
+	Identify() fiber.Handler
+	Require(scope string) fiber.Handler
+}
+
+type guard struct {
+	authenticator Authenticator
+	publicRead    bool
+}
+
+// New creates a Guard, when publicRead is true the read scope does not require a credential
+func New(authenticator Authenticator, publicRead bool) Guard {
+	return &guard{
+		authenticator: authenticator,
+		publicRead:    publicRead,
+	}
+}
+
+// Identify is used before the middlewares that depend on who did the request, as the rate limit,
+// when g is nil the requests are not identified
+func Identify(g Guard) fiber.Handler {
+	if g == nil {
+		return func(ctx *fiber.Ctx) error {
+			return ctx.Next()
+		}
+	}
+	return g.Identify()
+}
+
+// Require is used by the controllers to protect their routes, when g is nil the routes are open
+func Require(g Guard, scope string) fiber.Handler {
+	if g == nil {
+		return func(ctx *fiber.Ctx) error {
+			return ctx.Next()
+		}
+	}
+	return g.Require(scope)
+}
+
+// authentication is the result of checking the credential of a request
+type authentication struct {
+	principal *entity.Principal
+	err       error
+}
+
+// authenticate finds who owns the credential, the result is kept in the ctx so the credential is
+// checked once even when Identify and Require run in the same request
+func (g guard) authenticate(ctx *fiber.Ctx, credential string) (*entity.Principal, error) {
+	if res, ok := ctx.Locals(authenticationKey).(authentication); ok {
+		return res.principal, res.err
+	}
+
+	principal, err := g.authenticator.Authenticate(ctx.UserContext(), credential)
+	ctx.Locals(authenticationKey, authentication{principal: principal, err: err})
+	if err == nil {
+		ctx.Locals(principalKey, principal)
+	}
+	return principal, err
+}
+
+// Identify implements a handler that authenticates the request when it has a credential, the
+// requests with a missing or invalid credential continue without principal and Require answers them
+func (g guard) Identify() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if credential := Credential(ctx); credential != "" {
+			g.authenticate(ctx, credential)
+		}
+		return ctx.Next()
+	}
+}
+
+// Require implements a handler that authenticates the request and checks its scope
+func (g guard) Require(scope string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		credential := Credential(ctx)
+		if credential == "" {
+			if g.publicRead && scope == entity.ScopeRead {
+				return ctx.Next()
+			}
+			return unauthorized(ctx, "the credential is missing, inform an api key or a bearer token")
+		}
+
+		principal, err := g.authenticate(ctx, credential)
+		if errors.Is(err, entity.ErrInvalidCredential) {
+			logging.From(ctx).Warnf("could not authenticate: %v", err)
+			return unauthorized(ctx, "the credential is invalid, expired or revoked")
+		}
+		if err != nil {
+			logging.From(ctx).Errorf("could not authenticate: %v", err)
+			return response.Error(ctx, err, "could not authenticate")
+		}
+
+		if !principal.HasScope(scope) {
+			logging.From(ctx).Warnf("%s does not have the scope %s", principal.Subject, scope)
+			return response.Send(ctx, response.NewProblem(response.TypeForbidden, "the credential does not have the scope "+scope))
+		}
+
+		return ctx.Next()
+	}
+}
+
+// Credential returns the bearer token of the Authorization header or the X-API-Key header
+func Credential(ctx *fiber.Ctx) string {
+	if key := ctx.Get(HeaderAPIKey); key != "" {
+		return key
+	}
+	authorization := ctx.Get(fiber.HeaderAuthorization)
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	return ""
+}
+
+// PrincipalOf returns who did the request, nil when it was not authenticated
+func PrincipalOf(ctx *fiber.Ctx) *entity.Principal {
+	principal, _ := ctx.Locals(principalKey).(*entity.Principal)
+	return principal
+}
+
+// SubjectOf returns the subject of who did the request, empty when it was not authenticated
+func SubjectOf(ctx *fiber.Ctx) string {
+	if principal := PrincipalOf(ctx); principal != nil {
+		return principal.Subject
+	}
+	return ""
+}
+
+func unauthorized(ctx *fiber.Ctx, detail string) error {
+	ctx.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+	return response.Send(ctx, response.NewProblem(response.TypeUnauthorized, detail))
+}
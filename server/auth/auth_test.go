@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+type authenticatorFunc func(ctx context.Context, credential string) (*entity.Principal, error)
+
+func (f authenticatorFunc) Authenticate(ctx context.Context, credential string) (*entity.Principal, error) {
+	return f(ctx, credential)
+}
+
+var keys = authenticatorFunc(func(_ context.Context, credential string) (*entity.Principal, error) {
+	switch credential {
+	case "reader":
+		return &entity.Principal{Subject: "reader", Scopes: []string{entity.ScopeRead}}, nil
+	case "admin":
+		return &entity.Principal{Subject: "admin", Scopes: []string{entity.ScopeAdmin}}, nil
+	case "broken":
+		return nil, errors.New("database is down")
+	}
+	return nil, fmt.Errorf("unknown key: %w", entity.ErrInvalidCredential)
+})
+
+func TestGuardRequire(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	testCases := []struct {
+		name       string
+		publicRead bool
+		scope      string
+		header     string
+		value      string
+		outStatus  int
+		outSubject string
+	}{
+		{name: "when credential is missing", scope: entity.ScopeRead, outStatus: http.StatusUnauthorized},
+		{name: "when credential is missing and read is public", publicRead: true, scope: entity.ScopeRead, outStatus: http.StatusOK},
+		{name: "when credential is missing and write is required", publicRead: true, scope: entity.ScopeWrite, outStatus: http.StatusUnauthorized},
+		{name: "when credential is invalid", scope: entity.ScopeRead, header: HeaderAPIKey, value: "unknown", outStatus: http.StatusUnauthorized},
+		{name: "when authenticator fails", scope: entity.ScopeRead, header: HeaderAPIKey, value: "broken", outStatus: http.StatusInternalServerError},
+		{name: "when scope is missing", scope: entity.ScopeWrite, header: HeaderAPIKey, value: "reader", outStatus: http.StatusForbidden},
+		{name: "when scope is granted", scope: entity.ScopeRead, header: HeaderAPIKey, value: "reader", outStatus: http.StatusOK, outSubject: "reader"},
+		{name: "when admin uses the bearer token", scope: entity.ScopeImport, header: fiber.HeaderAuthorization, value: "Bearer admin", outStatus: http.StatusOK, outSubject: "admin"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", New(keys, tc.publicRead).Require(tc.scope), func(ctx *fiber.Ctx) error {
+				subject := ""
+				if p := PrincipalOf(ctx); p != nil {
+					subject = p.Subject
+				}
+				return ctx.SendString(subject)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+			if res.StatusCode == http.StatusUnauthorized && res.Header.Get(fiber.HeaderWWWAuthenticate) != "Bearer" {
+				t.Errorf("was expecting the %s header", fiber.HeaderWWWAuthenticate)
+			}
+			if res.StatusCode == http.StatusOK {
+				body, _ := ioutil.ReadAll(res.Body)
+				if string(body) != tc.outSubject {
+					t.Errorf("was expecting %v, but returns %v", tc.outSubject, string(body))
+				}
+			}
+		})
+	}
+}
+
+func TestGuardIdentify(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	testCases := []struct {
+		name       string
+		value      string
+		outSubject string
+		outCalls   int
+	}{
+		{name: "when credential is missing", outCalls: 0},
+		{name: "when credential is invalid", value: "unknown", outCalls: 1},
+		{name: "when authenticator fails", value: "broken", outCalls: 1},
+		{name: "when credential is valid", value: "reader", outSubject: "reader", outCalls: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			counting := authenticatorFunc(func(ctx context.Context, credential string) (*entity.Principal, error) {
+				calls++
+				return keys.Authenticate(ctx, credential)
+			})
+			g := New(counting, true)
+
+			app := fiber.New()
+			// the read is public, so Require only checks the credential informed
+			app.Get("/", g.Identify(), func(ctx *fiber.Ctx) error {
+				subject := ""
+				if p := PrincipalOf(ctx); p != nil {
+					subject = p.Subject
+				}
+				ctx.Set("X-Subject", subject)
+				return ctx.Next()
+			}, g.Require(entity.ScopeRead), func(ctx *fiber.Ctx) error {
+				return ctx.SendStatus(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.value != "" {
+				req.Header.Set(HeaderAPIKey, tc.value)
+			}
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if subject := res.Header.Get("X-Subject"); subject != tc.outSubject {
+				t.Errorf("was expecting %v, but returns %v", tc.outSubject, subject)
+			}
+			if calls != tc.outCalls {
+				t.Errorf("was expecting %d authentications, but returns %d", tc.outCalls, calls)
+			}
+		})
+	}
+}
+
+func TestRequireWithoutGuard(t *testing.T) {
+	app := fiber.New()
+	app.Post("/", Require(nil, entity.ScopeWrite), func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusCreated)
+	})
+
+	res, err := app.Test(httptest.NewRequest(http.MethodPost, "/", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("was expecting %v, but returns %v", http.StatusCreated, res.StatusCode)
+	}
+}
+
+func TestCredential(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header string
+		value  string
+		out    string
+	}{
+		{name: "when nothing is informed"},
+		{name: "when api key header is informed", header: HeaderAPIKey, value: "key", out: "key"},
+		{name: "when bearer token is informed", header: fiber.HeaderAuthorization, value: "bearer token", out: "token"},
+		{name: "when another scheme is informed", header: fiber.HeaderAuthorization, value: "Basic dXNlcjpwYXNz"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(ctx *fiber.Ctx) error {
+				return ctx.SendString(Credential(ctx))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			body, _ := ioutil.ReadAll(res.Body)
+			if string(body) != tc.out {
+				t.Errorf("was expecting %v, but returns %v", tc.out, string(body))
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+const (
+	// DefaultRolesClaim is the claim containing the roles when JWTConfig.RolesClaim is empty
+	DefaultRolesClaim = "roles"
+	// JWTSubjectPrefix starts the subject of the principals of the tokens, so a sub could not be
+	// taken as the subject of an api key, apikey:<id>:<name>, or of a role, role:<name>
+	JWTSubjectPrefix = "jwt:"
+	// maxSubjectLength is the length of the columns recording the subjects, as created_by
+	maxSubjectLength = 255
+)
+
+// JWTConfig contains the settings used to validate the bearer tokens issued by the SSO, the keys
+// are read from local files so the validation works offline
+type JWTConfig struct {
+	Issuer        string
+	Audience      string
+	JWKSFile      string
+	PublicKeyFile string
+	HMACSecret    string
+	// RolesClaim is the claim with the roles, nested claims are separated by dots (realm_access.roles)
+	RolesClaim string
+	// Roles maps the roles to the scopes, the roles not mapped grant no scope
+	Roles map[string][]string
+	// Leeway is the clock skew tolerated when checking the exp and nbf claims
+	Leeway time.Duration
+}
+
+type jwtAuthenticator struct {
+	config  JWTConfig
+	keys    map[string]interface{}
+	unnamed []interface{}
+	methods []string
+	parser  *jwt.Parser
+}
+
+// NewJWTAuthenticator creates an Authenticator validating the signature, issuer, audience and
+// expiry of the bearer tokens
+func NewJWTAuthenticator(config JWTConfig) (Authenticator, error) {
+	if config.RolesClaim == "" {
+		config.RolesClaim = DefaultRolesClaim
+	}
+
+	a := &jwtAuthenticator{
+		config: config,
+		keys:   map[string]interface{}{},
+	}
+
+	if config.JWKSFile != "" {
+		content, err := ioutil.ReadFile(config.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the jwks file: %v", err)
+		}
+		if err := a.addJWKS(content); err != nil {
+			return nil, err
+		}
+	}
+	if config.PublicKeyFile != "" {
+		content, err := ioutil.ReadFile(config.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the public key file: %v", err)
+		}
+		key, err := parsePublicKeyPEM(content)
+		if err != nil {
+			return nil, err
+		}
+		a.addKey("", key)
+	}
+	if config.HMACSecret != "" {
+		a.addKey("", []byte(config.HMACSecret))
+	}
+	if len(a.keys) == 0 && len(a.unnamed) == 0 {
+		return nil, errors.New("no key to validate the tokens, inform a jwks file, a public key file or a hmac secret")
+	}
+
+	a.parser = &jwt.Parser{ValidMethods: a.methods, SkipClaimsValidation: true}
+
+	return a, nil
+}
+
+// Authenticate implements how to find who owns the token, the roles claim is mapped to scopes
+func (a jwtAuthenticator) Authenticate(_ context.Context, credential string) (*entity.Principal, error) {
+	if strings.Count(credential, ".") != 2 {
+		return nil, fmt.Errorf("not a jwt: %w", entity.ErrInvalidCredential)
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(credential, claims, a.key); err != nil {
+		return nil, fmt.Errorf("%v: %w", err, entity.ErrInvalidCredential)
+	}
+	if err := a.validate(claims); err != nil {
+		return nil, fmt.Errorf("%v: %w", err, entity.ErrInvalidCredential)
+	}
+
+	subject, _ := claims["sub"].(string)
+	roles := a.roles(claims)
+
+	return &entity.Principal{
+		Subject: JWTSubjectPrefix + subject,
+		Scopes:  a.scopes(roles),
+		Roles:   roles,
+	}, nil
+}
+
+func (a jwtAuthenticator) validate(claims jwt.MapClaims) error {
+	now := time.Now()
+	leeway := int64(a.config.Leeway / time.Second)
+
+	if !claims.VerifyExpiresAt(now.Unix()-leeway, true) {
+		return errors.New("token is expired or has no exp")
+	}
+	if !claims.VerifyNotBefore(now.Unix()+leeway, false) {
+		return errors.New("token is not valid yet")
+	}
+	if a.config.Issuer != "" && !claims.VerifyIssuer(a.config.Issuer, true) {
+		return errors.New("token has an unexpected issuer")
+	}
+	if a.config.Audience != "" && !claims.VerifyAudience(a.config.Audience, true) {
+		return errors.New("token has an unexpected audience")
+	}
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return errors.New("token has no sub")
+	}
+	if utf8.RuneCountInString(JWTSubjectPrefix+subject) > maxSubjectLength {
+		return errors.New("token sub is too long")
+	}
+	return nil
+}
+
+// roles returns the roles of the roles claim, informed as a list or separated by spaces
+func (a jwtAuthenticator) roles(claims jwt.MapClaims) []string {
+	var roles []string
+	switch v := lookup(claims, a.config.RolesClaim).(type) {
+	case string:
+		roles = strings.Fields(v)
+	case []interface{}:
+		for _, r := range v {
+			if s, ok := r.(string); ok {
+				roles = append(roles, s)
+			}
+		}
+	}
+	return roles
+}
+
+// scopes maps the roles to the scopes
+func (a jwtAuthenticator) scopes(roles []string) []string {
+	seen := map[string]bool{}
+	var scopes []string
+	add := func(scope string) {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	for _, role := range roles {
+		for _, scope := range a.config.Roles[role] {
+			add(scope)
+		}
+	}
+	return scopes
+}
+
+// lookup returns the claim, following the dots of nested claims
+func lookup(claims map[string]interface{}, path string) interface{} {
+	var current interface{} = claims
+	for _, name := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[name]
+	}
+	return current
+}
+
+// key returns the key used to check the token signature, found by the kid header or, when the
+// token has no kid, the only key without kid
+func (a jwtAuthenticator) key(token *jwt.Token) (interface{}, error) {
+	if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+		if key, ok := a.keys[kid]; ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if len(a.unnamed) == 1 {
+		return a.unnamed[0], nil
+	}
+	if len(a.unnamed) == 0 && len(a.keys) == 1 {
+		for _, key := range a.keys {
+			return key, nil
+		}
+	}
+	return nil, errors.New("the token has no kid")
+}
+
+func (a *jwtAuthenticator) addKey(kid string, key interface{}) {
+	if kid == "" {
+		a.unnamed = append(a.unnamed, key)
+	} else {
+		a.keys[kid] = key
+	}
+
+	var methods []string
+	switch key.(type) {
+	case *rsa.PublicKey:
+		methods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}
+	case *ecdsa.PublicKey:
+		methods = []string{"ES256", "ES384", "ES512"}
+	case []byte:
+		methods = []string{"HS256", "HS384", "HS512"}
+	}
+	for _, m := range methods {
+		if !contains(a.methods, m) {
+			a.methods = append(a.methods, m)
+		}
+	}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// addJWKS adds the RSA and EC signing keys of a JWKS document
+func (a *jwtAuthenticator) addJWKS(content []byte) error {
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(content, &jwks); err != nil {
+		return fmt.Errorf("could not parse the jwks: %v", err)
+	}
+
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return fmt.Errorf("could not parse the jwk %q: %v", k.Kid, err)
+		}
+		a.addKey(k.Kid, key)
+	}
+	return nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("the point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func parsePublicKeyPEM(content []byte) (interface{}, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(content); err == nil {
+		return key, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(content); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("could not parse the public key, it must be a PEM encoded RSA or EC key")
+}
+
+// ParseRoles parses the roles mapping, formatted as role=scope,scope;role=scope
+func ParseRoles(s string) (map[string][]string, error) {
+	roles := map[string][]string{}
+	for _, item := range strings.Split(s, ";") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		role := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || role == "" {
+			return nil, fmt.Errorf("invalid role mapping %q", item)
+		}
+		for _, scope := range strings.Split(parts[1], ",") {
+			scope = strings.TrimSpace(scope)
+			if !contains(entity.Scopes, scope) {
+				return nil, fmt.Errorf("invalid scope %q in the role %s", scope, role)
+			}
+			roles[role] = append(roles[role], scope)
+		}
+	}
+	return roles, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+func writeJWKS(t *testing.T, keys ...map[string]string) string {
+	content, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatalf("could not marshal the jwks: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := ioutil.WriteFile(path, content, 0600); err != nil {
+		t.Fatalf("could not write the jwks: %v", err)
+	}
+	return path
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("could not sign the token: %v", err)
+	}
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   "https://sso.example.com",
+		"aud":   "feiras",
+		"sub":   "maria",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{"editor", "auditor"},
+	}
+}
+
+func TestJWTAuthenticatorAuthenticate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate the rsa key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate the ec key: %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("could not generate the rsa key: %v", err)
+	}
+
+	jwks := writeJWKS(t,
+		map[string]string{
+			"kty": "RSA",
+			"kid": "rsa-1",
+			"use": "sig",
+			"n":   encodeBigInt(rsaKey.N),
+			"e":   encodeBigInt(big.NewInt(int64(rsaKey.E))),
+		},
+		map[string]string{
+			"kty": "EC",
+			"kid": "ec-1",
+			"crv": "P-256",
+			"x":   encodeBigInt(ecKey.X),
+			"y":   encodeBigInt(ecKey.Y),
+		},
+	)
+	a, err := NewJWTAuthenticator(JWTConfig{
+		Issuer:   "https://sso.example.com",
+		Audience: "feiras",
+		JWKSFile: jwks,
+		Roles:    map[string][]string{"editor": {entity.ScopeRead, entity.ScopeWrite}},
+	})
+	if err != nil {
+		t.Fatalf("could not create the authenticator: %v", err)
+	}
+
+	with := func(change func(c jwt.MapClaims)) jwt.MapClaims {
+		c := validClaims()
+		change(c)
+		return c
+	}
+
+	testCases := []struct {
+		name     string
+		in       string
+		out      *entity.Principal
+		hasError bool
+	}{
+		{
+			name: "when token is signed with rsa",
+			in:   sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, validClaims()),
+			out:  &entity.Principal{Subject: "jwt:maria", Scopes: []string{entity.ScopeRead, entity.ScopeWrite}, Roles: []string{"editor", "auditor"}},
+		},
+		{
+			name: "when token is signed with ec",
+			in:   sign(t, jwt.SigningMethodES256, "ec-1", ecKey, validClaims()),
+			out:  &entity.Principal{Subject: "jwt:maria", Scopes: []string{entity.ScopeRead, entity.ScopeWrite}, Roles: []string{"editor", "auditor"}},
+		},
+		{
+			name: "when role is named as a scope but not mapped",
+			in:   sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, with(func(c jwt.MapClaims) { c["roles"] = []string{entity.ScopeAdmin} })),
+			out:  &entity.Principal{Subject: "jwt:maria", Roles: []string{entity.ScopeAdmin}},
+		},
+		{
+			name: "when token has no roles",
+			in:   sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, with(func(c jwt.MapClaims) { delete(c, "roles") })),
+			out:  &entity.Principal{Subject: "jwt:maria"},
+		},
+		{
+			name: "when sub is named as an api key",
+			in:   sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, with(func(c jwt.MapClaims) { c["sub"] = "apikey:1:admin" })),
+			out:  &entity.Principal{Subject: "jwt:apikey:1:admin", Scopes: []string{entity.ScopeRead, entity.ScopeWrite}, Roles: []string{"editor", "auditor"}},
+		},
+		{
+			name:     "when credential is not a jwt",
+			in:       "uc_0123abcd_secret",
+			hasError: true,
+		},
+		{
+			name:     "when signature does not match",
+			in:       sign(t, jwt.SigningMethodRS256, "rsa-1", otherKey, validClaims()),
+			hasError: true,
+		},
+		{
+			name:     "when kid is unknown",
+			in:       sign(t, jwt.SigningMethodRS256, "rsa-2", rsaKey, validClaims()),
+			hasError: true,
+		},
+		{
+			name:     "when algorithm does not match the key",
+			in:       sign(t, jwt.SigningMethodHS256, "rsa-1", []byte("secret"), validClaims()),
+			hasError: true,
+		},
+		{
+			name:     "when token is expired",
+			in:       sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+			hasError: true,
+		},
+		{
+			name:     "when token has no exp",
+			in:       sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, with(func(c jwt.MapClaims) { delete(c, "exp") })),
+			hasError: true,
+		},
+		{
+			name:     "when token is not valid yet",
+			in:       sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, with(func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(time.Hour).Unix() })),
+			hasError: true,
+		},
+		{
+			name:     "when issuer is unexpected",
+			in:       sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, with(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" })),
+			hasError: true,
+		},
+		{
+			name:     "when audience is unexpected",
+			in:       sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, with(func(c jwt.MapClaims) { c["aud"] = []string{"other"} })),
+			hasError: true,
+		},
+		{
+			name:     "when sub does not fit the subject columns",
+			in:       sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, with(func(c jwt.MapClaims) { c["sub"] = strings.Repeat("m", 252) })),
+			hasError: true,
+		},
+		{
+			name:     "when token has no sub",
+			in:       sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, with(func(c jwt.MapClaims) { delete(c, "sub") })),
+			hasError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := a.Authenticate(context.Background(), tc.in)
+			if tc.hasError && !errors.Is(err, entity.ErrInvalidCredential) {
+				t.Errorf("was expecting an invalid credential error, but returns %v", err)
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestJWTAuthenticatorHMAC(t *testing.T) {
+	a, err := NewJWTAuthenticator(JWTConfig{
+		Issuer:     "https://sso.example.com",
+		Audience:   "feiras",
+		HMACSecret: "secret",
+		RolesClaim: "realm_access.roles",
+		Roles:      map[string][]string{"feiras-admin": {entity.ScopeAdmin}},
+		Leeway:     time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("could not create the authenticator: %v", err)
+	}
+
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-30 * time.Second).Unix()
+	claims["realm_access"] = map[string]interface{}{"roles": []string{"feiras-admin"}}
+
+	res, err := a.Authenticate(context.Background(), sign(t, jwt.SigningMethodHS256, "", []byte("secret"), claims))
+	if err != nil {
+		t.Fatalf("was not expecting an error, but returns %v", err)
+	}
+	out := &entity.Principal{Subject: "jwt:maria", Scopes: []string{entity.ScopeAdmin}, Roles: []string{"feiras-admin"}}
+	if !reflect.DeepEqual(out, res) {
+		t.Errorf("was expecting %+v, but returns %+v", out, res)
+	}
+}
+
+func TestNewJWTAuthenticatorWithoutKey(t *testing.T) {
+	if _, err := NewJWTAuthenticator(JWTConfig{Issuer: "sso", Audience: "feiras"}); err == nil {
+		t.Error("was expecting an error, but returns nil")
+	}
+}
+
+func TestParseRoles(t *testing.T) {
+	testCases := []struct {
+		name     string
+		in       string
+		out      map[string][]string
+		hasError bool
+	}{
+		{name: "when roles are empty", in: "", out: map[string][]string{}},
+		{
+			name: "when roles are valid",
+			in:   "editor=read,write; importer = import",
+			out:  map[string][]string{"editor": {entity.ScopeRead, entity.ScopeWrite}, "importer": {entity.ScopeImport}},
+		},
+		{name: "when role has no scopes", in: "editor", hasError: true},
+		{name: "when scope is unknown", in: "editor=delete", hasError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := ParseRoles(tc.in)
+			if tc.hasError && err == nil {
+				t.Error("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if !tc.hasError && !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %v, but returns %v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	jwtAuth := authenticatorFunc(func(_ context.Context, credential string) (*entity.Principal, error) {
+		if credential == "a.b.c" {
+			return &entity.Principal{Subject: "maria"}, nil
+		}
+		return nil, fmt.Errorf("not a jwt: %w", entity.ErrInvalidCredential)
+	})
+	a := Chain(keys, jwtAuth)
+
+	res, err := a.Authenticate(context.Background(), "reader")
+	if err != nil || res.Subject != "reader" {
+		t.Errorf("was expecting the reader, but returns %+v, %v", res, err)
+	}
+
+	res, err = a.Authenticate(context.Background(), "a.b.c")
+	if err != nil || res.Subject != "maria" {
+		t.Errorf("was expecting maria, but returns %+v, %v", res, err)
+	}
+
+	if _, err := a.Authenticate(context.Background(), "broken"); err == nil || errors.Is(err, entity.ErrInvalidCredential) {
+		t.Errorf("was expecting the failure to stop the chain, but returns %v", err)
+	}
+
+	if _, err := Chain().Authenticate(context.Background(), "reader"); !errors.Is(err, entity.ErrInvalidCredential) {
+		t.Errorf("was expecting an invalid credential error, but returns %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package deprecation
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// HeaderDeprecation tells when the route was deprecated, as defined by the RFC 9745
+	HeaderDeprecation = "Deprecation"
+	// HeaderSunset tells when the route stops being answered, as defined by the RFC 8594
+	HeaderSunset = "Sunset"
+)
+
+// Config describes the deprecation of a version of the api
+type Config struct {
+	// Deprecation is when the version was deprecated, the Deprecation header is not sent when it is
+	// zero, since the RFC 9745 only defines it as a date
+	Deprecation time.Time
+	// Sunset is when the version stops being answered, the Sunset header is not sent when it is zero
+	Sunset time.Time
+	// Prefix is the prefix of the routes of the version, as /v1, replaced by Successor in the link
+	Prefix string
+	// Successor is the prefix of the routes of the version replacing it, as /v2, no link is sent
+	// when it is empty
+	Successor string
+}
+
+// Middleware creates a handler answering the routes of a deprecated version with the Deprecation
+// and Sunset headers and a Link to the same route in the successor version
+func Middleware(config Config) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if !config.Deprecation.IsZero() {
+			ctx.Set(HeaderDeprecation, "@"+strconv.FormatInt(config.Deprecation.Unix(), 10))
+		}
+		if !config.Sunset.IsZero() {
+			ctx.Set(HeaderSunset, config.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if config.Successor != "" {
+			successor := config.Successor + strings.TrimPrefix(ctx.Path(), config.Prefix)
+			ctx.Append(fiber.HeaderLink, fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		}
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package deprecation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMiddleware(t *testing.T) {
+	deprecatedAt := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	sunsetAt := time.Date(2027, 4, 18, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		in         Config
+		target     string
+		outHeaders map[string]string
+	}{
+		{
+			name:   "when version is deprecated with a sunset",
+			in:     Config{Deprecation: deprecatedAt, Sunset: sunsetAt, Prefix: "/v1", Successor: "/v2"},
+			target: "/v1/feiras-livres/1?limit=10",
+			outHeaders: map[string]string{
+				HeaderDeprecation: "@1792281600",
+				HeaderSunset:      "Sun, 18 Apr 2027 00:00:00 GMT",
+				fiber.HeaderLink:  `</v2/feiras-livres/1>; rel="successor-version"`,
+			},
+		},
+		{
+			name:   "when routes have no prefix",
+			in:     Config{Deprecation: deprecatedAt, Successor: "/v2"},
+			target: "/feiras-livres",
+			outHeaders: map[string]string{
+				HeaderDeprecation: "@1792281600",
+				HeaderSunset:      "",
+				fiber.HeaderLink:  `</v2/feiras-livres>; rel="successor-version"`,
+			},
+		},
+		{
+			name:   "when nothing is informed",
+			in:     Config{},
+			target: "/v1/feiras-livres",
+			outHeaders: map[string]string{
+				HeaderDeprecation: "",
+				HeaderSunset:      "",
+				fiber.HeaderLink:  "",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(Middleware(tc.in))
+			app.Get("/*", func(ctx *fiber.Ctx) error {
+				return ctx.SendStatus(http.StatusOK)
+			})
+
+			res, err := app.Test(httptest.NewRequest(http.MethodGet, tc.target, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for header, value := range tc.outHeaders {
+				if got := res.Header.Get(header); got != value {
+					t.Errorf("was expecting %s %q, but returns %q", header, value, got)
+				}
+			}
+		})
+	}
+}
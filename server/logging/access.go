@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bgildson/unico-challenge/server"
+)
+
+// AccessLog writes a line for every request with the fields used to trace it, the server
+// errors are logged as error and the client errors as warning
+func AccessLog(logger *logrus.Logger) fiber.Handler {
+	pid := os.Getpid()
+
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+		own := ctx.Route()
+
+		err := ctx.Next()
+
+		status := server.StatusOf(ctx, err)
+		entry := logger.WithFields(logrus.Fields{
+			"method":       ctx.Method(),
+			"path":         ctx.Path(),
+			"route":        server.RouteOf(ctx, own),
+			"query_params": string(ctx.Request().URI().QueryString()),
+			"status":       status,
+			"latency_ms":   float64(time.Since(start).Microseconds()) / 1000,
+			"user_agent":   string(ctx.Request().Header.UserAgent()),
+			"bytes_in":     len(ctx.Request().Body()),
+			"bytes_out":    len(ctx.Response().Body()),
+			"request_id":   server.RequestIDOf(ctx),
+			"remote_ip":    ctx.IP(),
+			"pid":          pid,
+		})
+
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			entry.Error("request")
+		case status >= fiber.StatusBadRequest:
+			entry.Warn("request")
+		default:
+			entry.Info("request")
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bgildson/unico-challenge/server"
+)
+
+const (
+	// FormatJSON writes a JSON object per line, used by the log shippers
+	FormatJSON = "json"
+	// FormatConsole writes human-readable lines, used in development
+	FormatConsole = "console"
+)
+
+// ErrUnknownFormat is used when the log format is not supported
+var ErrUnknownFormat = errors.New("unknown log format")
+
+// NewFormatter creates the logrus formatter for the format, json is used when it is empty
+func NewFormatter(format string) (logrus.Formatter, error) {
+	switch strings.ToLower(format) {
+	case "", FormatJSON:
+		return &logrus.JSONFormatter{
+			TimestampFormat: "2006-01-02T15:04:05.000Z07:00",
+			FieldMap: logrus.FieldMap{
+				logrus.FieldKeyTime:  "timestamp",
+				logrus.FieldKeyLevel: "level",
+				logrus.FieldKeyFunc:  "caller",
+				logrus.FieldKeyMsg:   "message",
+			},
+		}, nil
+	case FormatConsole:
+		return &logrus.TextFormatter{
+			FullTimestamp:   true,
+			TimestampFormat: "2006-01-02T15:04:05.000Z07:00",
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// loggerKey is the ctx.Locals key where the request logger is stored
+const loggerKey = "logger"
+
+// Middleware stores a logger carrying the request id in every request, it must be used after
+// the requestid middleware
+func Middleware(logger *logrus.Logger) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		ctx.Locals(loggerKey, logger.WithField("request_id", server.RequestIDOf(ctx)))
+		return ctx.Next()
+	}
+}
+
+// From returns the request logger with the method, the route and the params matched, when
+// the middleware is not used the standard logger is used
+func From(ctx *fiber.Ctx) *logrus.Entry {
+	entry, ok := ctx.Locals(loggerKey).(*logrus.Entry)
+	if !ok {
+		entry = logrus.NewEntry(logrus.StandardLogger())
+	}
+
+	route := ctx.Route()
+	fields := logrus.Fields{
+		"method": ctx.Method(),
+		"route":  route.Path,
+	}
+	if len(route.Params) > 0 {
+		params := make(map[string]string, len(route.Params))
+		for _, name := range route.Params {
+			params[name] = ctx.Params(name)
+		}
+		fields["params"] = params
+	}
+
+	return entry.WithFields(fields)
+}
@@ -0,0 +1,212 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bgildson/unico-challenge/server"
+)
+
+func TestFrom(t *testing.T) {
+	testCases := []struct {
+		name      string
+		requestID string
+		out       map[string]interface{}
+	}{
+		{
+			name:      "when the request id is informed by the caller",
+			requestID: "caller-id",
+			out: map[string]interface{}{
+				"request_id": "caller-id",
+				"method":     "GET",
+				"route":      "/feiras-livres/:id",
+				"params":     map[string]interface{}{"id": "5"},
+				"msg":        "something happened",
+				"level":      "error",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := logrus.New()
+			logger.SetOutput(&buf)
+			logger.SetFormatter(&logrus.JSONFormatter{DisableTimestamp: true})
+
+			app := fiber.New()
+			app.Use(requestid.New(requestid.Config{ContextKey: server.RequestIDKey}))
+			app.Use(Middleware(logger))
+			app.Get("/feiras-livres/:id", func(ctx *fiber.Ctx) error {
+				From(ctx).Error("something happened")
+				return ctx.SendString(server.RequestIDOf(ctx))
+			})
+
+			req := httptest.NewRequest("GET", "/feiras-livres/5", nil)
+			req.Header.Set(fiber.HeaderXRequestID, tc.requestID)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("could not execute the request: %v", err)
+			}
+
+			if id := resp.Header.Get(fiber.HeaderXRequestID); id != tc.requestID {
+				t.Errorf("was expecting request id header %q, but returns %q", tc.requestID, id)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("could not parse the log line %q: %v", buf.String(), err)
+			}
+			gotJSON, _ := json.Marshal(got)
+			outJSON, _ := json.Marshal(tc.out)
+			if string(gotJSON) != string(outJSON) {
+				t.Errorf("was expecting %s, but returns %s", outJSON, gotJSON)
+			}
+		})
+	}
+}
+
+func TestFromWithoutMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		entry := From(ctx)
+		if entry.Logger != logrus.StandardLogger() {
+			t.Errorf("was expecting the standard logger")
+		}
+		if _, ok := entry.Data["request_id"]; ok {
+			t.Errorf("was not expecting a request id, but returns %v", entry.Data["request_id"])
+		}
+		return nil
+	})
+
+	if _, err := app.Test(httptest.NewRequest("GET", "/", nil)); err != nil {
+		t.Fatalf("could not execute the request: %v", err)
+	}
+}
+
+func TestNewFormatter(t *testing.T) {
+	testCases := []struct {
+		name     string
+		in       string
+		out      logrus.Formatter
+		hasError bool
+	}{
+		{name: "when format is empty", in: "", out: &logrus.JSONFormatter{}},
+		{name: "when format is json", in: FormatJSON, out: &logrus.JSONFormatter{}},
+		{name: "when format is console", in: "CONSOLE", out: &logrus.TextFormatter{}},
+		{name: "when format is unknown", in: "xml", hasError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			formatter, err := NewFormatter(tc.in)
+			if (err != nil) != tc.hasError {
+				t.Fatalf("was expecting error %v, but returns %v", tc.hasError, err)
+			}
+			if tc.hasError {
+				if !errors.Is(err, ErrUnknownFormat) {
+					t.Errorf("was expecting %v, but returns %v", ErrUnknownFormat, err)
+				}
+				return
+			}
+			if reflect.TypeOf(formatter) != reflect.TypeOf(tc.out) {
+				t.Errorf("was expecting %T, but returns %T", tc.out, formatter)
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	testCases := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		out    map[string]interface{}
+	}{
+		{
+			name:   "when the request is handled",
+			method: "POST",
+			target: `/feiras-livres/5?nome_feira=a"b&bairro=c\d`,
+			body:   "12345",
+			out: map[string]interface{}{
+				"level":        "info",
+				"method":       "POST",
+				"path":         "/feiras-livres/5",
+				"route":        "/feiras-livres/:id",
+				"query_params": `nome_feira=a"b&bairro=c\d`,
+				"status":       float64(201),
+				"user_agent":   `agent "quoted"`,
+				"bytes_in":     float64(5),
+				"bytes_out":    float64(2),
+				"request_id":   "caller-id",
+				"remote_ip":    "0.0.0.0",
+			},
+		},
+		{
+			name:   "when no route matches",
+			method: "GET",
+			target: "/unknown",
+			out: map[string]interface{}{
+				"level":        "warning",
+				"method":       "GET",
+				"path":         "/unknown",
+				"route":        server.UnmatchedRoute,
+				"query_params": "",
+				"status":       float64(404),
+				"user_agent":   `agent "quoted"`,
+				"bytes_in":     float64(0),
+				"bytes_out":    float64(len("Cannot GET /unknown")),
+				"request_id":   "caller-id",
+				"remote_ip":    "0.0.0.0",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := logrus.New()
+			logger.SetOutput(&buf)
+			formatter, _ := NewFormatter(FormatJSON)
+			logger.SetFormatter(formatter)
+
+			app := fiber.New()
+			app.Use(requestid.New(requestid.Config{ContextKey: server.RequestIDKey}))
+			app.Use(AccessLog(logger))
+			app.Post("/feiras-livres/:id", func(ctx *fiber.Ctx) error {
+				return ctx.Status(fiber.StatusCreated).SendString("ok")
+			})
+
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			req.Header.Set(fiber.HeaderXRequestID, "caller-id")
+			req.Header.Set(fiber.HeaderUserAgent, `agent "quoted"`)
+			if _, err := app.Test(req); err != nil {
+				t.Fatalf("could not execute the request: %v", err)
+			}
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("could not parse the log line %q: %v", buf.String(), err)
+			}
+			for _, field := range []string{"timestamp", "latency_ms", "pid", "message"} {
+				if _, ok := got[field]; !ok {
+					t.Errorf("was expecting the field %s in %v", field, got)
+				}
+				delete(got, field)
+			}
+			if !reflect.DeepEqual(got, tc.out) {
+				t.Errorf("was expecting %v, but returns %v", tc.out, got)
+			}
+		})
+	}
+}
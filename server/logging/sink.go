@@ -0,0 +1,164 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/syslog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	// OutputStdout writes the logs to the standard output
+	OutputStdout = "stdout"
+	// OutputFile writes the logs to a file rotated by size and age
+	OutputFile = "file"
+	// OutputSyslog sends the logs to the local or a remote syslog
+	OutputSyslog = "syslog"
+)
+
+const (
+	// FileMode is the mode of the logs files, readable only by the owner and its group
+	FileMode os.FileMode = 0640
+	// DirMode is the mode of the logs directory when it does not exist
+	DirMode os.FileMode = 0750
+)
+
+// ErrUnknownOutput is used when the log output is not supported
+var ErrUnknownOutput = errors.New("unknown log output")
+
+// FileConfig contains the settings of the file output, MaxSize is in megabytes and MaxAge in days,
+// zero keeps the files forever
+type FileConfig struct {
+	Path       string
+	MaxSize    int
+	MaxAge     int
+	MaxBackups int
+	Compress   bool
+}
+
+// SyslogConfig contains the settings of the syslog output, an empty address uses the local syslog
+type SyslogConfig struct {
+	Network string
+	Address string
+	Tag     string
+}
+
+// Config contains the logging settings
+type Config struct {
+	Level   string
+	Format  string
+	Outputs []string
+	File    FileConfig
+	Syslog  SyslogConfig
+}
+
+// ParseOutputs splits a comma separated list of outputs, stdout is used when it is empty
+func ParseOutputs(s string) []string {
+	var outputs []string
+	for _, output := range strings.Split(s, ",") {
+		if output = strings.ToLower(strings.TrimSpace(output)); output != "" {
+			outputs = append(outputs, output)
+		}
+	}
+	if len(outputs) == 0 {
+		return []string{OutputStdout}
+	}
+	return outputs
+}
+
+// ParseLevel parses the log level, fallback is used when it is empty
+func ParseLevel(s string, fallback logrus.Level) (logrus.Level, error) {
+	if s == "" {
+		return fallback, nil
+	}
+	return logrus.ParseLevel(s)
+}
+
+// Open opens every output of the config, the returned writer writes to all of them and must
+// be closed before the process exits
+func Open(config Config) (io.WriteCloser, error) {
+	var writers []io.Writer
+	var closers []io.Closer
+	closeAll := func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}
+
+	for _, output := range config.Outputs {
+		switch output {
+		case OutputStdout:
+			writers = append(writers, os.Stdout)
+		case OutputFile:
+			file, err := openFile(config.File)
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			writers = append(writers, file)
+			closers = append(closers, file)
+		case OutputSyslog:
+			w, err := syslog.Dial(config.Syslog.Network, config.Syslog.Address, syslog.LOG_INFO|syslog.LOG_DAEMON, config.Syslog.Tag)
+			if err != nil {
+				closeAll()
+				return nil, fmt.Errorf("could not connect to the syslog: %v", err)
+			}
+			writers = append(writers, w)
+			closers = append(closers, w)
+		default:
+			closeAll()
+			return nil, fmt.Errorf("%w: %s", ErrUnknownOutput, output)
+		}
+	}
+
+	return &multiWriteCloser{Writer: io.MultiWriter(writers...), closers: closers}, nil
+}
+
+// openFile creates the logs file with FileMode before handing it to the rotation, so the
+// rotated files keep the same mode
+func openFile(config FileConfig) (io.WriteCloser, error) {
+	if config.Path == "" {
+		return nil, errors.New("the logs file path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), DirMode); err != nil {
+		return nil, fmt.Errorf("could not create the logs directory: %v", err)
+	}
+	file, err := os.OpenFile(config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, FileMode)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the logs file: %v", err)
+	}
+	defer file.Close()
+	// the files created by older versions were world writable
+	if err := file.Chmod(FileMode); err != nil {
+		return nil, fmt.Errorf("could not change the logs file mode: %v", err)
+	}
+
+	return &lumberjack.Logger{
+		Filename:   config.Path,
+		MaxSize:    config.MaxSize,
+		MaxAge:     config.MaxAge,
+		MaxBackups: config.MaxBackups,
+		Compress:   config.Compress,
+	}, nil
+}
+
+type multiWriteCloser struct {
+	io.Writer
+	closers []io.Closer
+}
+
+func (w *multiWriteCloser) Close() error {
+	var err error
+	for _, c := range w.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
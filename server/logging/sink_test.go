@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestParseOutputs(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   string
+		out  []string
+	}{
+		{name: "when outputs is empty", in: "", out: []string{OutputStdout}},
+		{name: "when outputs has only separators", in: " , ", out: []string{OutputStdout}},
+		{name: "when outputs has many values", in: "Stdout, file,syslog", out: []string{OutputStdout, OutputFile, OutputSyslog}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if out := ParseOutputs(tc.in); !reflect.DeepEqual(out, tc.out) {
+				t.Errorf("was expecting %v, but returns %v", tc.out, out)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	testCases := []struct {
+		name     string
+		in       string
+		fallback logrus.Level
+		out      logrus.Level
+		hasError bool
+	}{
+		{name: "when level is empty", in: "", fallback: logrus.WarnLevel, out: logrus.WarnLevel},
+		{name: "when level is valid", in: "info", fallback: logrus.WarnLevel, out: logrus.InfoLevel},
+		{name: "when level is invalid", in: "loud", fallback: logrus.WarnLevel, hasError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := ParseLevel(tc.in, tc.fallback)
+			if (err != nil) != tc.hasError {
+				t.Fatalf("was expecting error %v, but returns %v", tc.hasError, err)
+			}
+			if !tc.hasError && out != tc.out {
+				t.Errorf("was expecting %v, but returns %v", tc.out, out)
+			}
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("when output is unknown", func(t *testing.T) {
+		_, err := Open(Config{Outputs: []string{"kafka"}})
+		if !errors.Is(err, ErrUnknownOutput) {
+			t.Errorf("was expecting %v, but returns %v", ErrUnknownOutput, err)
+		}
+	})
+
+	t.Run("when file path is empty", func(t *testing.T) {
+		if _, err := Open(Config{Outputs: []string{OutputFile}}); err == nil {
+			t.Errorf("was expecting an error, but returns nil")
+		}
+	})
+
+	t.Run("when output is file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "logs", "logs.txt")
+		// an existing file created with a world writable mode
+		if err := os.MkdirAll(filepath.Dir(path), DirMode); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0777); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := Open(Config{Outputs: []string{OutputFile}, File: FileConfig{Path: path, MaxSize: 1}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := out.Write([]byte("line\n")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := out.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if info.Mode().Perm() != FileMode {
+			t.Errorf("was expecting %v, but returns %v", FileMode, info.Mode().Perm())
+		}
+		content, _ := ioutil.ReadFile(path)
+		if string(content) != "line\n" {
+			t.Errorf("was expecting %q, but returns %q", "line\n", content)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+
+	"github.com/bgildson/unico-challenge/server"
+)
+
+// Namespace prefixes every metric exported by the application
+const Namespace = "unico_challenge"
+
+// Metrics keeps the collectors exported by the /metrics endpoint
+type Metrics struct {
+	registry           *prometheus.Registry
+	httpRequests       *prometheus.CounterVec
+	httpDuration       *prometheus.HistogramVec
+	repositoryDuration *prometheus.HistogramVec
+	importRows         *prometheus.CounterVec
+}
+
+// New creates the application metrics, including the go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of the HTTP requests by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		repositoryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "repository_call_duration_seconds",
+			Help:      "Duration of the repository calls by repository, method and result.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"repository", "method", "result"}),
+		importRows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "import_rows_total",
+			Help:      "Number of rows handled by the imports by status (read, persisted or failed).",
+		}, []string{"status"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.repositoryDuration,
+		m.importRows,
+	)
+
+	return m
+}
+
+// RegisterDB exports the db pool stats (open, in use and idle connections, waits and so on)
+func (m *Metrics) RegisterDB(db *sql.DB, name string) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Register attachs the /metrics route to the fiber app
+func (m *Metrics) Register(app *fiber.App) {
+	handler := fasthttpadaptor.NewFastHTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	app.Get("/metrics", func(ctx *fiber.Ctx) error {
+		handler(ctx.Context())
+		return nil
+	})
+}
+
+// Middleware counts and times the requests by the route pattern, so the path params do not create new series
+func (m *Metrics) Middleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+		own := ctx.Route()
+
+		err := ctx.Next()
+
+		labels := prometheus.Labels{
+			"method": ctx.Method(),
+			"route":  server.RouteOf(ctx, own),
+			"status": strconv.Itoa(server.StatusOf(ctx, err)),
+		}
+		m.httpRequests.With(labels).Inc()
+		m.httpDuration.With(labels).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
+
+// ObserveRepository creates the observer used by the instrumented repositories
+func (m *Metrics) ObserveRepository(repository string) func(method string, duration time.Duration, err error) {
+	return func(method string, duration time.Duration, err error) {
+		result := "success"
+		if err != nil {
+			result = "error"
+		}
+		m.repositoryDuration.
+			WithLabelValues(repository, method, result).
+			Observe(duration.Seconds())
+	}
+}
+
+// ObserveImportRows counts the rows handled by the imports
+func (m *Metrics) ObserveImportRows(read, persisted, failed int64) {
+	if read > 0 {
+		m.importRows.WithLabelValues("read").Add(float64(read))
+	}
+	if persisted > 0 {
+		m.importRows.WithLabelValues("persisted").Add(float64(persisted))
+	}
+	if failed > 0 {
+		m.importRows.WithLabelValues("failed").Add(float64(failed))
+	}
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/bgildson/unico-challenge/server/response"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New()
+
+	// the routes not found are answered as the server does
+	app := fiber.New(fiber.Config{ErrorHandler: response.ErrorHandler})
+	app.Use(response.NotFound)
+	app.Use(m.Middleware())
+	m.Register(app)
+	app.Get("/feiras-livres/:id", func(ctx *fiber.Ctx) error {
+		return ctx.SendString("ok")
+	})
+	app.Get("/fail", func(ctx *fiber.Ctx) error {
+		return fiber.NewError(http.StatusBadGateway, "unexpected error")
+	})
+
+	for _, path := range []string{"/feiras-livres/1", "/feiras-livres/2", "/fail", "/unknown"} {
+		res, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if path == "/unknown" && res.Header.Get(fiber.HeaderContentType) != response.ContentTypeProblem {
+			t.Errorf("was expecting %s, but returns %s", response.ContentTypeProblem, res.Header.Get(fiber.HeaderContentType))
+		}
+	}
+
+	m.ObserveRepository("feiralivre")("GetByID", time.Millisecond, nil)
+	m.ObserveRepository("feiralivre")("Remove", time.Millisecond, errors.New("unexpected error"))
+	m.ObserveImportRows(3, 2, 1)
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("was expecting %v, but returns %v", http.StatusOK, res.StatusCode)
+	}
+	body, _ := ioutil.ReadAll(res.Body)
+
+	testCases := []string{
+		`unico_challenge_http_requests_total{method="GET",route="/feiras-livres/:id",status="200"} 2`,
+		`unico_challenge_http_requests_total{method="GET",route="/fail",status="502"} 1`,
+		`unico_challenge_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`unico_challenge_http_request_duration_seconds_count{method="GET",route="/feiras-livres/:id",status="200"} 2`,
+		`unico_challenge_repository_call_duration_seconds_count{method="GetByID",repository="feiralivre",result="success"} 1`,
+		`unico_challenge_repository_call_duration_seconds_count{method="Remove",repository="feiralivre",result="error"} 1`,
+		`unico_challenge_import_rows_total{status="read"} 3`,
+		`unico_challenge_import_rows_total{status="persisted"} 2`,
+		`unico_challenge_import_rows_total{status="failed"} 1`,
+	}
+	for _, tc := range testCases {
+		if !strings.Contains(string(body), tc) {
+			t.Errorf("was expecting %s in the metrics", tc)
+		}
+	}
+	if strings.Contains(string(body), `route="/"`) {
+		t.Errorf("was not expecting the unmatched routes labelled as the root route")
+	}
+}
@@ -0,0 +1,81 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// SpecPath is where the OpenAPI document is served
+	SpecPath = "/openapi.json"
+	// DocsPath is where the docs page is served
+	DocsPath = "/docs"
+	// RedocPath is where the Redoc bundle used by the docs page is served
+	RedocPath = DocsPath + "/redoc.standalone.js"
+)
+
+// Spec is the OpenAPI document describing the api routes
+//
+//go:embed openapi.json
+var Spec []byte
+
+// Register attachs the document and the docs page to the fiber app, both are public
+func Register(app *fiber.App) {
+	sub, _ := fs.Sub(assets, "assets")
+	register(app, sub)
+}
+
+func register(app *fiber.App, files fs.FS) {
+	app.Get(SpecPath, func(ctx *fiber.Ctx) error {
+		ctx.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+		return ctx.Send(Spec)
+	})
+	app.Get(DocsPath, func(ctx *fiber.Ctx) error {
+		ctx.Set(fiber.HeaderContentSecurityPolicy, DocsContentSecurityPolicy)
+		return sendFile(ctx, files, "docs.html", fiber.MIMETextHTMLCharsetUTF8)
+	})
+	app.Get(RedocPath, func(ctx *fiber.Ctx) error {
+		return sendRedoc(ctx, files)
+	})
+}
+
+// sendFile answers with the file name of files, 404 when it is missing
+func sendFile(ctx *fiber.Ctx, files fs.FS, name, contentType string) error {
+	content, err := fs.ReadFile(files, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fiber.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	ctx.Set(fiber.HeaderContentType, contentType)
+	return ctx.Send(content)
+}
+
+// Operations lists the operations of the document as "METHOD /path/{param}", sorted
+func Operations() ([]string, error) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(Spec, &doc); err != nil {
+		return nil, err
+	}
+
+	var operations []string
+	for path, item := range doc.Paths {
+		for method := range item {
+			switch method {
+			case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+				operations = append(operations, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	sort.Strings(operations)
+	return operations, nil
+}
@@ -0,0 +1,88 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gofiber/fiber/v2"
+
+	feiralivreRepository "github.com/bgildson/unico-challenge/repository/feiralivre"
+)
+
+func TestRegister(t *testing.T) {
+	app := fiber.New()
+	register(app, fstest.MapFS{
+		"docs.html": {Data: []byte("<html></html>")},
+	})
+
+	testCases := []struct {
+		name           string
+		app            *fiber.App
+		target         string
+		outStatus      int
+		outContentType string
+		outCSP         string
+	}{
+		{name: "when getting the document", app: app, target: SpecPath, outStatus: http.StatusOK, outContentType: fiber.MIMEApplicationJSONCharsetUTF8},
+		{name: "when getting the docs page", app: app, target: DocsPath, outStatus: http.StatusOK, outContentType: fiber.MIMETextHTMLCharsetUTF8, outCSP: DocsContentSecurityPolicy},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.app.Test(httptest.NewRequest(http.MethodGet, tc.target, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+			if contentType := res.Header.Get(fiber.HeaderContentType); contentType != tc.outContentType {
+				t.Errorf("was expecting %s, but returns %s", tc.outContentType, contentType)
+			}
+			if csp := res.Header.Get(fiber.HeaderContentSecurityPolicy); csp != tc.outCSP {
+				t.Errorf("was expecting %q, but returns %q", tc.outCSP, csp)
+			}
+			body, _ := ioutil.ReadAll(res.Body)
+			if tc.target == SpecPath && !json.Valid(body) {
+				t.Errorf("was expecting a json document, but returns %s", body)
+			}
+		})
+	}
+}
+
+// TestDocsPage fails when the embedded docs page loads the redoc bundle from another origin
+func TestDocsPage(t *testing.T) {
+	page, err := assets.ReadFile("assets/docs.html")
+	if err != nil {
+		t.Fatalf("could not read the docs page: %v", err)
+	}
+	if !strings.Contains(string(page), `src="`+RedocPath+`"`) {
+		t.Errorf("was expecting the docs page to load %s, but returns %s", RedocPath, page)
+	}
+}
+
+// TestColumnLengths fails when the maxLength of the feira livre schema is not the length of its
+// column, the readOnly fields are not checked as they are not sent by the clients
+func TestColumnLengths(t *testing.T) {
+	var doc document
+	if err := json.Unmarshal(Spec, &doc); err != nil {
+		t.Fatalf("could not parse the document: %v", err)
+	}
+	s := doc.Components.Schemas["FeiraLivreV2"]
+
+	for name, length := range feiralivreRepository.ColumnLengths {
+		prop, ok := s.Properties[name]
+		if !ok || prop.ReadOnly {
+			continue
+		}
+		if prop.MaxLength == nil || *prop.MaxLength != length {
+			t.Errorf("was expecting the maxLength of %s to be %d, but returns %v", name, length, prop.MaxLength)
+		}
+	}
+}
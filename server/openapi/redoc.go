@@ -0,0 +1,27 @@
+//go:build redoc
+// +build redoc
+
+package openapi
+
+import (
+	"embed"
+	"io/fs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DocsContentSecurityPolicy allows the docs page to load only the Redoc bundle served with it,
+// Redoc injects its styles and runs the search in a worker created from a blob
+const DocsContentSecurityPolicy = "default-src 'none'; script-src 'self'; style-src 'unsafe-inline'; " +
+	"img-src 'self' data:; worker-src blob:; connect-src 'self'; frame-ancestors 'none'"
+
+// assets contains the docs page and the Redoc bundle downloaded by make redoc, so the page does
+// not depend on a CDN, the build fails when the bundle is missing
+//
+//go:embed assets/docs.html assets/redoc.standalone.js
+var assets embed.FS
+
+// sendRedoc answers with the Redoc bundle of files
+func sendRedoc(ctx *fiber.Ctx, files fs.FS) error {
+	return sendFile(ctx, files, "redoc.standalone.js", fiber.MIMEApplicationJavaScriptCharsetUTF8)
+}
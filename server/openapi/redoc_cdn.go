@@ -0,0 +1,32 @@
+//go:build !redoc
+// +build !redoc
+
+package openapi
+
+import (
+	"embed"
+	"io/fs"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RedocURL is where the Redoc bundle is loaded from when it is not embedded, the version is the
+// REDOC_VERSION of the Makefile
+const RedocURL = "https://cdn.jsdelivr.net/npm/redoc@2.0.0-rc.55/bundles/redoc.standalone.js"
+
+// DocsContentSecurityPolicy allows the docs page to load only the Redoc bundle of the CDN, Redoc
+// injects its styles and runs the search in a worker created from a blob
+const DocsContentSecurityPolicy = "default-src 'none'; script-src 'self' https://cdn.jsdelivr.net; " +
+	"style-src 'unsafe-inline'; img-src 'self' data:; worker-src blob:; connect-src 'self'; " +
+	"frame-ancestors 'none'"
+
+// assets contains the docs page, the Redoc bundle is embedded only when building with the redoc
+// tag
+//
+//go:embed assets/docs.html
+var assets embed.FS
+
+// sendRedoc redirects to the Redoc bundle of the CDN
+func sendRedoc(ctx *fiber.Ctx, _ fs.FS) error {
+	return ctx.Redirect(RedocURL, fiber.StatusFound)
+}
@@ -0,0 +1,35 @@
+//go:build !redoc
+// +build !redoc
+
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// TestRedocBundle fails when the Redoc bundle is not redirected to the CDN allowed by the docs
+// page policy
+func TestRedocBundle(t *testing.T) {
+	app := fiber.New()
+	Register(app)
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, RedocPath, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if res.StatusCode != http.StatusFound {
+		t.Errorf("was expecting %v, but returns %v", http.StatusFound, res.StatusCode)
+	}
+	if location := res.Header.Get(fiber.HeaderLocation); location != RedocURL {
+		t.Errorf("was expecting %s, but returns %s", RedocURL, location)
+	}
+	if !strings.Contains(DocsContentSecurityPolicy, "script-src 'self' https://cdn.jsdelivr.net;") {
+		t.Errorf("was expecting the docs page policy to allow the CDN, but returns %q", DocsContentSecurityPolicy)
+	}
+}
@@ -0,0 +1,49 @@
+//go:build redoc
+// +build redoc
+
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/bgildson/unico-challenge/server/response"
+)
+
+// TestRedocBundle fails when the Redoc bundle downloaded by make redoc is not embedded
+func TestRedocBundle(t *testing.T) {
+	app := fiber.New()
+	Register(app)
+	missing := fiber.New(fiber.Config{ErrorHandler: response.ErrorHandler})
+	register(missing, fstest.MapFS{})
+
+	testCases := []struct {
+		name           string
+		app            *fiber.App
+		outStatus      int
+		outContentType string
+	}{
+		{name: "when getting the embedded bundle", app: app, outStatus: http.StatusOK, outContentType: fiber.MIMEApplicationJavaScriptCharsetUTF8},
+		{name: "when the bundle is missing", app: missing, outStatus: http.StatusNotFound, outContentType: response.ContentTypeProblem},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := tc.app.Test(httptest.NewRequest(http.MethodGet, RedocPath, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+			if contentType := res.Header.Get(fiber.HeaderContentType); contentType != tc.outContentType {
+				t.Errorf("was expecting %s, but returns %s", tc.outContentType, contentType)
+			}
+		})
+	}
+}
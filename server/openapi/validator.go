@@ -0,0 +1,560 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/bgildson/unico-challenge/server/logging"
+	"github.com/bgildson/unico-challenge/server/response"
+)
+
+// the locations of the params in the document, which are the same as the ones of the violations
+const (
+	InPath   = response.InPath
+	InQuery  = response.InQuery
+	InHeader = response.InHeader
+	InBody   = response.InBody
+)
+
+// document is the part of the OpenAPI document used to validate the requests
+type document struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas    map[string]*schema    `json:"schemas"`
+		Parameters map[string]*parameter `json:"parameters"`
+	} `json:"components"`
+}
+
+// operation is a method of a path
+type operation struct {
+	Parameters  []*parameter `json:"parameters"`
+	RequestBody *requestBody `json:"requestBody"`
+}
+
+type parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool `json:"required"`
+	Content  map[string]struct {
+		Schema *schema `json:"schema"`
+	} `json:"content"`
+}
+
+// schema is the subset of the JSON Schema used by the document
+type schema struct {
+	Ref                  string                `json:"$ref"`
+	Type                 string                `json:"type"`
+	Format               string                `json:"format"`
+	Enum                 []interface{}         `json:"enum"`
+	Required             []string              `json:"required"`
+	Properties           map[string]*schema    `json:"properties"`
+	AdditionalProperties *additionalProperties `json:"additionalProperties"`
+	Items                *schema               `json:"items"`
+	Nullable             bool                  `json:"nullable"`
+	ReadOnly             bool                  `json:"readOnly"`
+	Minimum              *float64              `json:"minimum"`
+	Maximum              *float64              `json:"maximum"`
+	MinLength            *int                  `json:"minLength"`
+	MaxLength            *int                  `json:"maxLength"`
+}
+
+// keywords are the keywords accepted in a schema, the ones checked by the validator and the
+// annotations, a schema using any other is rejected so a constraint is never silently ignored
+var keywords = map[string]bool{
+	"$ref": true, "type": true, "format": true, "enum": true, "required": true, "properties": true,
+	"additionalProperties": true, "items": true, "nullable": true, "readOnly": true,
+	"minimum": true, "maximum": true, "minLength": true, "maxLength": true,
+	"title": true, "description": true, "example": true, "default": true, "deprecated": true,
+}
+
+func (s *schema) UnmarshalJSON(b []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	for _, name := range sortedKeys(fields) {
+		if !keywords[name] {
+			return fmt.Errorf("the schema keyword %s is not supported", name)
+		}
+	}
+	// plain has the fields of schema without this method
+	type plain schema
+	return json.Unmarshal(b, (*plain)(s))
+}
+
+// additionalProperties is a schema or a boolean, false forbids the properties not listed
+type additionalProperties struct {
+	forbidden bool
+	schema    *schema
+}
+
+func (a *additionalProperties) UnmarshalJSON(b []byte) error {
+	switch string(b) {
+	case "true":
+		return nil
+	case "false":
+		a.forbidden = true
+		return nil
+	}
+	return json.Unmarshal(b, &a.schema)
+}
+
+// route is a path of the document, the segments between braces are params
+type route struct {
+	segments   []string
+	params     int
+	operations map[string]*operation
+}
+
+// Validator checks the requests against the OpenAPI document
+type Validator struct {
+	routes  []route
+	schemas map[string]*schema
+}
+
+// NewValidator creates a Validator from the OpenAPI document, failing when a schema uses a keyword
+// the validator does not check
+func NewValidator(spec []byte) (*Validator, error) {
+	var doc document
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("could not parse the document: %v", err)
+	}
+
+	v := &Validator{schemas: doc.Components.Schemas}
+	for path, item := range doc.Paths {
+		var common []*parameter
+		if raw, ok := item["parameters"]; ok {
+			if err := json.Unmarshal(raw, &common); err != nil {
+				return nil, fmt.Errorf("could not parse the parameters of %s: %v", path, err)
+			}
+		}
+
+		r := route{segments: strings.Split(strings.Trim(path, "/"), "/"), operations: map[string]*operation{}}
+		for _, segment := range r.segments {
+			if isParam(segment) {
+				r.params++
+			}
+		}
+
+		for method, raw := range item {
+			if method == "parameters" {
+				continue
+			}
+			var op operation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("could not parse %s %s: %v", method, path, err)
+			}
+			params, err := resolveParameters(doc, append(append([]*parameter{}, common...), op.Parameters...))
+			if err != nil {
+				return nil, fmt.Errorf("could not resolve the parameters of %s %s: %v", method, path, err)
+			}
+			op.Parameters = params
+			r.operations[strings.ToUpper(method)] = &op
+		}
+
+		v.routes = append(v.routes, r)
+	}
+
+	// the paths without params are matched first, so a fixed segment is preferred to a param
+	sort.SliceStable(v.routes, func(i, j int) bool {
+		return v.routes[i].params < v.routes[j].params
+	})
+
+	return v, nil
+}
+
+// resolveParameters replaces the references, the parameters of the operation override the ones of
+// the path with the same name and location
+func resolveParameters(doc document, params []*parameter) ([]*parameter, error) {
+	index := map[string]int{}
+	var resolved []*parameter
+	for _, p := range params {
+		if p.Ref != "" {
+			name := strings.TrimPrefix(p.Ref, "#/components/parameters/")
+			ref, ok := doc.Components.Parameters[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown parameter %s", p.Ref)
+			}
+			p = ref
+		}
+		key := p.In + ":" + p.Name
+		if i, ok := index[key]; ok {
+			resolved[i] = p
+			continue
+		}
+		index[key] = len(resolved)
+		resolved = append(resolved, p)
+	}
+	return resolved, nil
+}
+
+// Validate is used by the controllers to check the requests of their routes after the guard, so the
+// clients not allowed are answered before learning what the bodies must be, when v is nil the
+// requests are not checked
+func Validate(v *Validator) fiber.Handler {
+	if v == nil {
+		return func(ctx *fiber.Ctx) error {
+			return ctx.Next()
+		}
+	}
+	return v.Middleware()
+}
+
+// Middleware creates a handler rejecting the requests that do not follow the document, the
+// invalid params and the malformed bodies are answered with 400 and the bodies not matching the
+// schema with 422, listing every violation, the routes not documented are not checked
+func (v *Validator) Middleware() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		method := ctx.Method()
+		if method == fiber.MethodHead {
+			method = fiber.MethodGet
+		}
+		op, params := v.match(method, ctx.Path())
+		if op == nil {
+			return ctx.Next()
+		}
+
+		if violations := v.validateParams(ctx, op, params); len(violations) > 0 {
+			return reject(ctx, response.TypeInvalidRequest, "the params are invalid", violations)
+		}
+
+		status, violations := v.validateBody(ctx, op)
+		if len(violations) > 0 {
+			if status == http.StatusUnprocessableEntity {
+				return reject(ctx, response.TypeValidation, "the body does not match the schema", violations)
+			}
+			return reject(ctx, response.TypeInvalidRequest, "the body is invalid", violations)
+		}
+
+		return ctx.Next()
+	}
+}
+
+// match finds the operation of the request and the values of the path params
+func (v *Validator) match(method, path string) (*operation, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, r := range v.routes {
+		if len(r.segments) != len(segments) {
+			continue
+		}
+		params := map[string]string{}
+		matched := true
+		for i, segment := range r.segments {
+			if isParam(segment) {
+				params[strings.Trim(segment, "{}")] = segments[i]
+				continue
+			}
+			if segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			// a documented path without the method is left to the router, that answers 405 or 404
+			return r.operations[method], params
+		}
+	}
+	return nil, nil
+}
+
+func (v *Validator) validateParams(ctx *fiber.Ctx, op *operation, params map[string]string) []response.Violation {
+	var violations []response.Violation
+	for _, p := range op.Parameters {
+		var value string
+		switch p.In {
+		case InPath:
+			value = params[p.Name]
+		case InQuery:
+			value = ctx.Query(p.Name)
+		case InHeader:
+			value = ctx.Get(p.Name)
+		default:
+			continue
+		}
+
+		if value == "" {
+			if p.Required {
+				violations = append(violations, response.Violation{In: p.In, Field: p.Name, Message: "is required"})
+			}
+			continue
+		}
+		v.validate(p.Schema, v.coerce(p.Schema, value), p.In, p.Name, &violations)
+	}
+	return violations
+}
+
+// validateBody returns the status used when the body is invalid with the violations found
+func (v *Validator) validateBody(ctx *fiber.Ctx, op *operation) (int, []response.Violation) {
+	rb := op.RequestBody
+	if rb == nil {
+		return 0, nil
+	}
+
+	if media, ok := rb.Content[fiber.MIMEMultipartForm]; ok {
+		if !strings.HasPrefix(ctx.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+			return http.StatusBadRequest, []response.Violation{{In: InBody, Message: "must be " + fiber.MIMEMultipartForm}}
+		}
+		form, err := ctx.MultipartForm()
+		if err != nil {
+			return http.StatusBadRequest, []response.Violation{{In: InBody, Message: "is not a valid form: " + err.Error()}}
+		}
+		return http.StatusUnprocessableEntity, v.validateForm(media.Schema, form)
+	}
+
+	media, ok := rb.Content[fiber.MIMEApplicationJSON]
+	if !ok {
+		return 0, nil
+	}
+
+	// the content type is not checked, as the clients have been sending json without it
+	body := bytes.TrimSpace(ctx.Body())
+	if len(body) == 0 {
+		if rb.Required {
+			return http.StatusBadRequest, []response.Violation{{In: InBody, Message: "is required"}}
+		}
+		return 0, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return http.StatusBadRequest, []response.Violation{{In: InBody, Message: "is not a valid json: " + err.Error()}}
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return http.StatusBadRequest, []response.Violation{{In: InBody, Message: "is not a valid json: has data after the value"}}
+	}
+
+	var violations []response.Violation
+	v.validate(media.Schema, value, InBody, "", &violations)
+	return http.StatusUnprocessableEntity, violations
+}
+
+// validateForm checks the fields of a multipart form, the binary properties are the files
+func (v *Validator) validateForm(s *schema, form *multipart.Form) []response.Violation {
+	s = v.resolve(s)
+	if s == nil {
+		return nil
+	}
+
+	var violations []response.Violation
+	for _, name := range s.Required {
+		if len(form.File[name]) == 0 && len(form.Value[name]) == 0 {
+			violations = append(violations, response.Violation{In: InBody, Field: name, Message: "is required"})
+		}
+	}
+	for _, name := range sortedKeys(s.Properties) {
+		prop := v.resolve(s.Properties[name])
+		if prop == nil || prop.Format == "binary" || len(form.Value[name]) == 0 || form.Value[name][0] == "" {
+			continue
+		}
+		v.validate(prop, v.coerce(prop, form.Value[name][0]), InBody, name, &violations)
+	}
+	return violations
+}
+
+// coerce converts a param or a form value to the type of the schema, keeping it as string when it
+// could not be converted so the violation is reported
+func (v *Validator) coerce(s *schema, value string) interface{} {
+	s = v.resolve(s)
+	if s == nil {
+		return value
+	}
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// validate checks the value against the schema, appending the violations found, value is decoded
+// using json.Number for the numbers
+func (v *Validator) validate(s *schema, value interface{}, in, field string, violations *[]response.Violation) {
+	s = v.resolve(s)
+	if s == nil {
+		return
+	}
+	violate := func(field, message string) {
+		*violations = append(*violations, response.Violation{In: in, Field: field, Message: message})
+	}
+
+	if value == nil {
+		if !s.Nullable {
+			violate(field, "must not be null")
+		}
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			violate(field, "must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				violate(join(field, name), "is required")
+			}
+		}
+		for _, name := range sortedKeys(obj) {
+			if prop, ok := s.Properties[name]; ok {
+				// the read only properties are ignored in the requests, so a fetched value could be sent back
+				if !v.resolve(prop).ReadOnly {
+					v.validate(prop, obj[name], in, join(field, name), violations)
+				}
+				continue
+			}
+			if a := s.AdditionalProperties; a != nil {
+				if a.forbidden {
+					violate(join(field, name), "is not allowed")
+				} else if a.schema != nil {
+					v.validate(a.schema, obj[name], in, join(field, name), violations)
+				}
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			violate(field, "must be an array")
+			return
+		}
+		for i, item := range arr {
+			v.validate(s.Items, item, in, field+"["+strconv.Itoa(i)+"]", violations)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			violate(field, "must be a string")
+			return
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				violate(field, "must be a date-time")
+			}
+		}
+		if s.MinLength != nil && utf8.RuneCountInString(str) < *s.MinLength {
+			violate(field, fmt.Sprintf("must have at least %d characters", *s.MinLength))
+		}
+		if s.MaxLength != nil && utf8.RuneCountInString(str) > *s.MaxLength {
+			violate(field, fmt.Sprintf("must have at most %d characters", *s.MaxLength))
+		}
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			violate(field, "must be "+numberName(s.Type))
+			return
+		}
+		f, err := n.Float64()
+		if err == nil && s.Type == "integer" {
+			_, err = n.Int64()
+		}
+		if err != nil {
+			violate(field, "must be "+numberName(s.Type))
+			return
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			violate(field, fmt.Sprintf("must be at least %v", *s.Minimum))
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			violate(field, fmt.Sprintf("must be at most %v", *s.Maximum))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			violate(field, "must be a boolean")
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 && !contains(s.Enum, value) {
+		options := make([]string, len(s.Enum))
+		for i, option := range s.Enum {
+			options[i] = fmt.Sprint(option)
+		}
+		violate(field, "must be one of "+strings.Join(options, ", "))
+	}
+}
+
+// resolve follows the reference of the schema
+func (v *Validator) resolve(s *schema) *schema {
+	for s != nil && s.Ref != "" {
+		s = v.schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// reject answers the request with the violations found
+func reject(ctx *fiber.Ctx, t response.Type, detail string, violations []response.Violation) error {
+	logging.From(ctx).Warnf("the request does not follow the openapi document: %+v", violations)
+	return response.Send(ctx, response.NewProblem(t, detail, violations...))
+}
+
+func isParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+func numberName(t string) string {
+	if t == "integer" {
+		return "an integer"
+	}
+	return "a number"
+}
+
+func contains(options []interface{}, value interface{}) bool {
+	for _, option := range options {
+		if fmt.Sprint(option) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]interface{}:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]*schema:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]json.RawMessage:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
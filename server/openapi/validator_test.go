@@ -0,0 +1,283 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bgildson/unico-challenge/server/response"
+)
+
+func TestValidatorMiddleware(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+
+	validator, err := NewValidator(Spec)
+	if err != nil {
+		t.Fatalf("could not create the validator: %v", err)
+	}
+
+	app := fiber.New()
+	app.Use(validator.Middleware())
+	app.All("/*", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	form := func(fields map[string]string, file bool) (string, string) {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		for name, value := range fields {
+			w.WriteField(name, value)
+		}
+		if file {
+			part, _ := w.CreateFormFile("file", "feiras.csv")
+			part.Write([]byte("ID,LONG,LAT"))
+		}
+		w.Close()
+		return w.FormDataContentType(), body.String()
+	}
+	validForm, validFormBody := form(map[string]string{"format": "csv"}, true)
+	invalidForm, invalidFormBody := form(map[string]string{"format": "xml"}, false)
+
+	testCases := []struct {
+		name          string
+		method        string
+		target        string
+		contentType   string
+		in            string
+		outStatus     int
+		outViolations []response.Violation
+	}{
+		{
+			name:      "when route is not documented",
+			method:    http.MethodGet,
+			target:    "/metrics?limit=a",
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      "when query params are valid",
+			method:    http.MethodGet,
+			target:    "/feiras-livres?distrito=vila&limit=10&offset=0",
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      "when query params are invalid",
+			method:    http.MethodGet,
+			target:    "/feiras-livres?limit=ten&offset=1.5",
+			outStatus: http.StatusBadRequest,
+			outViolations: []response.Violation{
+				{In: InQuery, Field: "limit", Message: "must be an integer"},
+				{In: InQuery, Field: "offset", Message: "must be an integer"},
+			},
+		},
+		{
+			name:      "when path param is invalid",
+			method:    http.MethodDelete,
+			target:    "/feiras-livres/abc",
+			outStatus: http.StatusBadRequest,
+			outViolations: []response.Violation{
+				{In: InPath, Field: "id", Message: "must be an integer"},
+			},
+		},
+		{
+			name:      "when head uses the get operation",
+			method:    http.MethodHead,
+			target:    "/imports/abc",
+			outStatus: http.StatusBadRequest,
+		},
+		{
+			name:      "when body is required",
+			method:    http.MethodPost,
+			target:    "/feiras-livres",
+			outStatus: http.StatusBadRequest,
+			outViolations: []response.Violation{
+				{In: InBody, Message: "is required"},
+			},
+		},
+		{
+			name:      "when body is not a json",
+			method:    http.MethodPost,
+			target:    "/feiras-livres",
+			in:        `{"nome_feira": "VILA FORMOSA"} {}`,
+			outStatus: http.StatusBadRequest,
+			outViolations: []response.Violation{
+				{In: InBody, Message: "is not a valid json: has data after the value"},
+			},
+		},
+		{
+			name:      "when body does not match the schema",
+			method:    http.MethodPut,
+			target:    "/v2/feiras-livres/1",
+			in:        `{"longitude": -46.550164, "latitude": -123.5, "codigo_distrito": "87", "setor_censitario": 1.5, "nome_feira": null, "id": "ignored"}`,
+			outStatus: http.StatusUnprocessableEntity,
+			outViolations: []response.Violation{
+				{In: InBody, Field: "codigo_distrito", Message: "must be an integer"},
+				{In: InBody, Field: "latitude", Message: "must be at least -90"},
+				{In: InBody, Field: "nome_feira", Message: "must not be null"},
+				{In: InBody, Field: "setor_censitario", Message: "must be an integer"},
+			},
+		},
+		{
+			name:      "when v1 body is partial",
+			method:    http.MethodPost,
+			target:    "/v1/feiras-livres",
+			in:        `{"nome_feira": "VILA FORMOSA", "registro": "4041-0-ABCDE"}`,
+			outStatus: http.StatusOK,
+		},
+		{
+			name:      "when v1 body has the coordinates in millionths of degree",
+			method:    http.MethodPut,
+			target:    "/v1/feiras-livres/1",
+			in:        `{"longitude": -46550164, "latitude": -23558733, "nome_feira": "VILA FORMOSA"}`,
+			outStatus: http.StatusOK,
+		},
+		{
+			name:        "when body matches the schema",
+			method:      http.MethodPost,
+			target:      "/feiras-livres",
+			contentType: fiber.MIMEApplicationJSON,
+			in:          `{"longitude": -46.550164, "latitude": -23.558733, "setor_censitario": 355030885000091, "nome_feira": "VILA FORMOSA"}`,
+			outStatus:   http.StatusOK,
+		},
+		{
+			name:      "when feira misses the required fields",
+			method:    http.MethodPost,
+			target:    "/v2/feiras-livres",
+			in:        `{}`,
+			outStatus: http.StatusUnprocessableEntity,
+			outViolations: []response.Violation{
+				{In: InBody, Field: "longitude", Message: "is required"},
+				{In: InBody, Field: "latitude", Message: "is required"},
+				{In: InBody, Field: "nome_feira", Message: "is required"},
+			},
+		},
+		{
+			name:      "when feira field is longer than the column",
+			method:    http.MethodPost,
+			target:    "/v2/feiras-livres",
+			in:        `{"longitude": -46.550164, "latitude": -23.558733, "nome_feira": "VILA FORMOSA", "registro": "4041-0-ABCDE"}`,
+			outStatus: http.StatusUnprocessableEntity,
+			outViolations: []response.Violation{
+				{In: InBody, Field: "registro", Message: "must have at most 10 characters"},
+			},
+		},
+		{
+			name:      "when grant misses the required fields",
+			method:    http.MethodPost,
+			target:    "/admin/grants",
+			in:        `{"scope": "delete"}`,
+			outStatus: http.StatusUnprocessableEntity,
+			outViolations: []response.Violation{
+				{In: InBody, Field: "subject", Message: "is required"},
+				{In: InBody, Field: "scope", Message: "must be one of write, import"},
+			},
+		},
+		{
+			name:        "when form is valid",
+			method:      http.MethodPost,
+			target:      "/imports",
+			contentType: validForm,
+			in:          validFormBody,
+			outStatus:   http.StatusOK,
+		},
+		{
+			name:        "when form is invalid",
+			method:      http.MethodPost,
+			target:      "/imports",
+			contentType: invalidForm,
+			in:          invalidFormBody,
+			outStatus:   http.StatusUnprocessableEntity,
+			outViolations: []response.Violation{
+				{In: InBody, Field: "file", Message: "is required"},
+				{In: InBody, Field: "format", Message: "must be one of csv, json, ndjson, geojson"},
+			},
+		},
+		{
+			name:        "when form is not multipart",
+			method:      http.MethodPost,
+			target:      "/imports",
+			contentType: fiber.MIMEApplicationJSON,
+			in:          `{}`,
+			outStatus:   http.StatusBadRequest,
+			outViolations: []response.Violation{
+				{In: InBody, Message: "must be multipart/form-data"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.in))
+			if tc.contentType != "" {
+				req.Header.Set(fiber.HeaderContentType, tc.contentType)
+			}
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+			if tc.outViolations == nil {
+				return
+			}
+			if contentType := res.Header.Get(fiber.HeaderContentType); contentType != response.ContentTypeProblem {
+				t.Errorf("was expecting %s, but returns %s", response.ContentTypeProblem, contentType)
+			}
+			var body response.Problem
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatalf("could not decode body: %v", err)
+			}
+			if body.Status != tc.outStatus || !reflect.DeepEqual(body.Errors, tc.outViolations) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.outViolations, body)
+			}
+		})
+	}
+}
+
+func TestNewValidator(t *testing.T) {
+	testCases := []struct {
+		name     string
+		in       string
+		hasError bool
+	}{
+		{name: "when document is not a json", in: "openapi: 3.0.3", hasError: true},
+		{
+			name:     "when parameter reference is unknown",
+			in:       `{"paths": {"/feiras-livres": {"get": {"parameters": [{"$ref": "#/components/parameters/page"}]}}}}`,
+			hasError: true,
+		},
+		{
+			name:     "when schema has an unsupported keyword",
+			in:       `{"components": {"schemas": {"Feira": {"type": "object", "properties": {"registro": {"type": "string", "pattern": "^[0-9]+$"}}}}}}`,
+			hasError: true,
+		},
+		{
+			name:     "when parameter schema has an unsupported keyword",
+			in:       `{"paths": {"/feiras-livres": {"get": {"parameters": [{"name": "limit", "in": "query", "schema": {"oneOf": [{"type": "integer"}]}}]}}}}`,
+			hasError: true,
+		},
+		{name: "when document is valid", in: string(Spec)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewValidator([]byte(tc.in))
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is the interval between the removals of the full buckets
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// refill adds the tokens accumulated since the last update
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*b.limit.rate())
+	b.updated = now
+}
+
+// full returns the time until the bucket is full
+func (b *bucket) full() time.Duration {
+	return duration((float64(b.limit.Burst) - b.tokens) / b.limit.rate())
+}
+
+// MemoryStore keeps the buckets in the process memory, the full buckets are removed
+// periodically, since they are the same as a new one
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryStore creates a Store kept in the process memory
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Take implements how to take a token from the bucket of the key
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if limit.PerMinute <= 0 || limit.Burst <= 0 {
+		return Result{Allowed: false, RetryAfter: time.Minute, Reset: time.Minute}, nil
+	}
+
+	b, ok := s.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Burst), updated: now, limit: limit}
+		s.buckets[key] = b
+	}
+	b.refill(now)
+
+	if b.tokens < 1 {
+		return Result{
+			Allowed:    false,
+			Remaining:  0,
+			RetryAfter: duration((1 - b.tokens) / limit.rate()),
+			Reset:      b.full(),
+		}, nil
+	}
+
+	b.tokens--
+	return Result{
+		Allowed:   true,
+		Remaining: int(b.tokens),
+		Reset:     b.full(),
+	}, nil
+}
+
+// Refund implements how to give back a token to the bucket of the key
+func (s *MemoryStore) Refund(_ context.Context, key string, limit Limit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// a missing bucket is already full
+	b, ok := s.buckets[key]
+	if !ok || b.limit != limit {
+		return nil
+	}
+	b.refill(s.now())
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+1)
+
+	return nil
+}
+
+// sweep removes the buckets that are full again
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func duration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
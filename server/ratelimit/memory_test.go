@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	limit := Limit{PerMinute: 60, Burst: 2}
+
+	take := func(key string) Result {
+		res, err := s.Take(context.Background(), key, limit)
+		if err != nil {
+			t.Fatalf("was not expecting an error, but returns %v", err)
+		}
+		return res
+	}
+
+	if res := take("a"); !res.Allowed || res.Remaining != 1 || res.Reset != time.Second {
+		t.Errorf("was expecting the first token allowed, but returns %+v", res)
+	}
+	if res := take("a"); !res.Allowed || res.Remaining != 0 || res.Reset != 2*time.Second {
+		t.Errorf("was expecting the second token allowed, but returns %+v", res)
+	}
+	if res := take("a"); res.Allowed || res.RetryAfter != time.Second {
+		t.Errorf("was expecting the bucket empty, but returns %+v", res)
+	}
+	if res := take("b"); !res.Allowed {
+		t.Errorf("was expecting the buckets separated by key, but returns %+v", res)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if res := take("a"); res.Allowed || res.RetryAfter != 500*time.Millisecond {
+		t.Errorf("was expecting half a token refilled, but returns %+v", res)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if res := take("a"); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("was expecting a token refilled, but returns %+v", res)
+	}
+}
+
+func TestMemoryStoreRefund(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	limit := Limit{PerMinute: 60, Burst: 1}
+
+	if err := s.Refund(context.Background(), "a", limit); err != nil {
+		t.Errorf("was not expecting an error, but returns %v", err)
+	}
+	s.Take(context.Background(), "a", limit)
+	s.Refund(context.Background(), "a", limit)
+	s.Refund(context.Background(), "a", limit)
+
+	if res, _ := s.Take(context.Background(), "a", limit); !res.Allowed || res.Remaining != 0 {
+		t.Errorf("was expecting the token refunded up to the burst, but returns %+v", res)
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	s.lastSweep = now
+	limit := Limit{PerMinute: 60, Burst: 10}
+
+	s.Take(context.Background(), "idle", limit)
+	now = now.Add(sweepInterval)
+	s.Take(context.Background(), "active", limit)
+
+	if _, ok := s.buckets["idle"]; ok {
+		t.Error("was expecting the full bucket removed")
+	}
+	if _, ok := s.buckets["active"]; !ok {
+		t.Error("was expecting the active bucket kept")
+	}
+}
@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/bgildson/unico-challenge/server/auth"
+	"github.com/bgildson/unico-challenge/server/logging"
+	"github.com/bgildson/unico-challenge/server/response"
+)
+
+const (
+	// HeaderLimit informs the size of the bucket
+	HeaderLimit = "RateLimit-Limit"
+	// HeaderRemaining informs how many requests could be done right now
+	HeaderRemaining = "RateLimit-Remaining"
+	// HeaderReset informs the seconds until the bucket is full again
+	HeaderReset = "RateLimit-Reset"
+)
+
+// Limit is a token bucket refilled with PerMinute tokens every minute holding at most Burst tokens
+type Limit struct {
+	PerMinute int
+	Burst     int
+}
+
+// rate returns the tokens refilled per second
+func (l Limit) rate() float64 {
+	return float64(l.PerMinute) / 60
+}
+
+// Result is the state of the bucket after taking a token
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is the time until a token is available, zero when Allowed
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+}
+
+// Store represents where the buckets are kept, the in-process MemoryStore limits each instance
+// and a shared store limits every instance together
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+	Refund(ctx context.Context, key string, limit Limit) error
+}
+
+// KeyFunc identifies the client of the request
+type KeyFunc func(ctx *fiber.Ctx) string
+
+// ClientKey identifies the client by the principal authenticated by auth.Identify before the limit
+// or by its ip, so the requests with a missing or invalid credential spend the budget of the ip
+func ClientKey(ctx *fiber.Ctx) string {
+	if principal := auth.PrincipalOf(ctx); principal != nil {
+		return "principal:" + principal.Subject
+	}
+	return "ip:" + ctx.IP()
+}
+
+// IPKey identifies the client only by its ip, it is used by the limit before auth.Identify, so the
+// floods of credentials are throttled before they are looked up
+func IPKey(ctx *fiber.Ctx) string {
+	return "ip:" + ctx.IP()
+}
+
+// PrincipalKey identifies the client by the principal authenticated by auth.Identify before the
+// limit, the requests without principal are not limited, so they only spend the budget of the limit
+// using IPKey before auth.Identify
+func PrincipalKey(ctx *fiber.Ctx) string {
+	if principal := auth.PrincipalOf(ctx); principal != nil {
+		return "principal:" + principal.Subject
+	}
+	return ""
+}
+
+// takenKey is the key of the ctx locals keeping the token taken by the last limit
+const takenKey = "ratelimit_taken"
+
+// taken is the token taken by a limit, refunded when a later limit identifies the client
+type taken struct {
+	store Store
+	key   string
+	limit Limit
+}
+
+// Config contains the budgets of the read (GET and HEAD) and of the write routes, Prefix keeps the
+// buckets of the limits sharing a store apart
+type Config struct {
+	Read   Limit
+	Write  Limit
+	Key    KeyFunc
+	Prefix string
+}
+
+// Middleware creates a handler taking a token of the client budget, the requests without tokens
+// are answered with 429 and, when the store fails, the requests are allowed, the requests whose Key
+// is empty are not limited and the token taken by an earlier limit is refunded, so every request
+// spends and informs only the budget of the last limit that identified it
+func Middleware(store Store, config Config) fiber.Handler {
+	if config.Key == nil {
+		config.Key = ClientKey
+	}
+
+	return func(ctx *fiber.Ctx) error {
+		budget, limit := "write", config.Write
+		if ctx.Method() == fiber.MethodGet || ctx.Method() == fiber.MethodHead {
+			budget, limit = "read", config.Read
+		}
+
+		client := config.Key(ctx)
+		if client == "" {
+			return ctx.Next()
+		}
+
+		key := config.Prefix + budget + ":" + client
+		res, err := store.Take(ctx.UserContext(), key, limit)
+		if err != nil {
+			logging.From(ctx).Errorf("could not check the rate limit: %v", err)
+			return ctx.Next()
+		}
+
+		if prev, ok := ctx.Locals(takenKey).(taken); ok {
+			if err := prev.store.Refund(ctx.UserContext(), prev.key, prev.limit); err != nil {
+				logging.From(ctx).Errorf("could not refund the rate limit: %v", err)
+			}
+		}
+		ctx.Locals(takenKey, taken{store: store, key: key, limit: limit})
+
+		ctx.Set(HeaderLimit, strconv.Itoa(limit.Burst))
+		ctx.Set(HeaderRemaining, strconv.Itoa(res.Remaining))
+		ctx.Set(HeaderReset, seconds(res.Reset))
+
+		if !res.Allowed {
+			logging.From(ctx).Warnf("the %s rate limit was exceeded", budget)
+			ctx.Set(fiber.HeaderRetryAfter, seconds(res.RetryAfter))
+			return response.Send(ctx, response.NewProblem(
+				response.TypeTooManyRequests,
+				"the "+budget+" rate limit was exceeded, retry after "+seconds(res.RetryAfter)+" seconds",
+			))
+		}
+
+		return ctx.Next()
+	}
+}
+
+// seconds formats the duration as seconds, rounded up
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,178 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/server/auth"
+)
+
+type keys struct{}
+
+func (keys) Authenticate(_ context.Context, credential string) (*entity.Principal, error) {
+	if credential != "uc_key" {
+		return nil, fmt.Errorf("unknown key: %w", entity.ErrInvalidCredential)
+	}
+	return &entity.Principal{Subject: "apikey:1:reader", Scopes: []string{entity.ScopeRead}}, nil
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit) (Result, error) {
+	return Result{}, errors.New("store is down")
+}
+
+func (failingStore) Refund(context.Context, string, Limit) error {
+	return errors.New("store is down")
+}
+
+func TestMiddleware(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	app := fiber.New()
+	app.Use(auth.New(keys{}, true).Identify())
+	app.Use(Middleware(NewMemoryStore(), Config{
+		Read:  Limit{PerMinute: 60, Burst: 2},
+		Write: Limit{PerMinute: 60, Burst: 1},
+	}))
+	app.All("/", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name         string
+		method       string
+		credential   string
+		outStatus    int
+		outRemaining string
+	}{
+		{name: "when reading the first time", method: http.MethodGet, outStatus: http.StatusOK, outRemaining: "1"},
+		{name: "when reading the second time", method: http.MethodGet, outStatus: http.StatusOK, outRemaining: "0"},
+		{name: "when the read budget is exceeded", method: http.MethodGet, outStatus: http.StatusTooManyRequests, outRemaining: "0"},
+		{name: "when writing with a separate budget", method: http.MethodPost, outStatus: http.StatusOK, outRemaining: "0"},
+		{name: "when the write budget is exceeded", method: http.MethodPut, outStatus: http.StatusTooManyRequests, outRemaining: "0"},
+		{name: "when reading with a credential", method: http.MethodGet, credential: "uc_key", outStatus: http.StatusOK, outRemaining: "1"},
+		{name: "when reading with an invalid credential", method: http.MethodGet, credential: "uc_guess", outStatus: http.StatusTooManyRequests, outRemaining: "0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/", nil)
+			if tc.credential != "" {
+				req.Header.Set(auth.HeaderAPIKey, tc.credential)
+			}
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+			if remaining := res.Header.Get(HeaderRemaining); remaining != tc.outRemaining {
+				t.Errorf("was expecting %s remaining, but returns %s", tc.outRemaining, remaining)
+			}
+			if res.Header.Get(HeaderLimit) == "" || res.Header.Get(HeaderReset) == "" {
+				t.Errorf("was expecting the rate limit headers, but returns %v", res.Header)
+			}
+			if tc.outStatus == http.StatusTooManyRequests && res.Header.Get(fiber.HeaderRetryAfter) != "1" {
+				t.Errorf("was expecting to retry after 1 second, but returns %s", res.Header.Get(fiber.HeaderRetryAfter))
+			}
+		})
+	}
+}
+
+type countingKeys struct {
+	keys
+	calls *int
+}
+
+func (c countingKeys) Authenticate(ctx context.Context, credential string) (*entity.Principal, error) {
+	*c.calls++
+	return c.keys.Authenticate(ctx, credential)
+}
+
+// TestIPMiddleware fails when the credentials are looked up after the ip budget is exceeded, the
+// requests of the principals spend the ip budget or the headers are not of the budget spent
+func TestIPMiddleware(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	var calls int
+	store := NewMemoryStore()
+	ipLimit := Limit{PerMinute: 60, Burst: 2}
+	app := fiber.New()
+	app.Use(Middleware(store, Config{Read: ipLimit, Write: ipLimit, Key: IPKey, Prefix: "identify:"}))
+	app.Use(auth.New(countingKeys{calls: &calls}, true).Identify())
+	app.Use(Middleware(store, Config{
+		Read:  Limit{PerMinute: 60, Burst: 3},
+		Write: Limit{PerMinute: 60, Burst: 3},
+		Key:   PrincipalKey,
+	}))
+	app.All("/", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name         string
+		credential   string
+		outStatus    int
+		outCalls     int
+		outLimit     string
+		outRemaining string
+	}{
+		{name: "when reading with a credential", credential: "uc_key", outStatus: http.StatusOK, outCalls: 1, outLimit: "3", outRemaining: "2"},
+		{name: "when reading with a credential again", credential: "uc_key", outStatus: http.StatusOK, outCalls: 2, outLimit: "3", outRemaining: "1"},
+		{name: "when the credential did not spend the ip budget", credential: "uc_key", outStatus: http.StatusOK, outCalls: 3, outLimit: "3", outRemaining: "0"},
+		{name: "when guessing the first time", credential: "uc_guess", outStatus: http.StatusOK, outCalls: 4, outLimit: "2", outRemaining: "1"},
+		{name: "when guessing the second time", credential: "uc_guess", outStatus: http.StatusOK, outCalls: 5, outLimit: "2", outRemaining: "0"},
+		{name: "when the ip budget is exceeded", credential: "uc_guess", outStatus: http.StatusTooManyRequests, outCalls: 5, outLimit: "2", outRemaining: "0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(auth.HeaderAPIKey, tc.credential)
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+			if calls != tc.outCalls {
+				t.Errorf("was expecting %d lookups, but returns %d", tc.outCalls, calls)
+			}
+			if limit := res.Header.Get(HeaderLimit); limit != tc.outLimit {
+				t.Errorf("was expecting the limit %s, but returns %s", tc.outLimit, limit)
+			}
+			if remaining := res.Header.Get(HeaderRemaining); remaining != tc.outRemaining {
+				t.Errorf("was expecting %s remaining, but returns %s", tc.outRemaining, remaining)
+			}
+		})
+	}
+}
+
+func TestMiddlewareWhenStoreFails(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	app := fiber.New()
+	app.Use(Middleware(failingStore{}, Config{Read: Limit{PerMinute: 1, Burst: 1}}))
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("was expecting %v, but returns %v", http.StatusOK, res.StatusCode)
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// UnmatchedRoute is the route used for the requests that did not match any route
+const UnmatchedRoute = "unmatched"
+
+// RouteOf returns the pattern of the route that handled the request, own is the route of
+// the middleware calling it, so when it is the last route matched no route was found
+func RouteOf(ctx *fiber.Ctx, own *fiber.Route) string {
+	route := ctx.Route()
+	if route == own {
+		return UnmatchedRoute
+	}
+	return route.Path
+}
+
+// StatusOf returns the status sent for the request, the error handler sets the status
+// of the returned errors only after the middlewares return
+func StatusOf(ctx *fiber.Ctx, err error) int {
+	if err == nil {
+		return ctx.Response().StatusCode()
+	}
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		return fe.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// RequestIDKey is the ctx.Locals key where the requestid middleware stores the request id
+const RequestIDKey = "requestid"
+
+// RequestIDOf returns the id of the request, empty when the requestid middleware is not used
+func RequestIDOf(ctx *fiber.Ctx) string {
+	id, _ := ctx.Locals(RequestIDKey).(string)
+	return id
+}
@@ -0,0 +1,77 @@
+package response
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+
+	"github.com/bgildson/unico-challenge/server/logging"
+)
+
+// Error answers the request with the problem matching err, see FromError
+func Error(ctx *fiber.Ctx, err error, detail string) error {
+	return Send(ctx, FromError(err, detail))
+}
+
+// FromError maps the errors of the repositories to problems, detail describes what could not be
+// done and is completed with the reason, as "could not get the feira livre 1: it does not exist",
+// the reason of the unexpected errors is only logged
+func FromError(err error, detail string) Problem {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return NewProblem(TypeConflict, detail+": it conflicts with an existing one")
+		case "foreign_key_violation":
+			return NewProblem(TypeConflict, detail+": it references or is referenced by a missing one")
+		case "query_canceled":
+			return NewProblem(TypeUnavailable, detail+": the database did not answer in time, try again later")
+		}
+	}
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return NewProblem(TypeNotFound, detail+": it does not exist")
+	case errors.Is(err, context.DeadlineExceeded):
+		return NewProblem(TypeUnavailable, detail+": the database did not answer in time, try again later")
+	}
+
+	return NewProblem(TypeInternal, detail+", try again later and inform the request id if it persists")
+}
+
+// NotFound returns fiber.ErrNotFound for the requests not matched by any route, so they are
+// answered by the ErrorHandler, because fiber answers them with a plain text 404 otherwise while
+// the handlers answer theirs as problems, it must be the first handler registered so it wraps every
+// other without being taken as the route matched by the metrics, the access log and the traces
+func NotFound(ctx *fiber.Ctx) error {
+	err := ctx.Next()
+	if err == nil && ctx.Response().StatusCode() == http.StatusNotFound &&
+		string(ctx.Response().Header.ContentType()) != ContentTypeProblem {
+		return fiber.ErrNotFound
+	}
+	return err
+}
+
+// ErrorHandler answers the errors returned by the handlers, as the routes not found, with problems
+func ErrorHandler(ctx *fiber.Ctx, err error) error {
+	var fe *fiber.Error
+	if !errors.As(err, &fe) {
+		logging.From(ctx).Errorf("unexpected error: %v", err)
+		return Send(ctx, NewProblem(TypeInternal, "unexpected error, try again later and inform the request id if it persists"))
+	}
+
+	switch fe.Code {
+	case http.StatusNotFound:
+		return Send(ctx, NewProblem(TypeNotFound, "no route matches "+ctx.Method()+" "+ctx.Path()))
+	case http.StatusMethodNotAllowed:
+		return Send(ctx, NewProblem(TypeMethodNotAllowed, "the route does not accept "+ctx.Method()))
+	case http.StatusRequestEntityTooLarge:
+		return Send(ctx, NewProblem(TypePayloadTooLarge, "the body is bigger than the limit"))
+	}
+	// about:blank is the type of the problems described only by their status
+	return Send(ctx, Problem{Type: "about:blank", Title: http.StatusText(fe.Code), Status: fe.Code, Detail: fe.Message})
+}
@@ -0,0 +1,143 @@
+package response
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+func TestFromError(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   error
+		out  Problem
+	}{
+		{
+			name: "when register does not exist",
+			in:   fmt.Errorf("could not scan: %w", sql.ErrNoRows),
+			out:  NewProblem(TypeNotFound, "could not get: it does not exist"),
+		},
+		{
+			name: "when violates an unique constraint",
+			in:   &pq.Error{Code: "23505"},
+			out:  NewProblem(TypeConflict, "could not get: it conflicts with an existing one"),
+		},
+		{
+			name: "when violates a foreign key",
+			in:   &pq.Error{Code: "23503"},
+			out:  NewProblem(TypeConflict, "could not get: it references or is referenced by a missing one"),
+		},
+		{
+			name: "when query was canceled",
+			in:   &pq.Error{Code: "57014"},
+			out:  NewProblem(TypeUnavailable, "could not get: the database did not answer in time, try again later"),
+		},
+		{
+			name: "when deadline was exceeded",
+			in:   context.DeadlineExceeded,
+			out:  NewProblem(TypeUnavailable, "could not get: the database did not answer in time, try again later"),
+		},
+		{
+			name: "when error is unexpected",
+			in:   errors.New("unexpected error"),
+			out:  NewProblem(TypeInternal, "could not get, try again later and inform the request id if it persists"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if out := FromError(tc.in, "could not get"); !reflect.DeepEqual(out, tc.out) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, out)
+			}
+		})
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(NotFound)
+	app.Get("/feiras-livres", func(ctx *fiber.Ctx) error {
+		return errors.New("unexpected error")
+	})
+	app.Get("/teapot", func(ctx *fiber.Ctx) error {
+		return fiber.NewError(http.StatusTeapot, "no coffee")
+	})
+	app.Group("/v1", func(ctx *fiber.Ctx) error {
+		return ctx.Next()
+	})
+
+	testCases := []struct {
+		name   string
+		method string
+		target string
+		out    Problem
+	}{
+		{
+			name:   "when route does not exist",
+			method: http.MethodGet,
+			target: "/unknown",
+			out:    NewProblem(TypeNotFound, "no route matches GET /unknown"),
+		},
+		{
+			name:   "when route does not exist under a group",
+			method: http.MethodGet,
+			target: "/v1/unknown",
+			out:    NewProblem(TypeNotFound, "no route matches GET /v1/unknown"),
+		},
+		{
+			name:   "when method is not allowed",
+			method: http.MethodPatch,
+			target: "/feiras-livres",
+			out:    NewProblem(TypeMethodNotAllowed, "the route does not accept PATCH"),
+		},
+		{
+			name:   "when handler returns an unexpected error",
+			method: http.MethodGet,
+			target: "/feiras-livres",
+			out:    NewProblem(TypeInternal, "unexpected error, try again later and inform the request id if it persists"),
+		},
+		{
+			name:   "when handler returns another status",
+			method: http.MethodGet,
+			target: "/teapot",
+			out:    Problem{Type: "about:blank", Title: http.StatusText(http.StatusTeapot), Status: http.StatusTeapot, Detail: "no coffee"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := app.Test(httptest.NewRequest(tc.method, tc.target, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res.StatusCode != tc.out.Status {
+				t.Errorf("was expecting %v, but returns %v", tc.out.Status, res.StatusCode)
+			}
+			if contentType := res.Header.Get(fiber.HeaderContentType); contentType != ContentTypeProblem {
+				t.Errorf("was expecting %s, but returns %s", ContentTypeProblem, contentType)
+			}
+			var body Problem
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+				t.Fatalf("could not decode body: %v", err)
+			}
+			tc.out.Instance = tc.target
+			if !reflect.DeepEqual(body, tc.out) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, body)
+			}
+		})
+	}
+}
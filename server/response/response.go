@@ -1,7 +1,99 @@
 package response
 
-// Generic represents a generic response for errors
-type Generic struct {
-	Code    int    `json:"code"`
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/bgildson/unico-challenge/server"
+)
+
+// ContentTypeProblem is the content type of the error responses
+const ContentTypeProblem = "application/problem+json"
+
+// Type identifies a kind of problem, URI is resolved against the api url and documented in the
+// OpenAPI document, Title and Status are the same for every problem of the type
+type Type struct {
+	URI    string
+	Title  string
+	Status int
+}
+
+var (
+	// TypeInvalidRequest is used when a param or the body could not be parsed
+	TypeInvalidRequest = Type{URI: "/problems/invalid-request", Title: "Invalid request", Status: http.StatusBadRequest}
+	// TypeUnauthorized is used when the credential is missing or invalid
+	TypeUnauthorized = Type{URI: "/problems/unauthorized", Title: "Unauthorized", Status: http.StatusUnauthorized}
+	// TypeForbidden is used when the credential does not allow the request
+	TypeForbidden = Type{URI: "/problems/forbidden", Title: "Forbidden", Status: http.StatusForbidden}
+	// TypeNotFound is used when the route or the resource does not exist
+	TypeNotFound = Type{URI: "/problems/not-found", Title: "Not found", Status: http.StatusNotFound}
+	// TypeMethodNotAllowed is used when the route does not accept the method
+	TypeMethodNotAllowed = Type{URI: "/problems/method-not-allowed", Title: "Method not allowed", Status: http.StatusMethodNotAllowed}
+	// TypeConflict is used when the request conflicts with the state of a resource
+	TypeConflict = Type{URI: "/problems/conflict", Title: "Conflict", Status: http.StatusConflict}
+	// TypePayloadTooLarge is used when the body is bigger than the limit
+	TypePayloadTooLarge = Type{URI: "/problems/payload-too-large", Title: "Payload too large", Status: http.StatusRequestEntityTooLarge}
+	// TypeValidation is used when the body was parsed but its values are invalid
+	TypeValidation = Type{URI: "/problems/validation", Title: "Validation failed", Status: http.StatusUnprocessableEntity}
+	// TypeTooManyRequests is used when the rate limit was exceeded
+	TypeTooManyRequests = Type{URI: "/problems/too-many-requests", Title: "Too many requests", Status: http.StatusTooManyRequests}
+	// TypeInternal is used by the unexpected errors
+	TypeInternal = Type{URI: "/problems/internal", Title: "Internal error", Status: http.StatusInternalServerError}
+	// TypeUnavailable is used when a dependency, as the database, could not answer in time
+	TypeUnavailable = Type{URI: "/problems/unavailable", Title: "Service unavailable", Status: http.StatusServiceUnavailable}
+)
+
+// Problem represents an error response as defined by the RFC 7807, Detail explains this occurrence,
+// Instance is the path requested, RequestID ties the response to the logs and Errors lists every
+// problem found when the request is invalid
+type Problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Instance  string      `json:"instance,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+	Errors    []Violation `json:"errors,omitempty"`
+}
+
+const (
+	// InPath is used by the violations of the path params
+	InPath = "path"
+	// InQuery is used by the violations of the query params
+	InQuery = "query"
+	// InHeader is used by the violations of the header params
+	InHeader = "header"
+	// InBody is used by the violations of the body
+	InBody = "body"
+)
+
+// Violation represents a problem of the request, In is where it was found (path, query or body)
+// and Field is the param name or the path of the body field, as items[0].nome_feira
+type Violation struct {
+	In      string `json:"in"`
+	Field   string `json:"field,omitempty"`
 	Message string `json:"message"`
 }
+
+// NewProblem creates a problem of the type
+func NewProblem(t Type, detail string, violations ...Violation) Problem {
+	return Problem{
+		Type:   t.URI,
+		Title:  t.Title,
+		Status: t.Status,
+		Detail: detail,
+		Errors: violations,
+	}
+}
+
+// Send answers the request with the problem, filling its instance and request id
+func Send(ctx *fiber.Ctx, p Problem) error {
+	p.Instance = ctx.Path()
+	p.RequestID = server.RequestIDOf(ctx)
+	if err := ctx.Status(p.Status).JSON(p); err != nil {
+		return err
+	}
+	ctx.Set(fiber.HeaderContentType, ContentTypeProblem)
+	return nil
+}
@@ -0,0 +1,53 @@
+// Package routes attachs the api controllers to their paths, it is shared by the serve command and
+// by the test checking the routes against the OpenAPI document
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	feiralivreController "github.com/bgildson/unico-challenge/controller/feiralivre"
+	grantController "github.com/bgildson/unico-challenge/controller/grant"
+	importjobController "github.com/bgildson/unico-challenge/controller/importjob"
+)
+
+const (
+	// FeirasLivresPath is where the feiras livres are served, under each version prefix
+	FeirasLivresPath = "/feiras-livres"
+	// ImportsPath is where the import jobs are created from the uploads
+	ImportsPath = "/imports"
+	// GrantsPath is where the admins manage the grants
+	GrantsPath = "/admin/grants"
+)
+
+// Controllers contains the controllers attached by Register, Grant is nil when the authentication
+// is disabled
+type Controllers struct {
+	FeiraLivre *feiralivreController.Controller
+	ImportJob  *importjobController.Controller
+	Grant      *grantController.Controller
+}
+
+// Deprecation creates the middleware marking the v1 routes as deprecated, prefix is the version
+// prefix of the routes, empty for the routes without version
+type Deprecation func(prefix string) fiber.Handler
+
+// Register attachs the controllers routes to the fiber app, the v1 routes and the routes without
+// version are marked as deprecated when deprecation is not nil
+func Register(app *fiber.App, c Controllers, deprecation Deprecation) {
+	var v1, unversioned []fiber.Handler
+	if deprecation != nil {
+		v1 = append(v1, deprecation("/v1"))
+		unversioned = append(unversioned, deprecation(""))
+	}
+
+	c.FeiraLivre.Register(app.Group("/v1", v1...), FeirasLivresPath, feiralivreController.V1)
+	c.FeiraLivre.Register(app.Group("/v2"), FeirasLivresPath, feiralivreController.V2)
+	// the routes without version answer as the v1, kept for the clients older than the versions
+	c.FeiraLivre.Register(app.Group(FeirasLivresPath, unversioned...), "", feiralivreController.V1)
+
+	c.ImportJob.Register(app, ImportsPath)
+
+	if c.Grant != nil {
+		c.Grant.Register(app, GrantsPath)
+	}
+}
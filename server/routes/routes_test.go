@@ -0,0 +1,52 @@
+package routes
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	feiralivreController "github.com/bgildson/unico-challenge/controller/feiralivre"
+	grantController "github.com/bgildson/unico-challenge/controller/grant"
+	healthController "github.com/bgildson/unico-challenge/controller/health"
+	importjobController "github.com/bgildson/unico-challenge/controller/importjob"
+	"github.com/bgildson/unico-challenge/server/openapi"
+)
+
+var param = regexp.MustCompile(`:(\w+)`)
+
+// TestOperations fails when a route is registered by a controller and is not in the document or
+// the opposite, the routes are registered as the serve command does
+func TestOperations(t *testing.T) {
+	app := fiber.New()
+	// the routes are registered without the deprecation middleware, which fiber lists as routes
+	Register(app, Controllers{
+		FeiraLivre: feiralivreController.New(nil, nil, nil, nil, nil),
+		ImportJob:  importjobController.New(nil, nil, nil, nil),
+		Grant:      grantController.New(nil, nil, nil),
+	}, nil)
+	healthController.New(nil, nil, 0).Register(app)
+
+	var registered []string
+	for _, stack := range app.Stack() {
+		for _, route := range stack {
+			// fiber registers a HEAD route for every GET route
+			if route.Method == fiber.MethodHead {
+				continue
+			}
+			registered = append(registered, route.Method+" "+param.ReplaceAllString(route.Path, "{$1}"))
+		}
+	}
+	sort.Strings(registered)
+
+	operations, err := openapi.Operations()
+	if err != nil {
+		t.Fatalf("could not parse the document: %v", err)
+	}
+
+	if !reflect.DeepEqual(registered, operations) {
+		t.Errorf("was expecting the routes %v, but the document has %v", registered, operations)
+	}
+}
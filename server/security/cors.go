@@ -0,0 +1,45 @@
+package security
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+)
+
+// CORSConfig contains the origins allowed to call the api from a browser and what they could
+// send and read, an origin could be a wildcard subdomain as https://*.example.com
+type CORSConfig struct {
+	AllowOrigins     []string
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	// MaxAge is how long the browsers cache the preflight responses
+	MaxAge time.Duration
+}
+
+// CORS creates a handler answering the preflight requests and adding the CORS headers to the
+// responses of the origins allowed
+func CORS(config CORSConfig) fiber.Handler {
+	return cors.New(cors.Config{
+		AllowOrigins:     strings.Join(config.AllowOrigins, ","),
+		AllowMethods:     strings.Join(config.AllowMethods, ","),
+		AllowHeaders:     strings.Join(config.AllowHeaders, ","),
+		ExposeHeaders:    strings.Join(config.ExposeHeaders, ","),
+		AllowCredentials: config.AllowCredentials,
+		MaxAge:           int(config.MaxAge.Seconds()),
+	})
+}
+
+// ParseList splits a comma separated list, ignoring the spaces and the empty items
+func ParseList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,35 @@
+package security
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// ContentSecurityPolicy forbids loading anything from the responses, as the api only serves data
+	ContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+)
+
+// Headers creates a handler adding the standard security headers to every response, the
+// Strict-Transport-Security is only sent when hstsMaxAge is positive, as it requires https
+func Headers(hstsMaxAge time.Duration) fiber.Handler {
+	hsts := ""
+	if hstsMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(hstsMaxAge.Seconds())) + "; includeSubDomains"
+	}
+
+	return func(ctx *fiber.Ctx) error {
+		// set before the handlers, so the error responses also have them and a route could relax them
+		ctx.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+		ctx.Set(fiber.HeaderXFrameOptions, "DENY")
+		ctx.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+		ctx.Set(fiber.HeaderContentSecurityPolicy, ContentSecurityPolicy)
+		if hsts != "" {
+			ctx.Set(fiber.HeaderStrictTransportSecurity, hsts)
+		}
+
+		return ctx.Next()
+	}
+}
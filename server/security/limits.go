@@ -0,0 +1,99 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/bgildson/unico-challenge/server/logging"
+	"github.com/bgildson/unico-challenge/server/response"
+)
+
+// LimitsConfig contains the limits of the request bodies, the uploads are only limited by the
+// server body limit
+type LimitsConfig struct {
+	// MaxBodySize is the maximum size in bytes of a body
+	MaxBodySize int
+	// MaxJSONDepth is the maximum nesting of the objects and arrays of a body
+	MaxJSONDepth int
+	// UploadPaths are the paths receiving the uploads with POST, the only requests not limited
+	UploadPaths []string
+}
+
+// upload checks if the request is a POST to one of the paths, matched as the router does, ignoring
+// the case and a trailing slash
+func upload(ctx *fiber.Ctx, paths []string) bool {
+	if ctx.Method() != fiber.MethodPost {
+		return false
+	}
+	path := strings.TrimSuffix(ctx.Path(), "/")
+	for _, p := range paths {
+		if strings.EqualFold(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Limits creates a handler rejecting the bodies bigger than MaxBodySize with 413 and the bodies
+// nested deeper than MaxJSONDepth with 400, before they are decoded by the controllers
+func Limits(config LimitsConfig) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if upload(ctx, config.UploadPaths) {
+			return ctx.Next()
+		}
+
+		body := ctx.Body()
+		if config.MaxBodySize > 0 && len(body) > config.MaxBodySize {
+			logging.From(ctx).Warnf("the body has %d bytes, the maximum is %d", len(body), config.MaxBodySize)
+			return response.Send(ctx, response.NewProblem(
+				response.TypePayloadTooLarge,
+				fmt.Sprintf("the body has %d bytes, the maximum is %d", len(body), config.MaxBodySize),
+			))
+		}
+
+		if config.MaxJSONDepth > 0 && exceedsDepth(body, config.MaxJSONDepth) {
+			logging.From(ctx).Warnf("the body is nested deeper than %d levels", config.MaxJSONDepth)
+			return response.Send(ctx, response.NewProblem(
+				response.TypeInvalidRequest,
+				fmt.Sprintf("the body is nested deeper than %d levels", config.MaxJSONDepth),
+			))
+		}
+
+		return ctx.Next()
+	}
+}
+
+// exceedsDepth scans the body counting the objects and arrays opened, ignoring the brackets
+// inside the strings, without decoding it
+func exceedsDepth(body []byte, max int) bool {
+	depth := 0
+	inString, escaped := false, false
+	for _, b := range body {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch b {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > max {
+				return true
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+package security
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCORS(t *testing.T) {
+	app := fiber.New()
+	app.Use(CORS(CORSConfig{
+		AllowOrigins:  []string{"https://app.example.com", "https://*.feiras.example.com"},
+		AllowMethods:  []string{http.MethodGet, http.MethodPost},
+		AllowHeaders:  []string{fiber.HeaderAuthorization, fiber.HeaderContentType},
+		ExposeHeaders: []string{fiber.HeaderXRequestID},
+		MaxAge:        10 * time.Minute,
+	}))
+	app.All("/", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name       string
+		method     string
+		origin     string
+		outStatus  int
+		outHeaders map[string]string
+	}{
+		{
+			name:      "when origin is allowed",
+			method:    http.MethodGet,
+			origin:    "https://app.example.com",
+			outStatus: http.StatusOK,
+			outHeaders: map[string]string{
+				fiber.HeaderAccessControlAllowOrigin:   "https://app.example.com",
+				fiber.HeaderAccessControlExposeHeaders: fiber.HeaderXRequestID,
+			},
+		},
+		{
+			name:      "when origin is a subdomain allowed",
+			method:    http.MethodGet,
+			origin:    "https://norte.feiras.example.com",
+			outStatus: http.StatusOK,
+			outHeaders: map[string]string{
+				fiber.HeaderAccessControlAllowOrigin: "https://norte.feiras.example.com",
+			},
+		},
+		{
+			name:      "when origin is not allowed",
+			method:    http.MethodGet,
+			origin:    "https://evil.com",
+			outStatus: http.StatusOK,
+			outHeaders: map[string]string{
+				fiber.HeaderAccessControlAllowOrigin: "",
+			},
+		},
+		{
+			name:      "when preflight",
+			method:    http.MethodOptions,
+			origin:    "https://app.example.com",
+			outStatus: http.StatusNoContent,
+			outHeaders: map[string]string{
+				fiber.HeaderAccessControlAllowOrigin:  "https://app.example.com",
+				fiber.HeaderAccessControlAllowMethods: "GET,POST",
+				fiber.HeaderAccessControlAllowHeaders: "Authorization,Content-Type",
+				fiber.HeaderAccessControlMaxAge:       "600",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/", nil)
+			req.Header.Set(fiber.HeaderOrigin, tc.origin)
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+			for header, value := range tc.outHeaders {
+				if res.Header.Get(header) != value {
+					t.Errorf("was expecting %s to be %q, but returns %q", header, value, res.Header.Get(header))
+				}
+			}
+		})
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	testCases := []struct {
+		name       string
+		hstsMaxAge time.Duration
+		outHSTS    string
+	}{
+		{name: "when hsts is disabled", outHSTS: ""},
+		{name: "when hsts is enabled", hstsMaxAge: 24 * time.Hour, outHSTS: "max-age=86400; includeSubDomains"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Use(Headers(tc.hstsMaxAge))
+
+			// the headers are sent even when the route does not exist
+			res, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res.Header.Get(fiber.HeaderXContentTypeOptions) != "nosniff" ||
+				res.Header.Get(fiber.HeaderXFrameOptions) != "DENY" ||
+				res.Header.Get(fiber.HeaderContentSecurityPolicy) != ContentSecurityPolicy {
+				t.Errorf("was expecting the security headers, but returns %v", res.Header)
+			}
+			if hsts := res.Header.Get(fiber.HeaderStrictTransportSecurity); hsts != tc.outHSTS {
+				t.Errorf("was expecting %q, but returns %q", tc.outHSTS, hsts)
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	app := fiber.New()
+	app.Use(Limits(LimitsConfig{MaxBodySize: 64, MaxJSONDepth: 3, UploadPaths: []string{"/imports"}}))
+	app.Post("/", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusOK)
+	})
+	app.Post("/imports", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	var uploadBody bytes.Buffer
+	upload := multipart.NewWriter(&uploadBody)
+	upload.WriteField("file", strings.Repeat("[", 128))
+	upload.Close()
+
+	testCases := []struct {
+		name        string
+		path        string
+		contentType string
+		in          string
+		outStatus   int
+	}{
+		{name: "when body is empty", contentType: fiber.MIMEApplicationJSON, in: "", outStatus: http.StatusOK},
+		{name: "when body is within the limits", contentType: fiber.MIMEApplicationJSON, in: `{"a": [{"b": 1}]}`, outStatus: http.StatusOK},
+		{name: "when body is too large", contentType: fiber.MIMEApplicationJSON, in: `{"nome": "` + strings.Repeat("a", 64) + `"}`, outStatus: http.StatusRequestEntityTooLarge},
+		{name: "when body is too deep", contentType: fiber.MIMEApplicationJSON, in: `{"a": [{"b": [1]}]}`, outStatus: http.StatusBadRequest},
+		{name: "when brackets are inside strings", contentType: fiber.MIMEApplicationJSON, in: `{"a": "[[[{{{\"]]]"}`, outStatus: http.StatusOK},
+		{name: "when body is not declared as json", contentType: fiber.MIMETextPlain, in: `[[[[1]]]]`, outStatus: http.StatusBadRequest},
+		{name: "when body is an upload", path: "/imports", contentType: upload.FormDataContentType(), in: uploadBody.String(), outStatus: http.StatusOK},
+		{name: "when body is an upload to a path ending with a slash", path: "/IMPORTS/", contentType: upload.FormDataContentType(), in: uploadBody.String(), outStatus: http.StatusOK},
+		{name: "when body is an upload outside the upload paths", contentType: upload.FormDataContentType(), in: uploadBody.String(), outStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := tc.path
+			if path == "" {
+				path = "/"
+			}
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(tc.in))
+			req.Header.Set(fiber.HeaderContentType, tc.contentType)
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if res.StatusCode != tc.outStatus {
+				t.Errorf("was expecting %v, but returns %v", tc.outStatus, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestParseList(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   string
+		out  []string
+	}{
+		{name: "when empty", in: " ", out: nil},
+		{name: "when has spaces and empty items", in: "GET, POST,,PUT ", out: []string{"GET", "POST", "PUT"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := ParseList(tc.in); !reflect.DeepEqual(res, tc.out) {
+				t.Errorf("was expecting %v, but returns %v", tc.out, res)
+			}
+		})
+	}
+}
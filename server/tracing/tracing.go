@@ -0,0 +1,154 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/bgildson/unico-challenge/server"
+)
+
+const (
+	// ExporterNone disables the tracing
+	ExporterNone = "none"
+	// ExporterOTLP sends the spans to an OTLP/HTTP collector, configured by the OTEL_EXPORTER_OTLP_* envs
+	ExporterOTLP = "otlp"
+	// ExporterFile writes the spans as JSON to a local file, used for offline analysis
+	ExporterFile = "file"
+)
+
+// ErrUnknownExporter is used when the exporter is not supported
+var ErrUnknownExporter = errors.New("unknown tracing exporter")
+
+// Config contains the tracing settings
+type Config struct {
+	ServiceName string
+	Exporter    string
+	FilePath    string
+}
+
+// Setup installs the global tracer provider and propagator, the returned func flushes the
+// pending spans and must be called before the process exits
+func Setup(ctx context.Context, config Config) (shutdown func(context.Context) error, err error) {
+	var exporter sdktrace.SpanExporter
+	var file *os.File
+
+	switch strings.ToLower(config.Exporter) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not create the otlp exporter: %v", err)
+		}
+	case ExporterFile:
+		file, err = os.OpenFile(config.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("could not open the traces file: %v", err)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("could not create the file exporter: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownExporter, config.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(config.ServiceName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			file.Close()
+		}
+		return err
+	}, nil
+}
+
+// headerCarrier adapts the fasthttp request headers to the propagators
+type headerCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+func (c headerCarrier) Get(key string) string {
+	return string(c.header.Peek(key))
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c.header.Set(key, value)
+}
+
+func (c headerCarrier) Keys() []string {
+	var keys []string
+	c.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// Middleware starts a span for every request, continuing the trace informed by the caller headers,
+// the handlers receive it through ctx.UserContext()
+func Middleware() fiber.Handler {
+	tracer := otel.Tracer("github.com/bgildson/unico-challenge/server/tracing")
+
+	return func(ctx *fiber.Ctx) error {
+		own := ctx.Route()
+
+		parent := otel.GetTextMapPropagator().Extract(ctx.UserContext(), headerCarrier{&ctx.Request().Header})
+		spanCtx, span := tracer.Start(
+			parent,
+			ctx.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(ctx.Method()),
+				semconv.HTTPTargetKey.String(string(ctx.Request().RequestURI())),
+			),
+		)
+		defer span.End()
+		ctx.SetUserContext(spanCtx)
+
+		err := ctx.Next()
+
+		route := server.RouteOf(ctx, own)
+		status := server.StatusOf(ctx, err)
+		span.SetName(ctx.Method() + " " + route)
+		span.SetAttributes(
+			semconv.HTTPRouteKey.String(route),
+			semconv.HTTPStatusCodeKey.Int(status),
+		)
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("status %d", status))
+		}
+		if err != nil {
+			span.RecordError(err)
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/feiras-livres/:id", func(ctx *fiber.Ctx) error {
+		if !trace.SpanFromContext(ctx.UserContext()).SpanContext().IsValid() {
+			t.Errorf("was expecting a span in the user context")
+		}
+		return ctx.SendStatus(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/feiras-livres/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if _, err := app.Test(req); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("was expecting 1 span, but returns %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /feiras-livres/:id" {
+		t.Errorf("was expecting the span name %s, but returns %s", "GET /feiras-livres/:id", span.Name())
+	}
+	if traceID := span.SpanContext().TraceID().String(); traceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("was expecting the trace of the caller, but returns %s", traceID)
+	}
+	var status int64
+	for _, attr := range span.Attributes() {
+		if attr.Key == "http.status_code" {
+			status = attr.Value.AsInt64()
+		}
+	}
+	if status != http.StatusNotFound {
+		t.Errorf("was expecting the status %d, but returns %d", http.StatusNotFound, status)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	if _, err := Setup(context.Background(), Config{Exporter: "xml"}); !errors.Is(err, ErrUnknownExporter) {
+		t.Errorf("was expecting %v, but returns %v", ErrUnknownExporter, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := Setup(context.Background(), Config{ServiceName: "test", Exporter: ExporterFile, FilePath: path})
+	if err != nil {
+		t.Fatalf("was not expecting an error, but returns %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("was not expecting an error, but returns %v", err)
+	}
+
+	content, _ := ioutil.ReadFile(path)
+	if !strings.Contains(string(content), "test-span") {
+		t.Errorf("was expecting the span in the file, but returns %s", content)
+	}
+}
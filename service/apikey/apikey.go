@@ -0,0 +1,154 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/repository/apikey"
+)
+
+const (
+	// KeyPrefix starts every generated key, making them easy to find by secret scanners
+	KeyPrefix = "uc_"
+	// MaxNameLength is the longest name of a key, so its subject, apikey:<id>:<name>, fits the 255
+	// characters of the columns recording who did a change, as created_by
+	MaxNameLength = 255 - len("apikey:") - len("2147483647:")
+)
+
+var (
+	// ErrInvalidKey is used when the key does not exist, is malformed or was revoked
+	ErrInvalidKey = fmt.Errorf("invalid api key: %w", entity.ErrInvalidCredential)
+	// ErrInvalidScope is used when creating a key with an unknown scope
+	ErrInvalidScope = errors.New("invalid scope")
+	// ErrInvalidName is used when creating a key with an empty name or longer than MaxNameLength
+	ErrInvalidName = errors.New("invalid name")
+	// ErrKeyNotFound is used when revoking a key that does not exist or is already revoked
+	ErrKeyNotFound = errors.New("api key not found")
+)
+
+// Service represents how an apikey service should be implemented
+type Service interface {
+	Create(ctx context.Context, name string, scopes []string) (string, *entity.APIKey, error)
+	List(ctx context.Context) ([]entity.APIKey, error)
+	Revoke(ctx context.Context, id int) error
+	Authenticate(ctx context.Context, key string) (*entity.Principal, error)
+}
+
+type service struct {
+	repo apikey.Repository
+}
+
+// New creates a service for apikey
+func New(repo apikey.Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+// Create implements how to create an apikey, the returned key is not stored and could not be
+// recovered later
+func (s service) Create(ctx context.Context, name string, scopes []string) (string, *entity.APIKey, error) {
+	if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+		return "", nil, fmt.Errorf("%w: it must have from 1 to %d characters", ErrInvalidName, MaxNameLength)
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	for _, scope := range scopes {
+		if !validScope(scope) {
+			return "", nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	id, err := random(4)
+	if err != nil {
+		return "", nil, err
+	}
+	secret, err := random(32)
+	if err != nil {
+		return "", nil, err
+	}
+	// the prefix identifies the key in the listings without exposing it
+	prefix := hex.EncodeToString(id)
+	key := KeyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+
+	created, err := s.repo.Create(ctx, entity.APIKey{
+		Name:   name,
+		Prefix: prefix,
+		Scopes: scopes,
+	}, Hash(key))
+	if err != nil {
+		return "", nil, fmt.Errorf("could not create the api key: %v", err)
+	}
+
+	return key, created, nil
+}
+
+// List implements how to list every apikey
+func (s service) List(ctx context.Context) ([]entity.APIKey, error) {
+	return s.repo.List(ctx)
+}
+
+// Revoke implements how to revoke an apikey
+func (s service) Revoke(ctx context.Context, id int) error {
+	err := s.repo.Revoke(ctx, id)
+	if err == sql.ErrNoRows {
+		return ErrKeyNotFound
+	}
+	return err
+}
+
+// Authenticate implements how to find who owns the key
+func (s service) Authenticate(ctx context.Context, key string) (*entity.Principal, error) {
+	if !strings.HasPrefix(key, KeyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	k, err := s.repo.GetByHash(ctx, Hash(key))
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get the api key: %v", err)
+	}
+	if k.Revoked() {
+		return nil, ErrInvalidKey
+	}
+
+	return &entity.Principal{
+		Subject: fmt.Sprintf("apikey:%d:%s", k.ID, k.Name),
+		Scopes:  k.Scopes,
+	}, nil
+}
+
+// Hash returns the stored form of the key, the keys are random enough to not need a slow hash
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func random(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("could not generate the api key: %v", err)
+	}
+	return b, nil
+}
+
+func validScope(scope string) bool {
+	for _, s := range entity.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,216 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/repository/apikey"
+)
+
+func TestServiceCreate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setupMocks func(repo *apikey.MockRepository)
+		inName     string
+		in         []string
+		outErr     error
+		hasError   bool
+	}{
+		{
+			name:       "when no name is informed",
+			setupMocks: func(repo *apikey.MockRepository) {},
+			in:         []string{entity.ScopeRead},
+			outErr:     ErrInvalidName,
+		},
+		{
+			name:       "when the name does not fit the subject",
+			setupMocks: func(repo *apikey.MockRepository) {},
+			inName:     strings.Repeat("á", MaxNameLength+1),
+			in:         []string{entity.ScopeRead},
+			outErr:     ErrInvalidName,
+		},
+		{
+			name:       "when no scope is informed",
+			setupMocks: func(repo *apikey.MockRepository) {},
+			inName:     "ci",
+			outErr:     ErrInvalidScope,
+		},
+		{
+			name:       "when the scope is unknown",
+			setupMocks: func(repo *apikey.MockRepository) {},
+			inName:     "ci",
+			in:         []string{entity.ScopeRead, "delete"},
+			outErr:     ErrInvalidScope,
+		},
+		{
+			name: "when repository returns an error",
+			setupMocks: func(repo *apikey.MockRepository) {
+				repo.EXPECT().Create(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("unexpected error"))
+			},
+			inName:   "ci",
+			in:       []string{entity.ScopeRead},
+			hasError: true,
+		},
+		{
+			name: "when success",
+			setupMocks: func(repo *apikey.MockRepository) {
+				repo.
+					EXPECT().
+					Create(gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, key entity.APIKey, hash string) (*entity.APIKey, error) {
+						key.ID = 1
+						return &key, nil
+					})
+			},
+			inName: "ci",
+			in:     []string{entity.ScopeRead, entity.ScopeWrite},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := apikey.NewMockRepository(ctrl)
+			tc.setupMocks(repo)
+			s := New(repo)
+
+			key, created, err := s.Create(context.Background(), tc.inName, tc.in)
+			if tc.outErr != nil || tc.hasError {
+				if err == nil || (tc.outErr != nil && !errors.Is(err, tc.outErr)) {
+					t.Errorf("was expecting %v, but returns %v", tc.outErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("was not expecting an error, but returns %v", err)
+			}
+
+			if !regexp.MustCompile(`^uc_[0-9a-f]{8}_[A-Za-z0-9_-]{43}$`).MatchString(key) {
+				t.Errorf("was expecting a generated key, but returns %s", key)
+			}
+			if key[3:11] != created.Prefix {
+				t.Errorf("was expecting the prefix %s, but returns %s", key[3:11], created.Prefix)
+			}
+			if !reflect.DeepEqual(created.Scopes, tc.in) {
+				t.Errorf("was expecting %v, but returns %v", tc.in, created.Scopes)
+			}
+		})
+	}
+}
+
+func TestServiceRevoke(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setupMocks func(repo *apikey.MockRepository)
+		outErr     error
+	}{
+		{
+			name: "when the key does not exist",
+			setupMocks: func(repo *apikey.MockRepository) {
+				repo.EXPECT().Revoke(gomock.Any(), 1).Return(sql.ErrNoRows)
+			},
+			outErr: ErrKeyNotFound,
+		},
+		{
+			name: "when success",
+			setupMocks: func(repo *apikey.MockRepository) {
+				repo.EXPECT().Revoke(gomock.Any(), 1).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := apikey.NewMockRepository(ctrl)
+			tc.setupMocks(repo)
+
+			if err := New(repo).Revoke(context.Background(), 1); err != tc.outErr {
+				t.Errorf("was expecting %v, but returns %v", tc.outErr, err)
+			}
+		})
+	}
+}
+
+func TestServiceAuthenticate(t *testing.T) {
+	now := time.Now()
+	key := "uc_abcd1234_secret"
+	testCases := []struct {
+		name       string
+		setupMocks func(repo *apikey.MockRepository)
+		in         string
+		out        *entity.Principal
+		outErr     error
+		hasError   bool
+	}{
+		{
+			name:       "when the key is malformed",
+			setupMocks: func(repo *apikey.MockRepository) {},
+			in:         "secret",
+			outErr:     ErrInvalidKey,
+		},
+		{
+			name: "when the key does not exist",
+			setupMocks: func(repo *apikey.MockRepository) {
+				repo.EXPECT().GetByHash(gomock.Any(), Hash(key)).Return(nil, sql.ErrNoRows)
+			},
+			in:     key,
+			outErr: ErrInvalidKey,
+		},
+		{
+			name: "when the key was revoked",
+			setupMocks: func(repo *apikey.MockRepository) {
+				repo.EXPECT().GetByHash(gomock.Any(), Hash(key)).Return(&entity.APIKey{ID: 1, RevokedAt: &now}, nil)
+			},
+			in:     key,
+			outErr: ErrInvalidKey,
+		},
+		{
+			name: "when repository returns an error",
+			setupMocks: func(repo *apikey.MockRepository) {
+				repo.EXPECT().GetByHash(gomock.Any(), Hash(key)).Return(nil, errors.New("unexpected error"))
+			},
+			in:       key,
+			hasError: true,
+		},
+		{
+			name: "when success",
+			setupMocks: func(repo *apikey.MockRepository) {
+				repo.EXPECT().GetByHash(gomock.Any(), Hash(key)).Return(&entity.APIKey{ID: 1, Name: "ci", Scopes: []string{entity.ScopeRead}}, nil)
+			},
+			in:  key,
+			out: &entity.Principal{Subject: "apikey:1:ci", Scopes: []string{entity.ScopeRead}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := apikey.NewMockRepository(ctrl)
+			tc.setupMocks(repo)
+
+			res, err := New(repo).Authenticate(context.Background(), tc.in)
+			if tc.hasError {
+				if err == nil {
+					t.Errorf("was expecting an error, but returns nil")
+				}
+				return
+			}
+			if err != tc.outErr {
+				t.Errorf("was expecting %v, but returns %v", tc.outErr, err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
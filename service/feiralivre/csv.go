@@ -0,0 +1,105 @@
+package feiralivre
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+type csvReader struct{}
+
+func parseColsToFeiraLivre(cols []string) (*entity.FeiraLivre, error) {
+	if len(cols) < 17 {
+		return nil, fmt.Errorf("the number of cols must be 17 or more, was received %d", len(cols))
+	}
+
+	id, err := strconv.Atoi(cols[0])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse ID column: %v", err)
+	}
+
+	long, err := strconv.ParseFloat(cols[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse LONG column: %v", err)
+	}
+
+	lat, err := strconv.ParseFloat(cols[2], 64)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse LAT column: %v", err)
+	}
+
+	setcens, err := strconv.Atoi(cols[3])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse SETCENS column: %v", err)
+	}
+
+	areap, err := strconv.Atoi(cols[4])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse AREAP column: %v", err)
+	}
+
+	coddist, err := strconv.Atoi(cols[5])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse CODDIST column: %v", err)
+	}
+
+	codsubpref, err := strconv.Atoi(cols[7])
+	if err != nil {
+		return nil, fmt.Errorf("could not parse CODSUBPREF column: %v", err)
+	}
+
+	return &entity.FeiraLivre{
+		ID:                  id,
+		Latitude:            lat,
+		Longitude:           long,
+		SetorCensitario:     setcens,
+		AreaPonderacao:      areap,
+		CodigoDistrito:      coddist,
+		Distrito:            cols[6],
+		CodigoSubprefeitura: codsubpref,
+		Subprefeitura:       cols[8],
+		Regiao5:             cols[9],
+		Regiao8:             cols[10],
+		NomeFeira:           cols[11],
+		Registro:            cols[12],
+		Logradouro:          cols[13],
+		Numero:              cols[14],
+		Bairro:              cols[15],
+		Referencia:          cols[16],
+	}, nil
+}
+
+// Read implements how to read a csv in the DEINFO layout
+func (csvReader) Read(r io.Reader, flChan chan<- *entity.FeiraLivre, errChan chan<- error) {
+	reader := csv.NewReader(r)
+
+	// skip header
+	if _, err := reader.Read(); err != nil {
+		errChan <- fmt.Errorf("could not read csv header: %v", err)
+		return
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errChan <- fmt.Errorf("could not read csv row: %v", err)
+			// only a parse error allows to keep reading the next rows
+			if _, ok := err.(*csv.ParseError); !ok {
+				return
+			}
+			continue
+		}
+		fl, err := parseColsToFeiraLivre(row)
+		if err != nil {
+			errChan <- fmt.Errorf("could not parse row to feiralivre: %v", err)
+			continue
+		}
+		flChan <- fl
+	}
+}
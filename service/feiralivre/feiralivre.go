@@ -1,181 +1,351 @@
 package feiralivre
 
 import (
-	"encoding/csv"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"io"
-	"strconv"
+	"os"
 	"sync"
-	"sync/atomic"
+	"time"
 
 	"github.com/spf13/afero"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/bgildson/unico-challenge/entity"
 	"github.com/bgildson/unico-challenge/repository/feiralivre"
 )
 
+var tracer = otel.Tracer("github.com/bgildson/unico-challenge/service/feiralivre")
+
+// rowsAttributes represents the rows counted until there as span attributes
+func rowsAttributes(p Progress) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.Int64("import.rows_read", p.Read),
+		attribute.Int64("import.rows_persisted", p.Persisted),
+		attribute.Int64("import.rows_failed", p.Failed),
+	}
+}
+
+// endSpan records the attributes and the error of an import or export stage
+func endSpan(span trace.Span, err error, attrs ...attribute.KeyValue) {
+	span.SetAttributes(attrs...)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// ErrCouldNotSyncPK is used when the registers were imported, but the feira_livre table pk could not be synced
+var ErrCouldNotSyncPK = errors.New("could not sync feira_livre table pk")
+
 // Service represents how a feiralivre service should be implemented
 type Service interface {
-	Import(path string) (message string, err error)
+	Import(ctx context.Context, path, format string) (message string, err error)
+	ImportWithProgress(ctx context.Context, path, format string, areas entity.Areas, actor string, progress ProgressFunc) (Progress, error)
+	Export(ctx context.Context, w io.Writer, format string, qp feiralivre.QueryParams) (count int64, err error)
 }
 
 type service struct {
-	fs   afero.Fs
-	repo feiralivre.Repository
+	fs    afero.Fs
+	stdin io.Reader
+	repo  feiralivre.Repository
+	opts  Options
 }
 
 // New creates a service for feiralivre
-func New(fs afero.Fs, repo feiralivre.Repository) Service {
+func New(fs afero.Fs, repo feiralivre.Repository, opts Options) Service {
 	return &service{
-		fs:   fs,
-		repo: repo,
+		fs:    fs,
+		stdin: os.Stdin,
+		repo:  repo,
+		opts:  opts.normalize(),
 	}
 }
 
-func (s service) parseColsToFeiraLivre(cols []string) (*entity.FeiraLivre, error) {
-	if len(cols) < 17 {
-		return nil, fmt.Errorf("the number of cols must be 17 or more, was received %d", len(cols))
-	}
+func (s service) read(r io.Reader, reader SourceReader, flChan chan<- *entity.FeiraLivre, errChan chan<- error) {
+	defer close(flChan)
+	defer close(errChan)
 
-	id, err := strconv.Atoi(cols[0])
-	if err != nil {
-		return nil, fmt.Errorf("could not parse ID column: %v", err)
-	}
+	reader.Read(r, flChan, errChan)
+}
 
-	long, err := strconv.ParseFloat(cols[1], 64)
-	if err != nil {
-		return nil, fmt.Errorf("could not parse LONG column: %v", err)
+// throttle limits the registers sent to the workers to MaxRowsPerSecond
+func (s service) throttle(flChan <-chan *entity.FeiraLivre) <-chan *entity.FeiraLivre {
+	if s.opts.MaxRowsPerSecond == 0 {
+		return flChan
 	}
 
-	lat, err := strconv.ParseFloat(cols[2], 64)
-	if err != nil {
-		return nil, fmt.Errorf("could not parse LAT column: %v", err)
+	interval := time.Second / time.Duration(s.opts.MaxRowsPerSecond)
+	if interval <= 0 {
+		return flChan
 	}
 
-	setcens, err := strconv.Atoi(cols[3])
-	if err != nil {
-		return nil, fmt.Errorf("could not parse SETCENS column: %v", err)
-	}
+	throttled := make(chan *entity.FeiraLivre)
+	go func() {
+		defer close(throttled)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for fl := range flChan {
+			<-ticker.C
+			throttled <- fl
+		}
+	}()
 
-	areap, err := strconv.Atoi(cols[4])
-	if err != nil {
-		return nil, fmt.Errorf("could not parse AREAP column: %v", err)
-	}
+	return throttled
+}
 
-	coddist, err := strconv.Atoi(cols[5])
-	if err != nil {
-		return nil, fmt.Errorf("could not parse CODDIST column: %v", err)
+// allowed checks if the register and the stored feiralivre it replaces are in the areas
+func (s service) allowed(ctx context.Context, areas entity.Areas, fl entity.FeiraLivre) error {
+	if areas.All {
+		return nil
+	}
+	if !areas.Covers(fl) {
+		return fmt.Errorf("feiralivre %d is outside the areas granted", fl.ID)
+	}
+	stored, err := s.repo.GetByID(ctx, fl.ID)
+	if err == sql.ErrNoRows {
+		return nil
 	}
-
-	codsubpref, err := strconv.Atoi(cols[7])
 	if err != nil {
-		return nil, fmt.Errorf("could not parse CODSUBPREF column: %v", err)
-	}
-
-	return &entity.FeiraLivre{
-		ID:                  id,
-		Latitude:            lat,
-		Longitude:           long,
-		SetorCensitario:     setcens,
-		AreaPonderacao:      areap,
-		CodigoDistrito:      coddist,
-		Distrito:            cols[6],
-		CodigoSubprefeitura: codsubpref,
-		Subprefeitura:       cols[8],
-		Regiao5:             cols[9],
-		Regiao8:             cols[10],
-		NomeFeira:           cols[11],
-		Registro:            cols[12],
-		Logradouro:          cols[13],
-		Numero:              cols[14],
-		Bairro:              cols[15],
-		Referencia:          cols[16],
-	}, nil
+		return fmt.Errorf("could not check the area of feiralivre %d: %v", fl.ID, err)
+	}
+	if !areas.Covers(*stored) {
+		return fmt.Errorf("feiralivre %d replaces a register outside the areas granted", fl.ID)
+	}
+	return nil
 }
 
-func (s service) readFile(path string, flChan chan<- *entity.FeiraLivre, errChan chan<- error) {
-	defer close(flChan)
-	defer close(errChan)
-
-	f, err := s.fs.Open(path)
-	if err != nil {
-		errChan <- fmt.Errorf("could not open csv file: %v", err)
+// persist saves the registers received in the areas, grouping them when BatchSize is greater than one
+func (s service) persist(ctx context.Context, areas entity.Areas, flChan <-chan *entity.FeiraLivre, c *counters) {
+	if s.opts.BatchSize == 1 {
+		for fl := range flChan {
+			if err := s.allowed(ctx, areas, *fl); err != nil {
+				c.fail(1, err)
+				continue
+			}
+			if _, err := s.repo.CreateOrUpdate(ctx, *fl); err != nil {
+				c.fail(1, fmt.Errorf("could not persist feiralivre %d: %v", fl.ID, err))
+				continue
+			}
+			c.addPersisted(1)
+		}
 		return
 	}
-	defer f.Close()
-
-	reader := csv.NewReader(f)
 
-	// skip header
-	if _, err := reader.Read(); err != nil {
-		errChan <- fmt.Errorf("could not read csv header: %v", err)
-		return
+	batch := make([]entity.FeiraLivre, 0, s.opts.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		// the batch runs in a transaction, so when it fails none of the registers were persisted
+		if err := s.repo.CreateOrUpdateBatch(ctx, batch); err != nil {
+			c.fail(int64(len(batch)), fmt.Errorf("could not persist a batch of %d feiralivre: %v", len(batch), err))
+		} else {
+			c.addPersisted(int64(len(batch)))
+		}
+		batch = batch[:0]
 	}
 
-	for {
-		row, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			errChan <- fmt.Errorf("could not read csv row: %v", err)
+	for fl := range flChan {
+		if err := s.allowed(ctx, areas, *fl); err != nil {
+			c.fail(1, err)
 			continue
 		}
-		fl, err := s.parseColsToFeiraLivre(row)
-		if err != nil {
-			errChan <- fmt.Errorf("could not parse row to feiralivre: %v", err)
-			continue
+		batch = append(batch, *fl)
+		if len(batch) == s.opts.BatchSize {
+			flush()
 		}
-		flChan <- fl
 	}
+	flush()
+}
+
+// Import implements the import operation, path could be StdinPath and gzip, bzip2 and zip files are
+// decompressed, when format is empty it is detected by the file extension (csv for the stdin), when
+// ctx is canceled the message reports what was imported until there together with the ctx error
+func (s service) Import(ctx context.Context, path, format string) (string, error) {
+	p, err := s.ImportWithProgress(ctx, path, format, entity.AllAreas, "", s.opts.Progress)
+	if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+		return fmt.Sprintf(
+			"Import canceled! Read %d registers, %d imported and %d errors.\n",
+			p.Read,
+			p.Persisted,
+			p.Failed,
+		), err
+	}
+	if err != nil && !errors.Is(err, ErrCouldNotSyncPK) {
+		return "", err
+	}
+
+	var extraErr string
+	if err != nil {
+		extraErr = err.Error() + "\n"
+	}
+
+	return fmt.Sprintf(
+		"Import finished! Read %d registers, %d imported and %d errors.\n%s",
+		p.Read,
+		p.Persisted,
+		p.Failed,
+		extraErr,
+	), nil
 }
 
-// Import implements the csv import operation
-func (s service) Import(path string) (string, error) {
+// ImportWithProgress implements the import operation sending the progress events to progress,
+// the registers outside the areas, or replacing a register outside them, fail, the registers are
+// recorded as created or updated by actor (nobody when empty), when ctx is canceled the import
+// stops and returns the progress until there
+func (s service) ImportWithProgress(ctx context.Context, path, format string, areas entity.Areas, actor string, progress ProgressFunc) (p Progress, err error) {
+	ctx, span := tracer.Start(ctx, "feiralivre.Import", trace.WithAttributes(
+		attribute.String("import.path", path),
+		attribute.String("import.format", format),
+	))
+	defer func() { endSpan(span, err, rowsAttributes(p)...) }()
+
+	c := &counters{start: time.Now(), rows: s.opts.Rows}
+
+	_, openSpan := tracer.Start(ctx, "feiralivre.Import.open")
+	src, reader, err := s.open(path, format, c)
+	endSpan(openSpan, err)
+	if err != nil {
+		return Progress{}, err
+	}
+	defer src.Close()
+
+	stopProgress := s.reportProgress(c, progress)
+
 	// read
-	flChan := make(chan *entity.FeiraLivre)
+	_, readSpan := tracer.Start(ctx, "feiralivre.Import.read")
+	readChan := make(chan *entity.FeiraLivre)
 	readErrChan := make(chan error)
-	go s.readFile(path, flChan, readErrChan)
+	go s.read(contextReader{ctx: ctx, r: src}, reader, readChan, readErrChan)
 
-	// count read errors
-	var readErrCount int64
+	// count the rows read and the read errors, after a cancellation the remaining
+	// rows are discarded until the reader stops
+	flChan := make(chan *entity.FeiraLivre)
 	go func() {
-		for range readErrChan {
-			atomic.AddInt64(&readErrCount, 1)
+		defer close(flChan)
+		defer func() { endSpan(readSpan, nil, rowsAttributes(c.progress(false))...) }()
+		for readChan != nil || readErrChan != nil {
+			select {
+			case fl, ok := <-readChan:
+				if !ok {
+					readChan = nil
+					continue
+				}
+				if ctx.Err() != nil {
+					continue
+				}
+				c.addRead(1)
+				fl.CreatedBy, fl.UpdatedBy = actor, actor
+				flChan <- fl
+			case err, ok := <-readErrChan:
+				if !ok {
+					readErrChan = nil
+					continue
+				}
+				if ctx.Err() != nil {
+					continue
+				}
+				c.addRead(1)
+				c.fail(1, err)
+			}
 		}
 	}()
 
-	// import (worker pool)
-	poolSize := 8
+	// import (worker pool), the registers already read are persisted even after a cancellation,
+	// so the repository receives only the trace of ctx
+	_, persistSpan := tracer.Start(ctx, "feiralivre.Import.persist", trace.WithAttributes(
+		attribute.Int("import.workers", s.opts.Workers),
+		attribute.Int("import.batch_size", s.opts.BatchSize),
+	))
+	persistCtx := trace.ContextWithSpan(context.Background(), persistSpan)
+	rowsChan := s.throttle(flChan)
 	wg := sync.WaitGroup{}
-	wg.Add(poolSize)
-	var flCount int64
-	var importErrCount int64
-	for i := 0; i < poolSize; i++ {
+	wg.Add(s.opts.Workers)
+	for i := 0; i < s.opts.Workers; i++ {
 		go func() {
 			defer wg.Done()
-			for fl := range flChan {
-				atomic.AddInt64(&flCount, 1)
-				if _, err := s.repo.CreateOrUpdate(*fl); err != nil {
-					atomic.AddInt64(&importErrCount, 1)
-				}
-			}
+			s.persist(persistCtx, areas, rowsChan, c)
 		}()
 	}
 
 	wg.Wait()
+	stopProgress()
+	endSpan(persistSpan, nil, rowsAttributes(c.progress(false))...)
 
 	// sync feira_livre table pk
-	var extraErr string
-	if err := s.repo.SyncPK(); err != nil {
-		extraErr = fmt.Sprintf("could not sync feira_livre table pk: %v\n", err)
+	if err := s.repo.SyncPK(trace.ContextWithSpan(context.Background(), span)); err != nil {
+		return c.progress(true), fmt.Errorf("%w: %v", ErrCouldNotSyncPK, err)
 	}
 
-	return fmt.Sprintf(
-		"Import finished! Read %d registers, %d imported and %d errors.\n%s",
-		flCount+atomic.LoadInt64(&readErrCount),
-		flCount-atomic.LoadInt64(&importErrCount),
-		atomic.LoadInt64(&readErrCount)+atomic.LoadInt64(&importErrCount),
-		extraErr,
-	), nil
+	return c.progress(true), ctx.Err()
+}
+
+// open opens the source and creates its reader, when format is empty it is detected
+// by the file extension (csv for the stdin)
+func (s service) open(path, format string, c *counters) (*source, SourceReader, error) {
+	src, err := s.openSource(path, format, &c.bytesRead)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.totalBytes = src.size
+
+	if format == "" {
+		format = FormatCSV
+		if src.name != "" {
+			detected, err := FormatFromPath(src.name)
+			if err != nil {
+				src.Close()
+				return nil, nil, fmt.Errorf("could not detect the format of %s: %v", src.name, err)
+			}
+			format = detected
+		}
+	}
+
+	reader, err := NewSourceReader(format)
+	if err != nil {
+		src.Close()
+		return nil, nil, fmt.Errorf("could not create a reader for %s: %v", format, err)
+	}
+
+	return src, reader, nil
+}
+
+// Export implements the export operation, writing every feiralivre matching qp to w
+func (s service) Export(ctx context.Context, w io.Writer, format string, qp feiralivre.QueryParams) (count int64, err error) {
+	ctx, span := tracer.Start(ctx, "feiralivre.Export", trace.WithAttributes(
+		attribute.String("export.format", format),
+	))
+	defer func() { endSpan(span, err, attribute.Int64("export.rows", count)) }()
+
+	writer, err := NewSinkWriter(format, w)
+	if err != nil {
+		return 0, fmt.Errorf("could not create a writer for %s: %v", format, err)
+	}
+
+	err = s.repo.ForEach(ctx, qp, func(fl entity.FeiraLivre) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := writer.Write(fl); err != nil {
+			return fmt.Errorf("could not write feiralivre %d: %v", fl.ID, err)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return count, fmt.Errorf("could not finish the %s content: %v", format, err)
+	}
+
+	return count, nil
 }
@@ -1,8 +1,12 @@
 package feiralivre
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -12,7 +16,7 @@ import (
 	"github.com/bgildson/unico-challenge/repository/feiralivre"
 )
 
-func TestServiceParseColsToFeiraLivre(t *testing.T) {
+func TestParseColsToFeiraLivre(t *testing.T) {
 	testCases := []struct {
 		name     string
 		in       []string
@@ -87,8 +91,7 @@ func TestServiceParseColsToFeiraLivre(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			s := service{}
-			res, err := s.parseColsToFeiraLivre(tc.in)
+			res, err := parseColsToFeiraLivre(tc.in)
 			if tc.hasError && err == nil {
 				t.Error("was expecting an error, but returns nil")
 			}
@@ -102,7 +105,7 @@ func TestServiceParseColsToFeiraLivre(t *testing.T) {
 	}
 }
 
-func TestServiceReadFile(t *testing.T) {
+func TestServiceRead(t *testing.T) {
 	headersLine := "ID,LONG,LAT,SETCENS,AREAP,CODDIST,DISTRITO,CODSUBPREF,SUBPREF,REGIAO5,REGIAO8,NOME_FEIRA,REGISTRO,LOGRADOURO,NUMERO,BAIRRO,REFERENCIA"
 	bodyLine := "1,-46548146,-23568390,355030885000019,3550308005040,87,VILA FORMOSA,26,ARICANDUVA,Leste,Leste 1,PRAÇA LEÃO X,7216-8,RUA CODAJÁS,45,VILA FORMOSA,PRAÇA MARECHAL LEITE BANDEIRA"
 	fl := entity.FeiraLivre{
@@ -125,58 +128,41 @@ func TestServiceReadFile(t *testing.T) {
 		Referencia:          "PRAÇA MARECHAL LEITE BANDEIRA",
 	}
 	testCases := []struct {
-		name          string
-		inPathToWrite string
-		inPathToRead  string
-		inContent     string
-		outFls        []entity.FeiraLivre
-		outErr        []error
+		name      string
+		inContent string
+		outFls    []entity.FeiraLivre
+		outErr    []error
 	}{
 		{
-			name:         "when the file does not exists",
-			inPathToRead: "/does-not-exists.csv",
-			outErr:       []error{errors.New("file does not exists")},
-		},
-		{
-			name:          "when the file contains an invalid content in the header",
-			inPathToWrite: "/my.csv",
-			inPathToRead:  "/my.csv",
-			inContent:     `"`,
-			outErr:        []error{errors.New("invalid content in the header")},
+			name:      "when the file contains an invalid content in the header",
+			inContent: `"`,
+			outErr:    []error{errors.New("invalid content in the header")},
 		},
 		{
-			name:          "when the file contains an invalid content in the body",
-			inPathToWrite: "/my.csv",
-			inPathToRead:  "/my.csv",
-			inContent:     headersLine + "\n" + `"`,
-			outErr:        []error{errors.New("invalid content in the body")},
+			name:      "when the file contains an invalid content in the body",
+			inContent: headersLine + "\n" + `"`,
+			outErr:    []error{errors.New("invalid content in the body")},
 		},
 		{
-			name:          "when can not parse the row",
-			inPathToWrite: "/my.csv",
-			inPathToRead:  "/my.csv",
-			inContent:     headersLine + "\n,,,,,,,,,,,,,,,,\n",
-			outErr:        []error{errors.New("can not parse the row")},
+			name:      "when can not parse the row",
+			inContent: headersLine + "\n,,,,,,,,,,,,,,,,\n",
+			outErr:    []error{errors.New("can not parse the row")},
 		},
 		{
-			name:          "when success",
-			inPathToWrite: "/my.csv",
-			inPathToRead:  "/my.csv",
-			inContent:     headersLine + "\n" + bodyLine,
-			outFls:        []entity.FeiraLivre{fl},
+			name:      "when success",
+			inContent: headersLine + "\n" + bodyLine,
+			outFls:    []entity.FeiraLivre{fl},
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			fs := afero.NewMemMapFs()
-			afero.WriteFile(fs, tc.inPathToWrite, []byte(tc.inContent), 0644)
-			s := service{fs: fs}
+			s := service{}
 
 			flChan := make(chan *entity.FeiraLivre, 1)
 			errChan := make(chan error, 1)
 
-			go s.readFile(tc.inPathToRead, flChan, errChan)
+			go s.read(strings.NewReader(tc.inContent), csvReader{}, flChan, errChan)
 
 			var fls []entity.FeiraLivre
 			for fl := range flChan {
@@ -223,15 +209,53 @@ func TestServiceImport(t *testing.T) {
 		name       string
 		setupMocks func(fs afero.Fs, a *feiralivre.MockRepository)
 		in         string
+		inFormat   string
 		out        string
+		hasError   bool
 	}{
+		{
+			name:       "when the file does not exists",
+			setupMocks: func(fs afero.Fs, repo *feiralivre.MockRepository) {},
+			in:         "/does-not-exists.csv",
+			hasError:   true,
+		},
+		{
+			name:       "when the format can not be detected",
+			setupMocks: func(fs afero.Fs, repo *feiralivre.MockRepository) {},
+			in:         "/my.txt",
+			hasError:   true,
+		},
+		{
+			name:       "when the format is unknown",
+			setupMocks: func(fs afero.Fs, repo *feiralivre.MockRepository) {},
+			in:         "/my.csv",
+			inFormat:   "xml",
+			hasError:   true,
+		},
+		{
+			name: "when the format is informed",
+			setupMocks: func(fs afero.Fs, repo *feiralivre.MockRepository) {
+				afero.WriteFile(fs, "/my.txt", []byte(headersLine+"\n"+bodyLine), 0644)
+				repo.
+					EXPECT().
+					CreateOrUpdate(gomock.Any(), fl).
+					Return(&fl, nil)
+				repo.
+					EXPECT().
+					SyncPK(gomock.Any()).
+					Return(nil)
+			},
+			in:       "/my.txt",
+			inFormat: FormatCSV,
+			out:      "Import finished! Read 1 registers, 1 imported and 0 errors.\n",
+		},
 		{
 			name: "when the file is empty",
 			setupMocks: func(fs afero.Fs, repo *feiralivre.MockRepository) {
 				afero.WriteFile(fs, "/my.csv", []byte(headersLine), 0644)
 				repo.
 					EXPECT().
-					SyncPK().
+					SyncPK(gomock.Any()).
 					Return(nil)
 			},
 			in:  "/my.csv",
@@ -243,7 +267,7 @@ func TestServiceImport(t *testing.T) {
 				afero.WriteFile(fs, "/my.csv", []byte(headersLine+"\n,,,,,,,,,,,,,,,,"), 0644)
 				repo.
 					EXPECT().
-					SyncPK().
+					SyncPK(gomock.Any()).
 					Return(nil)
 			},
 			in:  "/my.csv",
@@ -255,11 +279,11 @@ func TestServiceImport(t *testing.T) {
 				afero.WriteFile(fs, "/my.csv", []byte(headersLine+"\n"+bodyLine), 0644)
 				repo.
 					EXPECT().
-					CreateOrUpdate(fl).
+					CreateOrUpdate(gomock.Any(), fl).
 					Return(nil, errors.New("unexpected error"))
 				repo.
 					EXPECT().
-					SyncPK().
+					SyncPK(gomock.Any()).
 					Return(nil)
 			},
 			in:  "/my.csv",
@@ -271,11 +295,11 @@ func TestServiceImport(t *testing.T) {
 				afero.WriteFile(fs, "/my.csv", []byte(headersLine+"\n"+bodyLine), 0644)
 				repo.
 					EXPECT().
-					CreateOrUpdate(fl).
+					CreateOrUpdate(gomock.Any(), fl).
 					Return(&fl, nil)
 				repo.
 					EXPECT().
-					SyncPK().
+					SyncPK(gomock.Any()).
 					Return(nil)
 			},
 			in:  "/my.csv",
@@ -287,11 +311,11 @@ func TestServiceImport(t *testing.T) {
 				afero.WriteFile(fs, "/my.csv", []byte(headersLine+"\n"+bodyLine), 0644)
 				repo.
 					EXPECT().
-					CreateOrUpdate(fl).
+					CreateOrUpdate(gomock.Any(), fl).
 					Return(&fl, nil)
 				repo.
 					EXPECT().
-					SyncPK().
+					SyncPK(gomock.Any()).
 					Return(errors.New("unexpected error"))
 			},
 			in:  "/my.csv",
@@ -303,11 +327,11 @@ func TestServiceImport(t *testing.T) {
 				afero.WriteFile(fs, "/my.csv", []byte(headersLine+"\n"+bodyLine+"\n,,,,,,,,,,,,,,,,"), 0644)
 				repo.
 					EXPECT().
-					CreateOrUpdate(fl).
+					CreateOrUpdate(gomock.Any(), fl).
 					Return(&fl, nil)
 				repo.
 					EXPECT().
-					SyncPK().
+					SyncPK(gomock.Any()).
 					Return(nil)
 			},
 			in:  "/my.csv",
@@ -319,11 +343,11 @@ func TestServiceImport(t *testing.T) {
 				afero.WriteFile(fs, "/my.csv", []byte(headersLine+"\n,,,,,,,,,,,,,,,,\n"+bodyLine), 0644)
 				repo.
 					EXPECT().
-					CreateOrUpdate(fl).
+					CreateOrUpdate(gomock.Any(), fl).
 					Return(&fl, nil)
 				repo.
 					EXPECT().
-					SyncPK().
+					SyncPK(gomock.Any()).
 					Return(nil)
 			},
 			in:  "/my.csv",
@@ -337,9 +361,118 @@ func TestServiceImport(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			repo := feiralivre.NewMockRepository(ctrl)
 			tc.setupMocks(fs, repo)
-			svc := New(fs, repo)
+			svc := New(fs, repo, DefaultOptions())
 
-			msg, err := svc.Import(tc.in)
+			msg, err := svc.Import(context.Background(), tc.in, tc.inFormat)
+
+			if msg != tc.out {
+				t.Errorf("was expecting:\n%s\nbut returns:\n%s\n", tc.out, msg)
+			}
+			if tc.hasError && err == nil {
+				t.Error("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was expecting an empty error, but returns: %v", err)
+			}
+		})
+	}
+}
+
+func TestServiceImportWithOptions(t *testing.T) {
+	headersLine := "ID,LONG,LAT,SETCENS,AREAP,CODDIST,DISTRITO,CODSUBPREF,SUBPREF,REGIAO5,REGIAO8,NOME_FEIRA,REGISTRO,LOGRADOURO,NUMERO,BAIRRO,REFERENCIA"
+	bodyLine := "1,-46548146,-23568390,355030885000019,3550308005040,87,VILA FORMOSA,26,ARICANDUVA,Leste,Leste 1,PRAÇA LEÃO X,7216-8,RUA CODAJÁS,45,VILA FORMOSA,PRAÇA MARECHAL LEITE BANDEIRA"
+	content := headersLine + "\n" + bodyLine + "\n" + bodyLine + "\n" + bodyLine
+	fl := entity.FeiraLivre{
+		ID:                  1,
+		Latitude:            -23568390,
+		Longitude:           -46548146,
+		SetorCensitario:     355030885000019,
+		AreaPonderacao:      3550308005040,
+		CodigoDistrito:      87,
+		Distrito:            "VILA FORMOSA",
+		CodigoSubprefeitura: 26,
+		Subprefeitura:       "ARICANDUVA",
+		Regiao5:             "Leste",
+		Regiao8:             "Leste 1",
+		NomeFeira:           "PRAÇA LEÃO X",
+		Registro:            "7216-8",
+		Logradouro:          "RUA CODAJÁS",
+		Numero:              "45",
+		Bairro:              "VILA FORMOSA",
+		Referencia:          "PRAÇA MARECHAL LEITE BANDEIRA",
+	}
+	testCases := []struct {
+		name       string
+		setupMocks func(repo *feiralivre.MockRepository)
+		inOpts     Options
+		out        string
+	}{
+		{
+			name: "when persisting in batches",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.
+					EXPECT().
+					CreateOrUpdateBatch(gomock.Any(), []entity.FeiraLivre{fl, fl}).
+					Return(nil)
+				repo.
+					EXPECT().
+					CreateOrUpdateBatch(gomock.Any(), []entity.FeiraLivre{fl}).
+					Return(nil)
+				repo.
+					EXPECT().
+					SyncPK(gomock.Any()).
+					Return(nil)
+			},
+			inOpts: Options{Workers: 1, BatchSize: 2},
+			out:    "Import finished! Read 3 registers, 3 imported and 0 errors.\n",
+		},
+		{
+			name: "when a batch fails",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.
+					EXPECT().
+					CreateOrUpdateBatch(gomock.Any(), []entity.FeiraLivre{fl, fl}).
+					Return(errors.New("unexpected error"))
+				repo.
+					EXPECT().
+					CreateOrUpdateBatch(gomock.Any(), []entity.FeiraLivre{fl}).
+					Return(nil)
+				repo.
+					EXPECT().
+					SyncPK(gomock.Any()).
+					Return(nil)
+			},
+			inOpts: Options{Workers: 1, BatchSize: 2},
+			out:    "Import finished! Read 3 registers, 1 imported and 2 errors.\n",
+		},
+		{
+			name: "when limiting the rows per second",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.
+					EXPECT().
+					CreateOrUpdate(gomock.Any(), fl).
+					Return(&fl, nil).
+					Times(3)
+				repo.
+					EXPECT().
+					SyncPK(gomock.Any()).
+					Return(nil)
+			},
+			inOpts: Options{Workers: 2, MaxRowsPerSecond: 1000},
+			out:    "Import finished! Read 3 registers, 3 imported and 0 errors.\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			afero.WriteFile(fs, "/my.csv", []byte(content), 0644)
+			ctrl := gomock.NewController(t)
+			repo := feiralivre.NewMockRepository(ctrl)
+			tc.setupMocks(repo)
+			svc := New(fs, repo, tc.inOpts)
+
+			msg, err := svc.Import(context.Background(), "/my.csv", "")
 
 			if msg != tc.out {
 				t.Errorf("was expecting:\n%s\nbut returns:\n%s\n", tc.out, msg)
@@ -350,3 +483,220 @@ func TestServiceImport(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceImportRows(t *testing.T) {
+	headersLine := "ID,LONG,LAT,SETCENS,AREAP,CODDIST,DISTRITO,CODSUBPREF,SUBPREF,REGIAO5,REGIAO8,NOME_FEIRA,REGISTRO,LOGRADOURO,NUMERO,BAIRRO,REFERENCIA"
+	bodyLine := "1,-46548146,-23568390,355030885000019,3550308005040,87,VILA FORMOSA,26,ARICANDUVA,Leste,Leste 1,PRAÇA LEÃO X,7216-8,RUA CODAJÁS,45,VILA FORMOSA,PRAÇA MARECHAL LEITE BANDEIRA"
+	content := headersLine + "\n" + bodyLine + "\n" + bodyLine + "\n" + "invalid"
+
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/my.csv", []byte(content), 0644)
+	ctrl := gomock.NewController(t)
+	repo := feiralivre.NewMockRepository(ctrl)
+	repo.
+		EXPECT().
+		CreateOrUpdate(gomock.Any(), gomock.Any()).
+		Return(&entity.FeiraLivre{}, nil).
+		Times(2)
+	repo.
+		EXPECT().
+		SyncPK(gomock.Any()).
+		Return(nil)
+
+	var read, persisted, failed int64
+	svc := New(fs, repo, Options{
+		Rows: func(r, p, f int64) {
+			atomic.AddInt64(&read, r)
+			atomic.AddInt64(&persisted, p)
+			atomic.AddInt64(&failed, f)
+		},
+	})
+
+	if _, err := svc.Import(context.Background(), "/my.csv", ""); err != nil {
+		t.Errorf("was not expecting an error, but returns %v", err)
+	}
+	if read != 3 || persisted != 2 || failed != 1 {
+		t.Errorf("was expecting 3 read, 2 persisted and 1 failed, but returns %d, %d and %d", read, persisted, failed)
+	}
+}
+
+func TestServiceImportWithProgressCanceled(t *testing.T) {
+	headersLine := "ID,LONG,LAT,SETCENS,AREAP,CODDIST,DISTRITO,CODSUBPREF,SUBPREF,REGIAO5,REGIAO8,NOME_FEIRA,REGISTRO,LOGRADOURO,NUMERO,BAIRRO,REFERENCIA"
+	bodyLine := "1,-46548146,-23568390,355030885000019,3550308005040,87,VILA FORMOSA,26,ARICANDUVA,Leste,Leste 1,PRAÇA LEÃO X,7216-8,RUA CODAJÁS,45,VILA FORMOSA,PRAÇA MARECHAL LEITE BANDEIRA"
+
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/my.csv", []byte(headersLine+"\n"+bodyLine), 0644)
+	ctrl := gomock.NewController(t)
+	repo := feiralivre.NewMockRepository(ctrl)
+	repo.
+		EXPECT().
+		SyncPK(gomock.Any()).
+		Return(nil)
+	svc := New(fs, repo, DefaultOptions())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p, err := svc.ImportWithProgress(ctx, "/my.csv", "", entity.AllAreas, "", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("was expecting %v, but returns %v", context.Canceled, err)
+	}
+	if p.Read != 0 || p.Persisted != 0 || p.Failed != 0 {
+		t.Errorf("was expecting nothing imported, but returns %+v", p)
+	}
+}
+
+func TestServiceImportWithProgressAreas(t *testing.T) {
+	headersLine := "ID,LONG,LAT,SETCENS,AREAP,CODDIST,DISTRITO,CODSUBPREF,SUBPREF,REGIAO5,REGIAO8,NOME_FEIRA,REGISTRO,LOGRADOURO,NUMERO,BAIRRO,REFERENCIA"
+	lines := []string{
+		headersLine,
+		"1,-46548146,-23568390,355030885000019,3550308005040,87,VILA FORMOSA,26,ARICANDUVA,Leste,Leste 1,PRAÇA LEÃO X,7216-8,RUA CODAJÁS,45,VILA FORMOSA,PRAÇA MARECHAL LEITE BANDEIRA",
+		"2,-46728590,-23533660,355030830000047,3550308005115,41,JAGUARA,8,LAPA,Oeste,Oeste 1,VILA JAGUARA,1078-2,RUA DOUTOR AMADEU CARAMANTI,92,VILA JAGUARA,",
+		"3,-46548146,-23568390,355030885000019,3550308005040,87,VILA FORMOSA,26,ARICANDUVA,Leste,Leste 1,PRAÇA LEÃO X,7216-8,RUA CODAJÁS,45,VILA FORMOSA,PRAÇA MARECHAL LEITE BANDEIRA",
+	}
+
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/my.csv", []byte(strings.Join(lines, "\n")), 0644)
+	ctrl := gomock.NewController(t)
+	repo := feiralivre.NewMockRepository(ctrl)
+	repo.EXPECT().GetByID(gomock.Any(), 1).Return(nil, sql.ErrNoRows)
+	repo.EXPECT().GetByID(gomock.Any(), 3).Return(&entity.FeiraLivre{ID: 3, CodigoSubprefeitura: 8, Regiao5: "Oeste"}, nil)
+	repo.
+		EXPECT().
+		CreateOrUpdate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fl entity.FeiraLivre) (*entity.FeiraLivre, error) {
+			if fl.ID != 1 {
+				t.Errorf("was expecting only the feiralivre 1 persisted, but persists %d", fl.ID)
+			}
+			if fl.CreatedBy != "maria" || fl.UpdatedBy != "maria" {
+				t.Errorf("was expecting the feiralivre recorded as created and updated by maria, but returns %q and %q", fl.CreatedBy, fl.UpdatedBy)
+			}
+			return &fl, nil
+		})
+	repo.
+		EXPECT().
+		SyncPK(gomock.Any()).
+		Return(nil)
+	svc := New(fs, repo, Options{Workers: 1, BatchSize: 1})
+
+	areas := entity.Areas{Grants: []entity.Grant{{Scope: entity.ScopeImport, CodigoSubprefeitura: 26}}}
+	p, err := svc.ImportWithProgress(context.Background(), "/my.csv", "", areas, "maria", nil)
+	if err != nil {
+		t.Errorf("was not expecting an error, but returns %v", err)
+	}
+	if p.Read != 3 || p.Persisted != 1 || p.Failed != 2 {
+		t.Errorf("was expecting 3 read, 1 persisted and 2 failed, but returns %+v", p)
+	}
+}
+
+func TestServiceImportCanceled(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/my.csv", []byte("ID,LONG,LAT"), 0644)
+	ctrl := gomock.NewController(t)
+	repo := feiralivre.NewMockRepository(ctrl)
+	repo.
+		EXPECT().
+		SyncPK(gomock.Any()).
+		Return(nil)
+	svc := New(fs, repo, DefaultOptions())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	msg, err := svc.Import(ctx, "/my.csv", "")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("was expecting %v, but returns %v", context.Canceled, err)
+	}
+	out := "Import canceled! Read 0 registers, 0 imported and 0 errors.\n"
+	if msg != out {
+		t.Errorf("was expecting %q, but returns %q", out, msg)
+	}
+}
+
+func TestServiceExport(t *testing.T) {
+	fl := entity.FeiraLivre{
+		ID:                  1,
+		Latitude:            -23568390,
+		Longitude:           -46548146,
+		SetorCensitario:     355030885000019,
+		AreaPonderacao:      3550308005040,
+		CodigoDistrito:      87,
+		Distrito:            "VILA FORMOSA",
+		CodigoSubprefeitura: 26,
+		Subprefeitura:       "ARICANDUVA",
+		Regiao5:             "Leste",
+		Regiao8:             "Leste 1",
+		NomeFeira:           "PRAÇA LEÃO X",
+		Registro:            "7216-8",
+		Logradouro:          "RUA CODAJÁS",
+		Numero:              "45",
+		Bairro:              "VILA FORMOSA",
+		Referencia:          "PRAÇA MARECHAL LEITE BANDEIRA",
+	}
+	qp := feiralivre.QueryParams{Distrito: "VILA"}
+	testCases := []struct {
+		name       string
+		setupMocks func(repo *feiralivre.MockRepository)
+		inFormat   string
+		outCount   int64
+		outContent string
+		hasError   bool
+	}{
+		{
+			name:       "when the format is unknown",
+			setupMocks: func(repo *feiralivre.MockRepository) {},
+			inFormat:   "xml",
+			hasError:   true,
+		},
+		{
+			name: "when the repository returns an error",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.
+					EXPECT().
+					ForEach(gomock.Any(), qp, gomock.Any()).
+					Return(errors.New("unexpected error"))
+			},
+			inFormat: FormatCSV,
+			hasError: true,
+		},
+		{
+			name: "when success",
+			setupMocks: func(repo *feiralivre.MockRepository) {
+				repo.
+					EXPECT().
+					ForEach(gomock.Any(), qp, gomock.Any()).
+					DoAndReturn(func(_ context.Context, qp feiralivre.QueryParams, fn func(entity.FeiraLivre) error) error {
+						return fn(fl)
+					})
+			},
+			inFormat:   FormatCSV,
+			outCount:   1,
+			outContent: "ID,LONG,LAT,SETCENS,AREAP,CODDIST,DISTRITO,CODSUBPREF,SUBPREFE,REGIAO5,REGIAO8,NOME_FEIRA,REGISTRO,LOGRADOURO,NUMERO,BAIRRO,REFERENCIA\n1,-46548146,-23568390,355030885000019,3550308005040,87,VILA FORMOSA,26,ARICANDUVA,Leste,Leste 1,PRAÇA LEÃO X,7216-8,RUA CODAJÁS,45,VILA FORMOSA,PRAÇA MARECHAL LEITE BANDEIRA\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := feiralivre.NewMockRepository(ctrl)
+			tc.setupMocks(repo)
+			svc := New(afero.NewMemMapFs(), repo, DefaultOptions())
+
+			var content strings.Builder
+			count, err := svc.Export(context.Background(), &content, tc.inFormat, qp)
+
+			if tc.hasError && err == nil {
+				t.Error("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was expecting an empty error, but returns: %v", err)
+			}
+			if count != tc.outCount {
+				t.Errorf("was expecting %d exported, but returns %d", tc.outCount, count)
+			}
+			if content.String() != tc.outContent {
+				t.Errorf("was expecting:\n%s\nbut returns:\n%s\n", tc.outContent, content.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package feiralivre
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+const (
+	geojsonFeatureCollection = "FeatureCollection"
+	geojsonFeature           = "Feature"
+	geojsonPoint             = "Point"
+)
+
+type geojsonGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type geojsonFeatureItem struct {
+	Type       string           `json:"type"`
+	Geometry   *geojsonGeometry `json:"geometry"`
+	Properties json.RawMessage  `json:"properties"`
+}
+
+type geojsonReader struct{}
+
+func parseGeoJSONFeature(raw json.RawMessage) (*entity.FeiraLivre, error) {
+	var feature geojsonFeatureItem
+	if err := json.Unmarshal(raw, &feature); err != nil {
+		return nil, err
+	}
+
+	if feature.Type != geojsonFeature {
+		return nil, fmt.Errorf("was expecting a %s, but found %q", geojsonFeature, feature.Type)
+	}
+
+	var fl entity.FeiraLivre
+	if len(feature.Properties) > 0 {
+		if err := json.Unmarshal(feature.Properties, &fl); err != nil {
+			return nil, fmt.Errorf("could not parse properties: %v", err)
+		}
+	}
+
+	if feature.Geometry == nil {
+		return nil, errors.New("the feature does not have a geometry")
+	}
+	if feature.Geometry.Type != geojsonPoint {
+		return nil, fmt.Errorf("was expecting a %s geometry, but found %q", geojsonPoint, feature.Geometry.Type)
+	}
+	if len(feature.Geometry.Coordinates) < 2 {
+		return nil, fmt.Errorf("the point must have 2 or more coordinates, was received %d", len(feature.Geometry.Coordinates))
+	}
+
+	// geojson positions are written as [longitude, latitude] in decimal degrees
+	fl.Longitude = entity.FromDegrees(feature.Geometry.Coordinates[0])
+	fl.Latitude = entity.FromDegrees(feature.Geometry.Coordinates[1])
+
+	return &fl, nil
+}
+
+// Read implements how to read a geojson FeatureCollection of feiralivre
+func (geojsonReader) Read(r io.Reader, flChan chan<- *entity.FeiraLivre, errChan chan<- error) {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		errChan <- fmt.Errorf("could not read geojson object: %v", err)
+		return
+	}
+
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			errChan <- fmt.Errorf("could not read geojson key: %v", err)
+			return
+		}
+
+		switch token {
+		case "type":
+			var t string
+			if err := dec.Decode(&t); err != nil {
+				errChan <- fmt.Errorf("could not read geojson type: %v", err)
+				return
+			}
+			if t != geojsonFeatureCollection {
+				errChan <- fmt.Errorf("was expecting a %s, but found %q", geojsonFeatureCollection, t)
+				return
+			}
+		case "features":
+			if err := expectDelim(dec, '['); err != nil {
+				errChan <- fmt.Errorf("could not read geojson features: %v", err)
+				return
+			}
+			if !readJSONArray(dec, flChan, errChan, parseGeoJSONFeature) {
+				return
+			}
+		default:
+			// skip members that are not used, like bbox and crs
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				errChan <- fmt.Errorf("could not read geojson member %v: %v", token, err)
+				return
+			}
+		}
+	}
+}
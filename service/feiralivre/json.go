@@ -0,0 +1,97 @@
+package feiralivre
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+// maxNDJSONLineSize is the biggest line accepted when reading ndjson
+const maxNDJSONLineSize = 1024 * 1024
+
+type jsonReader struct{}
+
+// Read implements how to read a json array of feiralivre
+func (jsonReader) Read(r io.Reader, flChan chan<- *entity.FeiraLivre, errChan chan<- error) {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '['); err != nil {
+		errChan <- fmt.Errorf("could not read json array: %v", err)
+		return
+	}
+
+	readJSONArray(dec, flChan, errChan, func(raw json.RawMessage) (*entity.FeiraLivre, error) {
+		var fl entity.FeiraLivre
+		if err := json.Unmarshal(raw, &fl); err != nil {
+			return nil, err
+		}
+		return &fl, nil
+	})
+}
+
+type ndjsonReader struct{}
+
+// Read implements how to read one feiralivre json per line
+func (ndjsonReader) Read(r io.Reader, flChan chan<- *entity.FeiraLivre, errChan chan<- error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxNDJSONLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		content := bytes.TrimSpace(scanner.Bytes())
+		if len(content) == 0 {
+			continue
+		}
+		var fl entity.FeiraLivre
+		if err := json.Unmarshal(content, &fl); err != nil {
+			errChan <- fmt.Errorf("could not parse line %d to feiralivre: %v", line, err)
+			continue
+		}
+		flChan <- &fl
+	}
+
+	if err := scanner.Err(); err != nil {
+		errChan <- fmt.Errorf("could not read ndjson line: %v", err)
+	}
+}
+
+// expectDelim consumes the next token and checks if it is the delim
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := token.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("was expecting %v, but found %v", delim, token)
+	}
+	return nil
+}
+
+// readJSONArray streams the items of an already opened json array, converting each one with parse,
+// returns false when the stream could not be read until the end of the array
+func readJSONArray(dec *json.Decoder, flChan chan<- *entity.FeiraLivre, errChan chan<- error, parse func(json.RawMessage) (*entity.FeiraLivre, error)) bool {
+	for i := 0; dec.More(); i++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			// a syntax error leaves the decoder in an unknown state
+			errChan <- fmt.Errorf("could not read item %d: %v", i, err)
+			return false
+		}
+		fl, err := parse(raw)
+		if err != nil {
+			errChan <- fmt.Errorf("could not parse item %d to feiralivre: %v", i, err)
+			continue
+		}
+		flChan <- fl
+	}
+
+	if err := expectDelim(dec, ']'); err != nil {
+		errChan <- fmt.Errorf("could not read the end of the array: %v", err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,72 @@
+package feiralivre
+
+import "time"
+
+const (
+	// DefaultWorkers is the number of workers used when Options.Workers is not informed
+	DefaultWorkers = 8
+	// DefaultBatchSize is the batch size used when Options.BatchSize is not informed
+	DefaultBatchSize = 1
+	// DefaultMaxStdinZipSize is the limit used when Options.MaxStdinZipSize is not informed
+	DefaultMaxStdinZipSize = 1 << 30
+)
+
+// Options contains the settings used by the service
+type Options struct {
+	// Workers is the number of goroutines persisting the registers
+	Workers int
+	// BatchSize is the number of registers persisted by each repository call
+	BatchSize int
+	// MaxRowsPerSecond limits how many rows are read per second, zero means unlimited
+	MaxRowsPerSecond int
+	// MaxOpenConns is the database pool limit, when greater than zero the workers never exceed it
+	MaxOpenConns int
+	// Progress receives the import progress every ProgressInterval and when it finishes
+	Progress ProgressFunc
+	// ProgressInterval is the interval between the progress events
+	ProgressInterval time.Duration
+	// Rows receives the rows counted by every import as they happen
+	Rows RowsFunc
+	// MaxStdinZipSize is the size limit of a zip read from the stdin, which is copied to a
+	// temporary file since the zip needs random access
+	MaxStdinZipSize int64
+}
+
+// DefaultOptions creates the Options used when nothing is informed
+func DefaultOptions() Options {
+	return Options{
+		Workers:          DefaultWorkers,
+		BatchSize:        DefaultBatchSize,
+		ProgressInterval: DefaultProgressInterval,
+		MaxStdinZipSize:  DefaultMaxStdinZipSize,
+	}
+}
+
+// normalize replaces the invalid values by the defaults and applies the limits
+func (o Options) normalize() Options {
+	if o.Workers < 1 {
+		o.Workers = DefaultWorkers
+	}
+
+	if o.MaxOpenConns > 0 && o.Workers > o.MaxOpenConns {
+		o.Workers = o.MaxOpenConns
+	}
+
+	if o.BatchSize < 1 {
+		o.BatchSize = DefaultBatchSize
+	}
+
+	if o.MaxRowsPerSecond < 0 {
+		o.MaxRowsPerSecond = 0
+	}
+
+	if o.ProgressInterval <= 0 {
+		o.ProgressInterval = DefaultProgressInterval
+	}
+
+	if o.MaxStdinZipSize <= 0 {
+		o.MaxStdinZipSize = DefaultMaxStdinZipSize
+	}
+
+	return o
+}
@@ -0,0 +1,44 @@
+package feiralivre
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestOptionsNormalize(t *testing.T) {
+	testCases := []struct {
+		name string
+		in   Options
+		out  Options
+	}{
+		{
+			name: "when empty",
+			in:   Options{},
+			out:  DefaultOptions(),
+		},
+		{
+			name: "when has invalid values",
+			in:   Options{Workers: -1, BatchSize: -1, MaxRowsPerSecond: -1},
+			out:  DefaultOptions(),
+		},
+		{
+			name: "when workers exceed the max open conns",
+			in:   Options{Workers: 16, BatchSize: 100, MaxOpenConns: 4},
+			out:  Options{Workers: 4, BatchSize: 100, MaxOpenConns: 4, ProgressInterval: DefaultProgressInterval, MaxStdinZipSize: DefaultMaxStdinZipSize},
+		},
+		{
+			name: "when workers do not exceed the max open conns",
+			in:   Options{Workers: 2, BatchSize: 100, MaxRowsPerSecond: 50, MaxOpenConns: 4, ProgressInterval: time.Minute, MaxStdinZipSize: 1024},
+			out:  Options{Workers: 2, BatchSize: 100, MaxRowsPerSecond: 50, MaxOpenConns: 4, ProgressInterval: time.Minute, MaxStdinZipSize: 1024},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := tc.in.normalize(); !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package feiralivre
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultProgressInterval is the interval used when Options.ProgressInterval is not informed
+	DefaultProgressInterval = time.Second
+	// MaxProgressErrors is the number of error messages kept in the Progress
+	MaxProgressErrors = 100
+)
+
+// Progress contains the state of an import
+type Progress struct {
+	// Read is the number of rows read, including the ones that could not be parsed
+	Read int64
+	// Persisted is the number of registers saved in the repository
+	Persisted int64
+	// Failed is the number of rows that could not be parsed or persisted
+	Failed int64
+	// BytesRead is the number of bytes read from the source
+	BytesRead int64
+	// TotalBytes is the size of the source, zero when it is unknown (like the stdin)
+	TotalBytes int64
+	// Errors contains the first MaxProgressErrors error messages
+	Errors []string
+	// Elapsed is the time since the import started
+	Elapsed time.Duration
+	// Done indicates that it is the last event of the import
+	Done bool
+}
+
+// ProgressFunc receives the progress events of an import
+type ProgressFunc func(Progress)
+
+// RowsFunc receives the increments of the rows read, persisted and failed while every import runs,
+// e.g. to export them as metrics
+type RowsFunc func(read, persisted, failed int64)
+
+// Throughput returns the number of rows read per second
+func (p Progress) Throughput() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Read) / p.Elapsed.Seconds()
+}
+
+// Ratio returns the fraction of the source already read, zero when the size is unknown
+func (p Progress) Ratio() float64 {
+	if p.TotalBytes <= 0 {
+		return 0
+	}
+	if p.BytesRead >= p.TotalBytes {
+		return 1
+	}
+	return float64(p.BytesRead) / float64(p.TotalBytes)
+}
+
+// ETA estimates the remaining time based on the bytes read, zero when it is unknown
+func (p Progress) ETA() time.Duration {
+	ratio := p.Ratio()
+	if ratio == 0 || p.Done {
+		return 0
+	}
+	return time.Duration(float64(p.Elapsed) * (1 - ratio) / ratio)
+}
+
+// counters keeps the import state shared between the goroutines
+type counters struct {
+	read       int64
+	persisted  int64
+	failed     int64
+	bytesRead  int64
+	totalBytes int64
+	start      time.Time
+	rows       RowsFunc
+	mu         sync.Mutex
+	errors     []string
+}
+
+// addRead counts n read rows
+func (c *counters) addRead(n int64) {
+	atomic.AddInt64(&c.read, n)
+	if c.rows != nil {
+		c.rows(n, 0, 0)
+	}
+}
+
+// addPersisted counts n persisted rows
+func (c *counters) addPersisted(n int64) {
+	atomic.AddInt64(&c.persisted, n)
+	if c.rows != nil {
+		c.rows(0, n, 0)
+	}
+}
+
+// fail counts n failed rows and keeps the err message
+func (c *counters) fail(n int64, err error) {
+	atomic.AddInt64(&c.failed, n)
+	if c.rows != nil {
+		c.rows(0, 0, n)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.errors) < MaxProgressErrors {
+		c.errors = append(c.errors, err.Error())
+	}
+}
+
+func (c *counters) progress(done bool) Progress {
+	c.mu.Lock()
+	var errs []string
+	if len(c.errors) > 0 {
+		errs = append(errs, c.errors...)
+	}
+	c.mu.Unlock()
+
+	return Progress{
+		Read:       atomic.LoadInt64(&c.read),
+		Persisted:  atomic.LoadInt64(&c.persisted),
+		Failed:     atomic.LoadInt64(&c.failed),
+		BytesRead:  atomic.LoadInt64(&c.bytesRead),
+		TotalBytes: c.totalBytes,
+		Errors:     errs,
+		Elapsed:    time.Since(c.start),
+		Done:       done,
+	}
+}
+
+// countingReader counts the bytes read from the source
+type countingReader struct {
+	r     io.Reader
+	count *int64
+}
+
+func (cr countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	atomic.AddInt64(cr.count, int64(n))
+	return n, err
+}
+
+// countingReaderAt counts the bytes read from a source with random access, as a zip
+type countingReaderAt struct {
+	r     io.ReaderAt
+	count *int64
+}
+
+func (cr countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := cr.r.ReadAt(p, off)
+	atomic.AddInt64(cr.count, int64(n))
+	return n, err
+}
+
+// contextReader stops reading the source when ctx is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// reportProgress calls progress every interval until the returned func is called,
+// which sends the last event
+func (s service) reportProgress(c *counters, progress ProgressFunc) (stop func()) {
+	if progress == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(s.opts.ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				progress(c.progress(false))
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+		progress(c.progress(true))
+	}
+}
@@ -0,0 +1,126 @@
+package feiralivre
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/afero"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/repository/feiralivre"
+)
+
+func TestProgress(t *testing.T) {
+	testCases := []struct {
+		name          string
+		in            Progress
+		outThroughput float64
+		outRatio      float64
+		outETA        time.Duration
+	}{
+		{
+			name: "when nothing elapsed",
+			in:   Progress{},
+		},
+		{
+			name:          "when the size is unknown",
+			in:            Progress{Read: 20, BytesRead: 100, Elapsed: 2 * time.Second},
+			outThroughput: 10,
+		},
+		{
+			name:          "when the size is known",
+			in:            Progress{Read: 20, BytesRead: 100, TotalBytes: 400, Elapsed: 2 * time.Second},
+			outThroughput: 10,
+			outRatio:      0.25,
+			outETA:        6 * time.Second,
+		},
+		{
+			name:          "when done",
+			in:            Progress{Read: 20, BytesRead: 400, TotalBytes: 400, Elapsed: 2 * time.Second, Done: true},
+			outThroughput: 10,
+			outRatio:      1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := tc.in.Throughput(); res != tc.outThroughput {
+				t.Errorf("was expecting the throughput %v, but returns %v", tc.outThroughput, res)
+			}
+			if res := tc.in.Ratio(); res != tc.outRatio {
+				t.Errorf("was expecting the ratio %v, but returns %v", tc.outRatio, res)
+			}
+			if res := tc.in.ETA(); res != tc.outETA {
+				t.Errorf("was expecting the eta %v, but returns %v", tc.outETA, res)
+			}
+		})
+	}
+}
+
+func TestServiceImportProgress(t *testing.T) {
+	headersLine := "ID,LONG,LAT,SETCENS,AREAP,CODDIST,DISTRITO,CODSUBPREF,SUBPREF,REGIAO5,REGIAO8,NOME_FEIRA,REGISTRO,LOGRADOURO,NUMERO,BAIRRO,REFERENCIA"
+	bodyLine := "1,-46548146,-23568390,355030885000019,3550308005040,87,VILA FORMOSA,26,ARICANDUVA,Leste,Leste 1,PRAÇA LEÃO X,7216-8,RUA CODAJÁS,45,VILA FORMOSA,PRAÇA MARECHAL LEITE BANDEIRA"
+	content := headersLine + "\n" + bodyLine + "\n,,,,,,,,,,,,,,,,\n" + bodyLine
+
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/my.csv", []byte(content), 0644)
+	ctrl := gomock.NewController(t)
+	repo := feiralivre.NewMockRepository(ctrl)
+	repo.
+		EXPECT().
+		CreateOrUpdate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, fl entity.FeiraLivre) (*entity.FeiraLivre, error) {
+			time.Sleep(5 * time.Millisecond)
+			return &fl, nil
+		}).
+		Times(2)
+	repo.
+		EXPECT().
+		SyncPK(gomock.Any()).
+		Return(nil)
+
+	var mu sync.Mutex
+	var events []Progress
+	svc := New(fs, repo, Options{
+		Workers:          1,
+		ProgressInterval: time.Millisecond,
+		Progress: func(p Progress) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, p)
+		},
+	})
+
+	if _, err := svc.Import(context.Background(), "/my.csv", ""); err != nil {
+		t.Fatalf("was expecting an empty error, but returns: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) < 2 {
+		t.Fatalf("was expecting periodic events and the last one, but returns %d events", len(events))
+	}
+	last := events[len(events)-1]
+	expected := Progress{
+		Read:       3,
+		Persisted:  2,
+		Failed:     1,
+		BytesRead:  int64(len(content)),
+		TotalBytes: int64(len(content)),
+		Errors:     []string{"could not parse row to feiralivre: could not parse ID column: strconv.Atoi: parsing \"\": invalid syntax"},
+		Elapsed:    last.Elapsed,
+		Done:       true,
+	}
+	if !reflect.DeepEqual(expected, last) {
+		t.Errorf("was expecting %+v, but returns %+v", expected, last)
+	}
+	for _, e := range events[:len(events)-1] {
+		if e.Done {
+			t.Errorf("was expecting only the last event as done, but returns %+v", e)
+		}
+	}
+}
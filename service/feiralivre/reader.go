@@ -0,0 +1,63 @@
+package feiralivre
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+const (
+	// FormatCSV indicates a source in the DEINFO csv layout
+	FormatCSV = "csv"
+	// FormatJSON indicates a source containing a json array of feiralivre
+	FormatJSON = "json"
+	// FormatNDJSON indicates a source containing one feiralivre json per line
+	FormatNDJSON = "ndjson"
+	// FormatGeoJSON indicates a source containing a geojson FeatureCollection of feiralivre
+	FormatGeoJSON = "geojson"
+)
+
+// ErrUnknownFormat is used when a source format is not supported
+var ErrUnknownFormat = errors.New("unknown source format")
+
+// SourceReader represents how a source reader should be implemented
+type SourceReader interface {
+	// Read sends every feiralivre found in r to flChan and every problem found to errChan,
+	// the channels should not be closed by the reader
+	Read(r io.Reader, flChan chan<- *entity.FeiraLivre, errChan chan<- error)
+}
+
+// NewSourceReader creates the SourceReader for the format
+func NewSourceReader(format string) (SourceReader, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return csvReader{}, nil
+	case FormatJSON:
+		return jsonReader{}, nil
+	case FormatNDJSON:
+		return ndjsonReader{}, nil
+	case FormatGeoJSON:
+		return geojsonReader{}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// FormatFromPath detects the source format based on the file extension
+func FormatFromPath(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".ndjson", ".jsonl":
+		return FormatNDJSON, nil
+	case ".geojson":
+		return FormatGeoJSON, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
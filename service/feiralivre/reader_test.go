@@ -0,0 +1,243 @@
+package feiralivre
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+func TestFormatFromPath(t *testing.T) {
+	testCases := []struct {
+		name     string
+		in       string
+		out      string
+		hasError bool
+	}{
+		{
+			name: "when csv",
+			in:   "/my.csv",
+			out:  FormatCSV,
+		},
+		{
+			name: "when json",
+			in:   "/my.JSON",
+			out:  FormatJSON,
+		},
+		{
+			name: "when ndjson",
+			in:   "/my.ndjson",
+			out:  FormatNDJSON,
+		},
+		{
+			name: "when jsonl",
+			in:   "/my.jsonl",
+			out:  FormatNDJSON,
+		},
+		{
+			name: "when geojson",
+			in:   "/my.geojson",
+			out:  FormatGeoJSON,
+		},
+		{
+			name:     "when the extension is unknown",
+			in:       "/my.txt",
+			hasError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := FormatFromPath(tc.in)
+			if tc.hasError && err == nil {
+				t.Error("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns: %v", err)
+			}
+			if res != tc.out {
+				t.Errorf("was expecting %s, but returns %s", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestNewSourceReader(t *testing.T) {
+	testCases := []struct {
+		name     string
+		in       string
+		out      SourceReader
+		hasError bool
+	}{
+		{
+			name: "when csv",
+			in:   FormatCSV,
+			out:  csvReader{},
+		},
+		{
+			name: "when json",
+			in:   FormatJSON,
+			out:  jsonReader{},
+		},
+		{
+			name: "when ndjson",
+			in:   FormatNDJSON,
+			out:  ndjsonReader{},
+		},
+		{
+			name: "when geojson",
+			in:   "GeoJSON",
+			out:  geojsonReader{},
+		},
+		{
+			name:     "when the format is unknown",
+			in:       "xml",
+			hasError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := NewSourceReader(tc.in)
+			if tc.hasError && err != ErrUnknownFormat {
+				t.Errorf("was expecting %v, but returns %v", ErrUnknownFormat, err)
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns: %v", err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %T, but returns %T", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestSourceReaders(t *testing.T) {
+	fl := entity.FeiraLivre{
+		ID:                  1,
+		Latitude:            -23568390,
+		Longitude:           -46548146,
+		SetorCensitario:     355030885000019,
+		AreaPonderacao:      3550308005040,
+		CodigoDistrito:      87,
+		Distrito:            "VILA FORMOSA",
+		CodigoSubprefeitura: 26,
+		Subprefeitura:       "ARICANDUVA",
+		Regiao5:             "Leste",
+		Regiao8:             "Leste 1",
+		NomeFeira:           "PRAÇA LEÃO X",
+		Registro:            "7216-8",
+		Logradouro:          "RUA CODAJÁS",
+		Numero:              "45",
+		Bairro:              "VILA FORMOSA",
+		Referencia:          "PRAÇA MARECHAL LEITE BANDEIRA",
+	}
+	properties := `"id":1,"setor_censitario":355030885000019,"area_ponderacao":3550308005040,"codigo_distrito":87,"distrito":"VILA FORMOSA","codigo_subprefeitura":26,"subprefeitura":"ARICANDUVA","regiao5":"Leste","regiao8":"Leste 1","nome_feira":"PRAÇA LEÃO X","registro":"7216-8","logradouro":"RUA CODAJÁS","numero":"45","bairro":"VILA FORMOSA","referencia":"PRAÇA MARECHAL LEITE BANDEIRA"`
+	jsonLine := `{"latitude":-23568390,"longitude":-46548146,` + properties + `}`
+	feature := `{"type":"Feature","geometry":{"type":"Point","coordinates":[-46.548146,-23.56839]},"properties":{` + properties + `}}`
+	testCases := []struct {
+		name      string
+		inReader  SourceReader
+		inContent string
+		outFls    []entity.FeiraLivre
+		outErrs   int
+	}{
+		{
+			name:      "when json is not an array",
+			inReader:  jsonReader{},
+			inContent: jsonLine,
+			outErrs:   1,
+		},
+		{
+			name:      "when json has an invalid item",
+			inReader:  jsonReader{},
+			inContent: `[` + jsonLine + `,{"id":"a"}]`,
+			outFls:    []entity.FeiraLivre{fl},
+			outErrs:   1,
+		},
+		{
+			name:      "when json success",
+			inReader:  jsonReader{},
+			inContent: `[` + jsonLine + `,` + jsonLine + `]`,
+			outFls:    []entity.FeiraLivre{fl, fl},
+		},
+		{
+			name:      "when ndjson has an invalid line",
+			inReader:  ndjsonReader{},
+			inContent: jsonLine + "\n{\n" + jsonLine,
+			outFls:    []entity.FeiraLivre{fl, fl},
+			outErrs:   1,
+		},
+		{
+			name:      "when ndjson success",
+			inReader:  ndjsonReader{},
+			inContent: jsonLine + "\n\n" + jsonLine + "\n",
+			outFls:    []entity.FeiraLivre{fl, fl},
+		},
+		{
+			name:      "when geojson is not a FeatureCollection",
+			inReader:  geojsonReader{},
+			inContent: feature,
+			outErrs:   1,
+		},
+		{
+			name:      "when geojson has a feature without geometry",
+			inReader:  geojsonReader{},
+			inContent: `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{}},` + feature + `]}`,
+			outFls:    []entity.FeiraLivre{fl},
+			outErrs:   1,
+		},
+		{
+			name:      "when geojson has a feature that is not a point",
+			inReader:  geojsonReader{},
+			inContent: `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"LineString","coordinates":[[1,2],[3,4]]}}]}`,
+			outErrs:   1,
+		},
+		{
+			name:      "when geojson success",
+			inReader:  geojsonReader{},
+			inContent: `{"type":"FeatureCollection","bbox":[0,0,1,1],"features":[` + feature + `]}`,
+			outFls:    []entity.FeiraLivre{fl},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			flChan := make(chan *entity.FeiraLivre)
+			errChan := make(chan error)
+
+			go func() {
+				defer close(flChan)
+				defer close(errChan)
+				tc.inReader.Read(strings.NewReader(tc.inContent), flChan, errChan)
+			}()
+
+			var fls []entity.FeiraLivre
+			var errs []error
+			for flChan != nil || errChan != nil {
+				select {
+				case fl, ok := <-flChan:
+					if !ok {
+						flChan = nil
+						continue
+					}
+					fls = append(fls, *fl)
+				case err, ok := <-errChan:
+					if !ok {
+						errChan = nil
+						continue
+					}
+					errs = append(errs, err)
+				}
+			}
+
+			if !reflect.DeepEqual(tc.outFls, fls) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.outFls, fls)
+			}
+			if tc.outErrs != len(errs) {
+				t.Errorf("was expecting %d errors, but returns %d errors (%+v)", tc.outErrs, len(errs), errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package feiralivre
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/spf13/afero"
+)
+
+// StdinPath is the path used to read the source from the stdin
+const StdinPath = "-"
+
+var (
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicBzip2 = []byte("BZh")
+	magicZip   = []byte("PK\x03\x04")
+)
+
+// ErrNoSupportedFileInZip is used when a zip does not contain a file that could be imported
+var ErrNoSupportedFileInZip = errors.New("the zip does not contain a supported file")
+
+// source is an opened source, name is used to detect the format of the content
+// and size is the size of the raw source (zero when it is unknown)
+type source struct {
+	io.Reader
+	name    string
+	size    int64
+	closers []io.Closer
+}
+
+// Close closes every resource used by the source
+func (s *source) Close() error {
+	var err error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if cerr := s.closers[i].Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// trimExt removes ext from the end of name ignoring the case
+func trimExt(name, ext string) string {
+	if strings.EqualFold(filepath.Ext(name), ext) {
+		return name[:len(name)-len(ext)]
+	}
+	return name
+}
+
+// openSource opens the path, or the stdin when path is StdinPath, and transparently
+// decompresses gzip, bzip2 and zip contents, when count is not nil the bytes read
+// from the raw source are added to it
+func (s service) openSource(path, format string, count *int64) (*source, error) {
+	src := &source{name: path}
+
+	// file is the opened path, a zip is read from it with random access
+	var file afero.File
+	if path == StdinPath {
+		src.name = ""
+		src.Reader = s.stdin
+	} else {
+		f, err := s.fs.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open file: %v", err)
+		}
+		file = f
+		src.Reader = f
+		src.closers = append(src.closers, f)
+		if info, err := f.Stat(); err == nil {
+			src.size = info.Size()
+		}
+	}
+
+	if count != nil {
+		src.Reader = countingReader{r: src.Reader, count: count}
+	}
+
+	br := bufio.NewReader(src.Reader)
+	src.Reader = br
+
+	// the compression is detected by the content, so it also works for the stdin
+	magic, _ := br.Peek(len(magicZip))
+	switch {
+	case bytes.HasPrefix(magic, magicGzip):
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			src.Close()
+			return nil, fmt.Errorf("could not read gzip: %v", err)
+		}
+		src.Reader = gr
+		src.closers = append(src.closers, gr)
+		src.name = trimExt(src.name, ".gz")
+		if src.name == "" {
+			src.name = gr.Name
+		}
+	case bytes.HasPrefix(magic, magicBzip2):
+		src.Reader = bzip2.NewReader(br)
+		src.name = trimExt(src.name, ".bz2")
+	case bytes.HasPrefix(magic, magicZip):
+		var ra io.ReaderAt = file
+		size := src.size
+		if file == nil {
+			spooled, n, err := s.spoolZip(src, br)
+			if err != nil {
+				src.Close()
+				return nil, err
+			}
+			ra, size = spooled, n
+		} else if count != nil {
+			// the zip is read from the file again, so the bytes buffered by the peek are not counted
+			atomic.StoreInt64(count, 0)
+			ra = countingReaderAt{r: file, count: count}
+		}
+		if err := openZipEntry(src, ra, size, format); err != nil {
+			src.Close()
+			return nil, err
+		}
+	}
+
+	return src, nil
+}
+
+// spoolZip copies the zip read from r to a temporary file removed when src is closed, since the zip
+// needs random access, failing when it is larger than Options.MaxStdinZipSize
+func (s service) spoolZip(src *source, r io.Reader) (afero.File, int64, error) {
+	tmp, err := afero.TempFile(s.fs, "", "import-*.zip")
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not create a temporary file for the zip: %v", err)
+	}
+	src.closers = append(src.closers, removeOnClose{fs: s.fs, f: tmp})
+
+	limit := s.opts.MaxStdinZipSize
+	size, err := io.Copy(tmp, io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not read zip: %v", err)
+	}
+	if size > limit {
+		return nil, 0, fmt.Errorf("could not read zip: it is larger than %d bytes", limit)
+	}
+
+	return tmp, size, nil
+}
+
+// removeOnClose removes the file after closing it
+type removeOnClose struct {
+	fs afero.Fs
+	f  afero.File
+}
+
+func (r removeOnClose) Close() error {
+	err := r.f.Close()
+	if rerr := r.fs.Remove(r.f.Name()); rerr != nil && err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// openZipEntry replaces the src content by the first file inside the zip that could be imported,
+// when format is informed the first file using that format
+func openZipEntry(src *source, r io.ReaderAt, size int64, format string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("could not read zip: %v", err)
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		detected, err := FormatFromPath(f.Name)
+		if err != nil || (format != "" && !strings.EqualFold(format, detected)) {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("could not open %s inside zip: %v", f.Name, err)
+		}
+		src.Reader = rc
+		src.closers = append(src.closers, rc)
+		src.name = f.Name
+		return nil
+	}
+
+	return ErrNoSupportedFileInZip
+}
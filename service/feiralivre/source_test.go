@@ -0,0 +1,182 @@
+package feiralivre
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestServiceOpenSource(t *testing.T) {
+	content := "ID,LONG\n"
+
+	var gzipContent bytes.Buffer
+	gw := gzip.NewWriter(&gzipContent)
+	gw.Name = "feiras.csv"
+	gw.Write([]byte(content))
+	gw.Close()
+
+	// bzip2 of content, the standard library only implements the decompression
+	bzip2Content, _ := hex.DecodeString("425a6839314159265359a112691600000254000010000404a5a000219189a10c0849f396f8bb9229c28485089348b0")
+
+	var zipContent bytes.Buffer
+	zw := zip.NewWriter(&zipContent)
+	w, _ := zw.Create("README.txt")
+	w.Write([]byte("readme"))
+	w, _ = zw.Create("data/feiras.csv")
+	w.Write([]byte(content))
+	zw.Close()
+
+	var zipWithoutSupportedFile bytes.Buffer
+	zw = zip.NewWriter(&zipWithoutSupportedFile)
+	w, _ = zw.Create("README.txt")
+	w.Write([]byte("readme"))
+	zw.Close()
+
+	testCases := []struct {
+		name       string
+		inFiles    map[string][]byte
+		inStdin    []byte
+		inPath     string
+		inFormat   string
+		inLimit    int64
+		outName    string
+		outContent string
+		hasError   bool
+	}{
+		{
+			name:     "when the file does not exists",
+			inPath:   "/does-not-exists.csv",
+			hasError: true,
+		},
+		{
+			name:       "when the file is not compressed",
+			inFiles:    map[string][]byte{"/my.csv": []byte(content)},
+			inPath:     "/my.csv",
+			outName:    "/my.csv",
+			outContent: content,
+		},
+		{
+			name:       "when reading from the stdin",
+			inStdin:    []byte(content),
+			inPath:     StdinPath,
+			outName:    "",
+			outContent: content,
+		},
+		{
+			name:       "when the file is a gzip",
+			inFiles:    map[string][]byte{"/my.ndjson.gz": gzipContent.Bytes()},
+			inPath:     "/my.ndjson.gz",
+			outName:    "/my.ndjson",
+			outContent: content,
+		},
+		{
+			name:       "when the stdin is a gzip",
+			inStdin:    gzipContent.Bytes(),
+			inPath:     StdinPath,
+			outName:    "feiras.csv",
+			outContent: content,
+		},
+		{
+			name:       "when the file is a bzip2",
+			inFiles:    map[string][]byte{"/my.csv.bz2": bzip2Content},
+			inPath:     "/my.csv.bz2",
+			outName:    "/my.csv",
+			outContent: content,
+		},
+		{
+			name:       "when the file is a zip",
+			inFiles:    map[string][]byte{"/my.zip": zipContent.Bytes()},
+			inPath:     "/my.zip",
+			outName:    "data/feiras.csv",
+			outContent: content,
+		},
+		{
+			name:       "when the stdin is a zip",
+			inStdin:    zipContent.Bytes(),
+			inPath:     StdinPath,
+			outName:    "data/feiras.csv",
+			outContent: content,
+		},
+		{
+			name:     "when the stdin zip is larger than the limit",
+			inStdin:  zipContent.Bytes(),
+			inPath:   StdinPath,
+			inLimit:  int64(zipContent.Len() - 1),
+			hasError: true,
+		},
+		{
+			name:     "when the zip does not contain the format",
+			inFiles:  map[string][]byte{"/my.zip": zipContent.Bytes()},
+			inPath:   "/my.zip",
+			inFormat: FormatGeoJSON,
+			hasError: true,
+		},
+		{
+			name:     "when the zip does not contain a supported file",
+			inFiles:  map[string][]byte{"/my.zip": zipWithoutSupportedFile.Bytes()},
+			inPath:   "/my.zip",
+			hasError: true,
+		},
+		{
+			name:     "when the gzip is invalid",
+			inFiles:  map[string][]byte{"/my.csv.gz": {0x1f, 0x8b, 0x00}},
+			inPath:   "/my.csv.gz",
+			hasError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for path, content := range tc.inFiles {
+				afero.WriteFile(fs, path, content, 0644)
+			}
+			s := service{fs: fs, stdin: bytes.NewReader(tc.inStdin), opts: Options{MaxStdinZipSize: tc.inLimit}.normalize()}
+
+			src, err := s.openSource(tc.inPath, tc.inFormat, nil)
+			if tc.hasError {
+				if err == nil {
+					t.Error("was expecting an error, but returns nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("was not expecting an error, but returns: %v", err)
+			}
+			defer src.Close()
+
+			if src.name != tc.outName {
+				t.Errorf("was expecting the name %q, but returns %q", tc.outName, src.name)
+			}
+			res, err := ioutil.ReadAll(src)
+			if err != nil {
+				t.Errorf("could not read the source: %v", err)
+			}
+			if string(res) != tc.outContent {
+				t.Errorf("was expecting %q, but returns %q", tc.outContent, res)
+			}
+
+			// the zip read from the stdin is copied to a temporary file removed when closing
+			src.Close()
+			if files, _ := afero.Glob(fs, filepath.Join(os.TempDir(), "import-*.zip")); len(files) > 0 {
+				t.Errorf("was expecting the temporary files to be removed, but returns %v", files)
+			}
+		})
+	}
+}
+
+func TestTrimExt(t *testing.T) {
+	if res := trimExt("/my.csv.GZ", ".gz"); res != "/my.csv" {
+		t.Errorf("was expecting /my.csv, but returns %s", res)
+	}
+	if res := trimExt("/my.csv", ".gz"); res != "/my.csv" {
+		t.Errorf("was expecting /my.csv, but returns %s", res)
+	}
+}
@@ -0,0 +1,208 @@
+package feiralivre
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+// csvHeader is the header used by the DEINFO csv layout
+var csvHeader = []string{"ID", "LONG", "LAT", "SETCENS", "AREAP", "CODDIST", "DISTRITO", "CODSUBPREF", "SUBPREFE", "REGIAO5", "REGIAO8", "NOME_FEIRA", "REGISTRO", "LOGRADOURO", "NUMERO", "BAIRRO", "REFERENCIA"}
+
+// SinkWriter represents how a sink writer should be implemented
+type SinkWriter interface {
+	// Write writes one feiralivre
+	Write(entity.FeiraLivre) error
+	// Close writes what is needed to finish the content, it does not close the underlying writer
+	Close() error
+}
+
+// NewSinkWriter creates the SinkWriter for the format writing to w
+func NewSinkWriter(format string, w io.Writer) (SinkWriter, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	case FormatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case FormatGeoJSON:
+		return &geojsonWriter{w: w}, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type csvWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// parseFeiraLivreToCols creates the cols in the DEINFO layout, the inverse of parseColsToFeiraLivre
+func parseFeiraLivreToCols(fl entity.FeiraLivre) []string {
+	return []string{
+		strconv.Itoa(fl.ID),
+		formatFloat(fl.Longitude),
+		formatFloat(fl.Latitude),
+		strconv.Itoa(fl.SetorCensitario),
+		strconv.Itoa(fl.AreaPonderacao),
+		strconv.Itoa(fl.CodigoDistrito),
+		fl.Distrito,
+		strconv.Itoa(fl.CodigoSubprefeitura),
+		fl.Subprefeitura,
+		fl.Regiao5,
+		fl.Regiao8,
+		fl.NomeFeira,
+		fl.Registro,
+		fl.Logradouro,
+		fl.Numero,
+		fl.Bairro,
+		fl.Referencia,
+	}
+}
+
+func (cw *csvWriter) writeHeader() error {
+	if cw.wroteHeader {
+		return nil
+	}
+	cw.wroteHeader = true
+	return cw.w.Write(csvHeader)
+}
+
+// Write implements how to write a feiralivre in the DEINFO csv layout
+func (cw *csvWriter) Write(fl entity.FeiraLivre) error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	return cw.w.Write(parseFeiraLivreToCols(fl))
+}
+
+// Close implements how to finish the csv, the header is written even without rows
+func (cw *csvWriter) Close() error {
+	if err := cw.writeHeader(); err != nil {
+		return err
+	}
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+// Write implements how to write a feiralivre as an item of a json array
+func (jw *jsonWriter) Write(fl entity.FeiraLivre) error {
+	content, err := json.Marshal(fl)
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if jw.count == 0 {
+		sep = "[\n"
+	}
+	jw.count++
+
+	_, err = fmt.Fprintf(jw.w, "%s%s", sep, content)
+	return err
+}
+
+// Close implements how to finish the json array
+func (jw *jsonWriter) Close() error {
+	end := "\n]\n"
+	if jw.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(jw.w, end)
+	return err
+}
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+// Write implements how to write a feiralivre as a json line
+func (nw *ndjsonWriter) Write(fl entity.FeiraLivre) error {
+	return nw.enc.Encode(fl)
+}
+
+// Close implements how to finish the ndjson, nothing is needed
+func (nw *ndjsonWriter) Close() error {
+	return nil
+}
+
+type geojsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+// parseFeiraLivreToGeoJSONFeature creates a geojson feature, the inverse of parseGeoJSONFeature,
+// the coordinates are written in decimal degrees
+func parseFeiraLivreToGeoJSONFeature(fl entity.FeiraLivre) (map[string]interface{}, error) {
+	content, err := json.Marshal(fl)
+	if err != nil {
+		return nil, err
+	}
+
+	var properties map[string]interface{}
+	if err := json.Unmarshal(content, &properties); err != nil {
+		return nil, err
+	}
+	// the coordinates are kept only in the geometry
+	delete(properties, "latitude")
+	delete(properties, "longitude")
+
+	return map[string]interface{}{
+		"type": geojsonFeature,
+		"geometry": geojsonGeometry{
+			Type:        geojsonPoint,
+			Coordinates: []float64{entity.ToDegrees(fl.Longitude), entity.ToDegrees(fl.Latitude)},
+		},
+		"properties": properties,
+	}, nil
+}
+
+// Write implements how to write a feiralivre as a feature of a geojson FeatureCollection
+func (gw *geojsonWriter) Write(fl entity.FeiraLivre) error {
+	feature, err := parseFeiraLivreToGeoJSONFeature(fl)
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(feature)
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if gw.count == 0 {
+		sep = `{"type":"` + geojsonFeatureCollection + `","features":[` + "\n"
+	}
+	gw.count++
+
+	_, err = fmt.Fprintf(gw.w, "%s%s", sep, content)
+	return err
+}
+
+// Close implements how to finish the geojson FeatureCollection
+func (gw *geojsonWriter) Close() error {
+	end := "\n]}\n"
+	if gw.count == 0 {
+		end = `{"type":"` + geojsonFeatureCollection + `","features":[]}` + "\n"
+	}
+	_, err := io.WriteString(gw.w, end)
+	return err
+}
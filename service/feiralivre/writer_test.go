@@ -0,0 +1,185 @@
+package feiralivre
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bgildson/unico-challenge/entity"
+)
+
+func TestSinkWriters(t *testing.T) {
+	fl := entity.FeiraLivre{
+		ID:                  1,
+		Latitude:            -23568390,
+		Longitude:           -46548146,
+		SetorCensitario:     355030885000019,
+		AreaPonderacao:      3550308005040,
+		CodigoDistrito:      87,
+		Distrito:            "VILA FORMOSA",
+		CodigoSubprefeitura: 26,
+		Subprefeitura:       "ARICANDUVA",
+		Regiao5:             "Leste",
+		Regiao8:             "Leste 1",
+		NomeFeira:           "PRAÇA LEÃO X",
+		Registro:            "7216-8",
+		Logradouro:          "RUA CODAJÁS, 45",
+		Numero:              "45",
+		Bairro:              "VILA FORMOSA",
+		Referencia:          "PRAÇA \"MARECHAL\" LEITE BANDEIRA",
+	}
+	withTimestamps := fl
+	withTimestamps.CreatedAt = time.Date(2021, 7, 27, 0, 0, 0, 0, time.UTC)
+	withTimestamps.UpdatedAt = time.Date(2021, 7, 28, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name   string
+		format string
+		in     []entity.FeiraLivre
+		// the csv layout does not have the timestamps
+		out []entity.FeiraLivre
+	}{
+		{
+			name:   "when csv is empty",
+			format: FormatCSV,
+		},
+		{
+			name:   "when csv",
+			format: FormatCSV,
+			in:     []entity.FeiraLivre{withTimestamps, fl},
+			out:    []entity.FeiraLivre{fl, fl},
+		},
+		{
+			name:   "when json is empty",
+			format: FormatJSON,
+		},
+		{
+			name:   "when json",
+			format: FormatJSON,
+			in:     []entity.FeiraLivre{withTimestamps, fl},
+			out:    []entity.FeiraLivre{withTimestamps, fl},
+		},
+		{
+			name:   "when ndjson is empty",
+			format: FormatNDJSON,
+		},
+		{
+			name:   "when ndjson",
+			format: FormatNDJSON,
+			in:     []entity.FeiraLivre{withTimestamps, fl},
+			out:    []entity.FeiraLivre{withTimestamps, fl},
+		},
+		{
+			name:   "when geojson is empty",
+			format: FormatGeoJSON,
+		},
+		{
+			name:   "when geojson",
+			format: FormatGeoJSON,
+			in:     []entity.FeiraLivre{withTimestamps, fl},
+			out:    []entity.FeiraLivre{withTimestamps, fl},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var content bytes.Buffer
+			writer, err := NewSinkWriter(tc.format, &content)
+			if err != nil {
+				t.Fatalf("was not expecting an error, but returns: %v", err)
+			}
+			for _, fl := range tc.in {
+				if err := writer.Write(fl); err != nil {
+					t.Fatalf("was not expecting an error, but returns: %v", err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("was not expecting an error, but returns: %v", err)
+			}
+
+			// what was written must be read back by the reader of the same format
+			reader, _ := NewSourceReader(tc.format)
+			flChan := make(chan *entity.FeiraLivre)
+			errChan := make(chan error)
+			s := service{}
+			go s.read(&content, reader, flChan, errChan)
+
+			var fls []entity.FeiraLivre
+			var errs []error
+			for flChan != nil || errChan != nil {
+				select {
+				case fl, ok := <-flChan:
+					if !ok {
+						flChan = nil
+						continue
+					}
+					fls = append(fls, *fl)
+				case err, ok := <-errChan:
+					if !ok {
+						errChan = nil
+						continue
+					}
+					errs = append(errs, err)
+				}
+			}
+
+			if len(errs) > 0 {
+				t.Errorf("was not expecting errors, but returns %+v", errs)
+			}
+			if !reflect.DeepEqual(tc.out, fls) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, fls)
+			}
+		})
+	}
+}
+
+func TestGeoJSONWriterCoordinates(t *testing.T) {
+	fl := entity.FeiraLivre{ID: 1, Longitude: -46548146, Latitude: -23568390, NomeFeira: "PRAÇA LEÃO X"}
+
+	var content bytes.Buffer
+	writer, _ := NewSinkWriter(FormatGeoJSON, &content)
+	if err := writer.Write(fl); err != nil {
+		t.Fatalf("was not expecting an error, but returns: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("was not expecting an error, but returns: %v", err)
+	}
+
+	var collection struct {
+		Features []geojsonFeatureItem `json:"features"`
+	}
+	if err := json.Unmarshal(content.Bytes(), &collection); err != nil {
+		t.Fatalf("could not decode the geojson: %v", err)
+	}
+	if len(collection.Features) != 1 {
+		t.Fatalf("was expecting 1 feature, but returns %d", len(collection.Features))
+	}
+	// the positions are WGS84 decimal degrees, as [longitude, latitude]
+	out := []float64{-46.548146, -23.56839}
+	if coordinates := collection.Features[0].Geometry.Coordinates; !reflect.DeepEqual(coordinates, out) {
+		t.Errorf("was expecting %v, but returns %v", out, coordinates)
+	}
+
+	read, err := parseGeoJSONFeature(mustMarshal(t, collection.Features[0]))
+	if err != nil {
+		t.Fatalf("was not expecting an error, but returns: %v", err)
+	}
+	if read.Longitude != fl.Longitude || read.Latitude != fl.Latitude {
+		t.Errorf("was expecting %v, %v, but returns %v, %v", fl.Longitude, fl.Latitude, read.Longitude, read.Latitude)
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	content, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("could not encode %+v: %v", v, err)
+	}
+	return content
+}
+
+func TestNewSinkWriter(t *testing.T) {
+	if _, err := NewSinkWriter("xml", &bytes.Buffer{}); err != ErrUnknownFormat {
+		t.Errorf("was expecting %v, but returns %v", ErrUnknownFormat, err)
+	}
+}
@@ -0,0 +1,107 @@
+package grant
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/repository/grant"
+)
+
+var (
+	// ErrInvalidGrant is used when creating a grant without subject or with a scope that is not restricted by area
+	ErrInvalidGrant = errors.New("invalid grant")
+	// ErrGrantNotFound is used when removing a grant that does not exist
+	ErrGrantNotFound = errors.New("grant not found")
+)
+
+// Scopes lists the scopes restricted by area, the other scopes are not affected by the grants
+var Scopes = []string{entity.ScopeWrite, entity.ScopeImport}
+
+// Service represents how a grant service should be implemented
+type Service interface {
+	Create(ctx context.Context, g entity.Grant) (*entity.Grant, error)
+	List(ctx context.Context) ([]entity.Grant, error)
+	Remove(ctx context.Context, id int) error
+	Areas(ctx context.Context, principal *entity.Principal, scope string) (entity.Areas, error)
+}
+
+type service struct {
+	repo grant.Repository
+}
+
+// New creates a service for grant
+func New(repo grant.Repository) Service {
+	return &service{
+		repo: repo,
+	}
+}
+
+// Create implements how to create a grant
+func (s service) Create(ctx context.Context, g entity.Grant) (*entity.Grant, error) {
+	g.Subject = strings.TrimSpace(g.Subject)
+	g.Regiao5 = strings.TrimSpace(g.Regiao5)
+	if g.Subject == "" || g.Subject == entity.RolePrefix {
+		return nil, fmt.Errorf("%w: the subject is required", ErrInvalidGrant)
+	}
+	if !restricted(g.Scope) {
+		return nil, fmt.Errorf("%w: the scope must be one of %s", ErrInvalidGrant, strings.Join(Scopes, ", "))
+	}
+	if g.CodigoSubprefeitura < 0 {
+		return nil, fmt.Errorf("%w: the codigo_subprefeitura could not be negative", ErrInvalidGrant)
+	}
+
+	created, err := s.repo.Create(ctx, g)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the grant: %v", err)
+	}
+
+	return created, nil
+}
+
+// List implements how to list every grant
+func (s service) List(ctx context.Context) ([]entity.Grant, error) {
+	return s.repo.List(ctx)
+}
+
+// Remove implements how to remove a grant
+func (s service) Remove(ctx context.Context, id int) error {
+	err := s.repo.Remove(ctx, id)
+	if err == sql.ErrNoRows {
+		return ErrGrantNotFound
+	}
+	return err
+}
+
+// Areas implements where the principal could use the scope, the admins, the unauthenticated
+// requests (when the authentication is disabled) and the scopes not restricted by area are
+// allowed everywhere, the others only where a grant of their subject or roles allows
+func (s service) Areas(ctx context.Context, principal *entity.Principal, scope string) (entity.Areas, error) {
+	if principal == nil || !restricted(scope) || principal.HasScope(entity.ScopeAdmin) {
+		return entity.AllAreas, nil
+	}
+
+	grants, err := s.repo.ListBySubjects(ctx, principal.Subjects(), scope)
+	if err != nil {
+		return entity.Areas{}, fmt.Errorf("could not list the grants of %s: %v", principal.Subject, err)
+	}
+	for _, g := range grants {
+		if g.Unrestricted() {
+			return entity.AllAreas, nil
+		}
+	}
+
+	return entity.Areas{Grants: grants}, nil
+}
+
+func restricted(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
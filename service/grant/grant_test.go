@@ -0,0 +1,246 @@
+package grant
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/repository/grant"
+)
+
+func TestServiceCreate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setupMocks func(repo *grant.MockRepository)
+		in         entity.Grant
+		out        *entity.Grant
+		outErr     error
+		hasError   bool
+	}{
+		{
+			name:       "when subject is empty",
+			setupMocks: func(repo *grant.MockRepository) {},
+			in:         entity.Grant{Subject: " ", Scope: entity.ScopeWrite},
+			outErr:     ErrInvalidGrant,
+		},
+		{
+			name:       "when scope is not restricted by area",
+			setupMocks: func(repo *grant.MockRepository) {},
+			in:         entity.Grant{Subject: "maria", Scope: entity.ScopeRead},
+			outErr:     ErrInvalidGrant,
+		},
+		{
+			name:       "when codigo_subprefeitura is negative",
+			setupMocks: func(repo *grant.MockRepository) {},
+			in:         entity.Grant{Subject: "maria", Scope: entity.ScopeWrite, CodigoSubprefeitura: -1},
+			outErr:     ErrInvalidGrant,
+		},
+		{
+			name: "when repository returns an error",
+			setupMocks: func(repo *grant.MockRepository) {
+				repo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil, errors.New("unexpected error"))
+			},
+			in:       entity.Grant{Subject: "maria", Scope: entity.ScopeWrite},
+			hasError: true,
+		},
+		{
+			name: "when success",
+			setupMocks: func(repo *grant.MockRepository) {
+				repo.
+					EXPECT().
+					Create(gomock.Any(), entity.Grant{Subject: "role:leste", Scope: entity.ScopeImport, Regiao5: "Leste"}).
+					Return(&entity.Grant{ID: 1, Subject: "role:leste", Scope: entity.ScopeImport, Regiao5: "Leste"}, nil)
+			},
+			in:  entity.Grant{Subject: " role:leste ", Scope: entity.ScopeImport, Regiao5: " Leste "},
+			out: &entity.Grant{ID: 1, Subject: "role:leste", Scope: entity.ScopeImport, Regiao5: "Leste"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := grant.NewMockRepository(ctrl)
+			tc.setupMocks(repo)
+
+			s := New(repo)
+
+			res, err := s.Create(context.Background(), tc.in)
+			if tc.outErr != nil && !errors.Is(err, tc.outErr) {
+				t.Errorf("was expecting %v, but returns %v", tc.outErr, err)
+			}
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if tc.outErr == nil && !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestServiceRemove(t *testing.T) {
+	testCases := []struct {
+		name       string
+		setupMocks func(repo *grant.MockRepository)
+		outErr     error
+	}{
+		{
+			name: "when does not exist",
+			setupMocks: func(repo *grant.MockRepository) {
+				repo.EXPECT().Remove(gomock.Any(), 1).Return(sql.ErrNoRows)
+			},
+			outErr: ErrGrantNotFound,
+		},
+		{
+			name: "when repository returns an error",
+			setupMocks: func(repo *grant.MockRepository) {
+				repo.EXPECT().Remove(gomock.Any(), 1).Return(sql.ErrConnDone)
+			},
+			outErr: sql.ErrConnDone,
+		},
+		{
+			name: "when success",
+			setupMocks: func(repo *grant.MockRepository) {
+				repo.EXPECT().Remove(gomock.Any(), 1).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := grant.NewMockRepository(ctrl)
+			tc.setupMocks(repo)
+
+			if err := New(repo).Remove(context.Background(), 1); err != tc.outErr {
+				t.Errorf("was expecting %v, but returns %v", tc.outErr, err)
+			}
+		})
+	}
+}
+
+func TestServiceAreas(t *testing.T) {
+	editor := &entity.Principal{Subject: "maria", Scopes: []string{entity.ScopeWrite}, Roles: []string{"sub-aricanduva"}}
+	subjects := []string{"maria", "role:sub-aricanduva"}
+	aricanduva := entity.Grant{ID: 1, Subject: "role:sub-aricanduva", Scope: entity.ScopeWrite, CodigoSubprefeitura: 26}
+	testCases := []struct {
+		name       string
+		setupMocks func(repo *grant.MockRepository)
+		principal  *entity.Principal
+		scope      string
+		out        entity.Areas
+		hasError   bool
+	}{
+		{
+			name:       "when the request is not authenticated",
+			setupMocks: func(repo *grant.MockRepository) {},
+			scope:      entity.ScopeWrite,
+			out:        entity.AllAreas,
+		},
+		{
+			name:       "when principal is admin",
+			setupMocks: func(repo *grant.MockRepository) {},
+			principal:  &entity.Principal{Subject: "root", Scopes: []string{entity.ScopeAdmin}},
+			scope:      entity.ScopeWrite,
+			out:        entity.AllAreas,
+		},
+		{
+			name:       "when scope is not restricted by area",
+			setupMocks: func(repo *grant.MockRepository) {},
+			principal:  editor,
+			scope:      entity.ScopeRead,
+			out:        entity.AllAreas,
+		},
+		{
+			name: "when repository returns an error",
+			setupMocks: func(repo *grant.MockRepository) {
+				repo.EXPECT().ListBySubjects(gomock.Any(), subjects, entity.ScopeWrite).Return(nil, sql.ErrConnDone)
+			},
+			principal: editor,
+			scope:     entity.ScopeWrite,
+			hasError:  true,
+		},
+		{
+			name: "when there is no grant",
+			setupMocks: func(repo *grant.MockRepository) {
+				repo.EXPECT().ListBySubjects(gomock.Any(), subjects, entity.ScopeWrite).Return([]entity.Grant{}, nil)
+			},
+			principal: editor,
+			scope:     entity.ScopeWrite,
+			out:       entity.Areas{Grants: []entity.Grant{}},
+		},
+		{
+			name: "when a grant is unrestricted",
+			setupMocks: func(repo *grant.MockRepository) {
+				repo.
+					EXPECT().
+					ListBySubjects(gomock.Any(), subjects, entity.ScopeWrite).
+					Return([]entity.Grant{aricanduva, {ID: 2, Subject: "maria", Scope: entity.ScopeWrite}}, nil)
+			},
+			principal: editor,
+			scope:     entity.ScopeWrite,
+			out:       entity.AllAreas,
+		},
+		{
+			name: "when the grants are restricted",
+			setupMocks: func(repo *grant.MockRepository) {
+				repo.EXPECT().ListBySubjects(gomock.Any(), subjects, entity.ScopeWrite).Return([]entity.Grant{aricanduva}, nil)
+			},
+			principal: editor,
+			scope:     entity.ScopeWrite,
+			out:       entity.Areas{Grants: []entity.Grant{aricanduva}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := grant.NewMockRepository(ctrl)
+			tc.setupMocks(repo)
+
+			res, err := New(repo).Areas(context.Background(), tc.principal, tc.scope)
+			if tc.hasError && err == nil {
+				t.Errorf("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns %v", err)
+			}
+			if !reflect.DeepEqual(tc.out, res) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.out, res)
+			}
+		})
+	}
+}
+
+func TestAreasCovers(t *testing.T) {
+	fl := entity.FeiraLivre{CodigoSubprefeitura: 26, Regiao5: "Leste"}
+	testCases := []struct {
+		name string
+		in   entity.Areas
+		out  bool
+	}{
+		{name: "when every area is allowed", in: entity.AllAreas, out: true},
+		{name: "when there is no grant", in: entity.Areas{}, out: false},
+		{name: "when subprefeitura matches", in: entity.Areas{Grants: []entity.Grant{{CodigoSubprefeitura: 26}}}, out: true},
+		{name: "when subprefeitura does not match", in: entity.Areas{Grants: []entity.Grant{{CodigoSubprefeitura: 25}}}, out: false},
+		{name: "when regiao5 matches ignoring the case", in: entity.Areas{Grants: []entity.Grant{{Regiao5: "leste"}}}, out: true},
+		{name: "when regiao5 does not match", in: entity.Areas{Grants: []entity.Grant{{Regiao5: "Oeste"}}}, out: false},
+		{name: "when both must match", in: entity.Areas{Grants: []entity.Grant{{CodigoSubprefeitura: 26, Regiao5: "Oeste"}}}, out: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if res := tc.in.Covers(fl); res != tc.out {
+				t.Errorf("was expecting %v, but returns %v", tc.out, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package importjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/repository/importjob"
+	feiralivreServ "github.com/bgildson/unico-challenge/service/feiralivre"
+)
+
+// ErrImportJobNotRunning is used when trying to cancel an importjob that is not running
+var ErrImportJobNotRunning = errors.New("the import job is not running")
+
+const (
+	// HeartbeatInterval is how often a running importjob records that it is still alive
+	HeartbeatInterval = 30 * time.Second
+	// StaleAfter is how long an unfinished importjob could go without a heartbeat before it is
+	// considered left by an instance that stopped, the jobs of the other instances are kept
+	StaleAfter = 3 * HeartbeatInterval
+	// DefaultMaxJobs is the number of importjob running at the same time when maxJobs is not informed
+	DefaultMaxJobs = 2
+)
+
+// Service represents how an importjob service should be implemented
+type Service interface {
+	Create(filename, format string, areas entity.Areas, actor string, content io.Reader) (*entity.ImportJob, error)
+	GetByID(id int) (*entity.ImportJob, error)
+	Cancel(id int) (*entity.ImportJob, error)
+	InterruptUnfinished() (int64, error)
+	Wait(ctx context.Context) error
+}
+
+type service struct {
+	ctx            context.Context
+	fs             afero.Fs
+	dir            string
+	repo           importjob.Repository
+	feiralivreServ feiralivreServ.Service
+	mu             sync.Mutex
+	cancels        map[int]context.CancelFunc
+	wg             sync.WaitGroup
+	heartbeat      time.Duration
+	slots          chan struct{}
+}
+
+// New creates a service for importjob, the uploaded files are kept in dir until the import finishes,
+// at most maxJobs run at the same time and the others wait as pending, the running importjob are
+// interrupted when ctx is done
+func New(ctx context.Context, fs afero.Fs, dir string, maxJobs int, repo importjob.Repository, feiralivreServ feiralivreServ.Service) Service {
+	if maxJobs < 1 {
+		maxJobs = DefaultMaxJobs
+	}
+
+	return &service{
+		ctx:            ctx,
+		fs:             fs,
+		dir:            dir,
+		repo:           repo,
+		feiralivreServ: feiralivreServ,
+		cancels:        map[int]context.CancelFunc{},
+		heartbeat:      HeartbeatInterval,
+		slots:          make(chan struct{}, maxJobs),
+	}
+}
+
+// Create implements how to create an importjob, the content is stored and imported in background
+// and only the registers in the areas are persisted, recorded as created or updated by actor
+func (s *service) Create(filename, format string, areas entity.Areas, actor string, content io.Reader) (*entity.ImportJob, error) {
+	job, err := s.repo.Create(entity.ImportJob{
+		Status:    entity.ImportJobPending,
+		Filename:  filepath.Base(filename),
+		Format:    format,
+		CreatedBy: actor,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create import job: %v", err)
+	}
+
+	path := filepath.Join(s.dir, fmt.Sprintf("import-job-%d-%s", job.ID, job.Filename))
+	if err := afero.WriteReader(s.fs, path, content); err != nil {
+		s.finish(*job, feiralivreServ.Progress{}, fmt.Errorf("could not store the file: %v", err))
+		return nil, fmt.Errorf("could not store the file of import job %d: %v", job.ID, err)
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.mu.Lock()
+	s.cancels[job.ID] = cancel
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.run(ctx, cancel, *job, path, areas, actor)
+
+	return job, nil
+}
+
+// apply copies the progress counters to the job
+func apply(job *entity.ImportJob, p feiralivreServ.Progress) {
+	job.RowsRead = p.Read
+	job.RowsPersisted = p.Persisted
+	job.RowsFailed = p.Failed
+	job.Errors = p.Errors
+}
+
+// finish saves the final state of the job based on the import result
+func (s *service) finish(job entity.ImportJob, p feiralivreServ.Progress, err error) {
+	apply(&job, p)
+
+	now := time.Now()
+	job.FinishedAt = &now
+
+	switch {
+	case errors.Is(err, context.Canceled) && s.ctx.Err() != nil:
+		// the service stopped, not the user
+		job.Status = entity.ImportJobInterrupted
+	case errors.Is(err, context.Canceled):
+		job.Status = entity.ImportJobCanceled
+	case errors.Is(err, feiralivreServ.ErrCouldNotSyncPK):
+		// the registers were imported, only the sequence is behind
+		job.Status = entity.ImportJobDone
+		job.Errors = append(job.Errors, err.Error())
+	case err != nil:
+		job.Status = entity.ImportJobFailed
+		job.Errors = append(job.Errors, err.Error())
+	default:
+		job.Status = entity.ImportJobDone
+	}
+
+	if _, err := s.repo.Update(job); err != nil {
+		logrus.Errorf("could not finish import job %d: %v", job.ID, err)
+	}
+}
+
+func (s *service) run(ctx context.Context, cancel context.CancelFunc, job entity.ImportJob, path string, areas entity.Areas, actor string) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		if cancel, ok := s.cancels[job.ID]; ok {
+			cancel()
+			delete(s.cancels, job.ID)
+		}
+		s.mu.Unlock()
+	}()
+	defer s.fs.Remove(path)
+
+	done := make(chan struct{})
+	defer close(done)
+	go s.beat(job.ID, cancel, done)
+
+	// the waiting job keeps its heartbeat and could be canceled before it starts
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		s.finish(job, feiralivreServ.Progress{}, ctx.Err())
+		return
+	}
+
+	now := time.Now()
+	job.Status = entity.ImportJobRunning
+	job.StartedAt = &now
+	if _, err := s.repo.Update(job); err != nil {
+		logrus.Errorf("could not start import job %d: %v", job.ID, err)
+	}
+
+	p, err := s.feiralivreServ.ImportWithProgress(ctx, path, job.Format, areas, actor, func(p feiralivreServ.Progress) {
+		if p.Done {
+			return
+		}
+		running := job
+		apply(&running, p)
+		if _, err := s.repo.Update(running); err != nil {
+			logrus.Errorf("could not update the progress of import job %d: %v", job.ID, err)
+		}
+	})
+
+	s.finish(job, p, err)
+}
+
+// beat records that the importjob is alive every heartbeat until done is closed, so the other
+// instances do not interrupt it, and calls cancel when the cancel was requested to any instance
+func (s *service) beat(id int, cancel context.CancelFunc, done <-chan struct{}) {
+	ticker := time.NewTicker(s.heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			canceled, err := s.repo.Touch(id)
+			if err != nil {
+				logrus.Errorf("could not record the heartbeat of import job %d: %v", id, err)
+			}
+			if canceled {
+				cancel()
+			}
+		}
+	}
+}
+
+// GetByID implements how to get an importjob by id
+func (s *service) GetByID(id int) (*entity.ImportJob, error) {
+	return s.repo.GetByID(id)
+}
+
+// Cancel implements how to cancel a pending or running importjob, the cancel is recorded so the
+// instance running it stops on its next heartbeat, the job status changes when the import stops
+func (s *service) Cancel(id int) (*entity.ImportJob, error) {
+	job, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Finished() {
+		return job, ErrImportJobNotRunning
+	}
+
+	requested, err := s.repo.RequestCancel(id)
+	if err != nil {
+		return nil, fmt.Errorf("could not request the cancel of import job %d: %v", id, err)
+	}
+	if !requested {
+		// it finished after it was read
+		return job, ErrImportJobNotRunning
+	}
+
+	// the jobs of this instance do not wait the heartbeat
+	s.mu.Lock()
+	if cancel, ok := s.cancels[id]; ok {
+		cancel()
+	}
+	s.mu.Unlock()
+
+	return job, nil
+}
+
+// InterruptUnfinished implements how to mark the importjob left unfinished by a stopped instance,
+// the ones without a heartbeat for StaleAfter
+func (s *service) InterruptUnfinished() (int64, error) {
+	return s.repo.InterruptUnfinished(StaleAfter)
+}
+
+// Wait blocks until every importjob started by the service finishes or ctx is done
+func (s *service) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
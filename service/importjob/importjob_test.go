@@ -0,0 +1,421 @@
+package importjob
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"github.com/bgildson/unico-challenge/entity"
+	"github.com/bgildson/unico-challenge/repository/importjob"
+	feiralivreServ "github.com/bgildson/unico-challenge/service/feiralivre"
+)
+
+// statusIs matches an importjob by status
+type statusIs string
+
+func (m statusIs) Matches(x interface{}) bool {
+	job, ok := x.(entity.ImportJob)
+	return ok && job.Status == string(m)
+}
+
+func (m statusIs) String() string {
+	return "has status " + string(m)
+}
+
+func TestServiceCreate(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	path := "/tmp/import-job-1-feiras.csv"
+	testCases := []struct {
+		name       string
+		setupMocks func(repo *importjob.MockRepository, serv *feiralivreServ.MockService)
+		outJob     *entity.ImportJob
+		outStatus  string
+		hasError   bool
+	}{
+		{
+			name: "when could not create the job",
+			setupMocks: func(repo *importjob.MockRepository, serv *feiralivreServ.MockService) {
+				repo.
+					EXPECT().
+					Create(entity.ImportJob{Status: entity.ImportJobPending, Filename: "feiras.csv", Format: "csv"}).
+					Return(nil, errors.New("unexpected error"))
+			},
+			hasError: true,
+		},
+		{
+			name: "when the import succeeds",
+			setupMocks: func(repo *importjob.MockRepository, serv *feiralivreServ.MockService) {
+				repo.
+					EXPECT().
+					Create(entity.ImportJob{Status: entity.ImportJobPending, Filename: "feiras.csv", Format: "csv"}).
+					Return(&entity.ImportJob{ID: 1, Status: entity.ImportJobPending, Filename: "feiras.csv", Format: "csv"}, nil)
+				gomock.InOrder(
+					repo.EXPECT().Update(statusIs(entity.ImportJobRunning)).Return(&entity.ImportJob{}, nil),
+					repo.EXPECT().Update(statusIs(entity.ImportJobRunning)).Return(&entity.ImportJob{}, nil),
+					repo.EXPECT().Update(statusIs(entity.ImportJobDone)).Return(&entity.ImportJob{}, nil),
+				)
+				serv.
+					EXPECT().
+					ImportWithProgress(gomock.Any(), path, "csv", entity.AllAreas, "", gomock.Any()).
+					DoAndReturn(func(ctx context.Context, path, format string, _ entity.Areas, _ string, progress feiralivreServ.ProgressFunc) (feiralivreServ.Progress, error) {
+						progress(feiralivreServ.Progress{Read: 1})
+						progress(feiralivreServ.Progress{Read: 1, Persisted: 1, Done: true})
+						return feiralivreServ.Progress{Read: 1, Persisted: 1, Done: true}, nil
+					})
+			},
+			outJob: &entity.ImportJob{ID: 1, Status: entity.ImportJobPending, Filename: "feiras.csv", Format: "csv"},
+		},
+		{
+			name: "when the import fails",
+			setupMocks: func(repo *importjob.MockRepository, serv *feiralivreServ.MockService) {
+				repo.
+					EXPECT().
+					Create(entity.ImportJob{Status: entity.ImportJobPending, Filename: "feiras.csv", Format: "csv"}).
+					Return(&entity.ImportJob{ID: 1, Status: entity.ImportJobPending, Filename: "feiras.csv", Format: "csv"}, nil)
+				gomock.InOrder(
+					repo.EXPECT().Update(statusIs(entity.ImportJobRunning)).Return(&entity.ImportJob{}, nil),
+					repo.EXPECT().Update(statusIs(entity.ImportJobFailed)).Return(&entity.ImportJob{}, nil),
+				)
+				serv.
+					EXPECT().
+					ImportWithProgress(gomock.Any(), path, "csv", entity.AllAreas, "", gomock.Any()).
+					Return(feiralivreServ.Progress{}, errors.New("unexpected error"))
+			},
+			outJob: &entity.ImportJob{ID: 1, Status: entity.ImportJobPending, Filename: "feiras.csv", Format: "csv"},
+		},
+		{
+			name: "when the pk could not be synced",
+			setupMocks: func(repo *importjob.MockRepository, serv *feiralivreServ.MockService) {
+				repo.
+					EXPECT().
+					Create(entity.ImportJob{Status: entity.ImportJobPending, Filename: "feiras.csv", Format: "csv"}).
+					Return(&entity.ImportJob{ID: 1, Status: entity.ImportJobPending, Filename: "feiras.csv", Format: "csv"}, nil)
+				gomock.InOrder(
+					repo.EXPECT().Update(statusIs(entity.ImportJobRunning)).Return(&entity.ImportJob{}, nil),
+					repo.EXPECT().Update(statusIs(entity.ImportJobDone)).Return(&entity.ImportJob{}, nil),
+				)
+				serv.
+					EXPECT().
+					ImportWithProgress(gomock.Any(), path, "csv", entity.AllAreas, "", gomock.Any()).
+					Return(feiralivreServ.Progress{}, feiralivreServ.ErrCouldNotSyncPK)
+			},
+			outJob: &entity.ImportJob{ID: 1, Status: entity.ImportJobPending, Filename: "feiras.csv", Format: "csv"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := importjob.NewMockRepository(ctrl)
+			serv := feiralivreServ.NewMockService(ctrl)
+			tc.setupMocks(repo, serv)
+			fs := afero.NewMemMapFs()
+			s := New(context.Background(), fs, "/tmp", 0, repo, serv)
+
+			res, err := s.Create("/uploads/feiras.csv", "csv", entity.AllAreas, "", strings.NewReader("content"))
+			s.Wait(context.Background())
+
+			if tc.hasError && err == nil {
+				t.Error("was expecting an error, but returns nil")
+			}
+			if !tc.hasError && err != nil {
+				t.Errorf("was not expecting an error, but returns: %v", err)
+			}
+			if tc.outJob != nil && (res == nil || res.ID != tc.outJob.ID || res.Status != tc.outJob.Status) {
+				t.Errorf("was expecting %+v, but returns %+v", tc.outJob, res)
+			}
+			if exists, _ := afero.Exists(fs, path); exists {
+				t.Errorf("was expecting the file %s to be removed", path)
+			}
+		})
+	}
+}
+
+func TestServiceCancel(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+
+	t.Run("when does not exist", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := importjob.NewMockRepository(ctrl)
+		repo.EXPECT().GetByID(1).Return(nil, sql.ErrNoRows)
+		s := New(context.Background(), afero.NewMemMapFs(), "/tmp", 0, repo, nil)
+
+		if _, err := s.Cancel(1); err != sql.ErrNoRows {
+			t.Errorf("was expecting %v, but returns %v", sql.ErrNoRows, err)
+		}
+	})
+
+	t.Run("when is not running", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := importjob.NewMockRepository(ctrl)
+		repo.EXPECT().GetByID(1).Return(&entity.ImportJob{ID: 1, Status: entity.ImportJobDone}, nil)
+		s := New(context.Background(), afero.NewMemMapFs(), "/tmp", 0, repo, nil)
+
+		if _, err := s.Cancel(1); err != ErrImportJobNotRunning {
+			t.Errorf("was expecting %v, but returns %v", ErrImportJobNotRunning, err)
+		}
+	})
+
+	t.Run("when finishes before the cancel is requested", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := importjob.NewMockRepository(ctrl)
+		repo.EXPECT().GetByID(1).Return(&entity.ImportJob{ID: 1, Status: entity.ImportJobRunning}, nil)
+		repo.EXPECT().RequestCancel(1).Return(false, nil)
+		s := New(context.Background(), afero.NewMemMapFs(), "/tmp", 0, repo, nil)
+
+		if _, err := s.Cancel(1); err != ErrImportJobNotRunning {
+			t.Errorf("was expecting %v, but returns %v", ErrImportJobNotRunning, err)
+		}
+	})
+
+	t.Run("when is running in another instance", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := importjob.NewMockRepository(ctrl)
+		repo.EXPECT().GetByID(1).Return(&entity.ImportJob{ID: 1, Status: entity.ImportJobRunning}, nil)
+		repo.EXPECT().RequestCancel(1).Return(true, nil)
+		s := New(context.Background(), afero.NewMemMapFs(), "/tmp", 0, repo, nil)
+
+		if _, err := s.Cancel(1); err != nil {
+			t.Errorf("was not expecting an error, but returns: %v", err)
+		}
+	})
+
+	t.Run("when is running", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := importjob.NewMockRepository(ctrl)
+		serv := feiralivreServ.NewMockService(ctrl)
+		started := make(chan struct{})
+		repo.
+			EXPECT().
+			Create(gomock.Any()).
+			Return(&entity.ImportJob{ID: 1, Status: entity.ImportJobPending, Filename: "feiras.csv"}, nil)
+		repo.
+			EXPECT().
+			GetByID(1).
+			Return(&entity.ImportJob{ID: 1, Status: entity.ImportJobRunning}, nil)
+		repo.EXPECT().RequestCancel(1).Return(true, nil)
+		gomock.InOrder(
+			repo.EXPECT().Update(statusIs(entity.ImportJobRunning)).Return(&entity.ImportJob{}, nil),
+			repo.EXPECT().Update(statusIs(entity.ImportJobCanceled)).Return(&entity.ImportJob{}, nil),
+		)
+		serv.
+			EXPECT().
+			ImportWithProgress(gomock.Any(), gomock.Any(), "", entity.AllAreas, "", gomock.Any()).
+			DoAndReturn(func(ctx context.Context, path, format string, _ entity.Areas, _ string, progress feiralivreServ.ProgressFunc) (feiralivreServ.Progress, error) {
+				close(started)
+				<-ctx.Done()
+				return feiralivreServ.Progress{}, ctx.Err()
+			})
+		s := New(context.Background(), afero.NewMemMapFs(), "/tmp", 0, repo, serv)
+
+		if _, err := s.Create("feiras.csv", "", entity.AllAreas, "", strings.NewReader("content")); err != nil {
+			t.Fatalf("was not expecting an error, but returns: %v", err)
+		}
+		<-started
+
+		if _, err := s.Cancel(1); err != nil {
+			t.Errorf("was not expecting an error, but returns: %v", err)
+		}
+		s.Wait(context.Background())
+	})
+}
+
+func TestServiceMaxJobs(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	ctrl := gomock.NewController(t)
+	repo := importjob.NewMockRepository(ctrl)
+	serv := feiralivreServ.NewMockService(ctrl)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	gomock.InOrder(
+		repo.EXPECT().Create(gomock.Any()).Return(&entity.ImportJob{ID: 1, Status: entity.ImportJobPending, Filename: "first.csv"}, nil),
+		repo.EXPECT().Create(gomock.Any()).Return(&entity.ImportJob{ID: 2, Status: entity.ImportJobPending, Filename: "second.csv"}, nil),
+	)
+	repo.EXPECT().GetByID(2).Return(&entity.ImportJob{ID: 2, Status: entity.ImportJobPending}, nil)
+	repo.EXPECT().RequestCancel(2).Return(true, nil)
+	// only the first job starts, the second is canceled while waiting
+	repo.EXPECT().Update(statusIs(entity.ImportJobRunning)).Return(&entity.ImportJob{}, nil)
+	repo.EXPECT().Update(statusIs(entity.ImportJobCanceled)).Return(&entity.ImportJob{}, nil)
+	repo.EXPECT().Update(statusIs(entity.ImportJobDone)).Return(&entity.ImportJob{}, nil)
+	serv.
+		EXPECT().
+		ImportWithProgress(gomock.Any(), gomock.Any(), "", entity.AllAreas, "", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, path, format string, _ entity.Areas, _ string, progress feiralivreServ.ProgressFunc) (feiralivreServ.Progress, error) {
+			close(started)
+			<-release
+			return feiralivreServ.Progress{Done: true}, nil
+		})
+	s := New(context.Background(), afero.NewMemMapFs(), "/tmp", 1, repo, serv)
+
+	if _, err := s.Create("first.csv", "", entity.AllAreas, "", strings.NewReader("content")); err != nil {
+		t.Fatalf("was not expecting an error, but returns: %v", err)
+	}
+	<-started
+	if _, err := s.Create("second.csv", "", entity.AllAreas, "", strings.NewReader("content")); err != nil {
+		t.Fatalf("was not expecting an error, but returns: %v", err)
+	}
+
+	if _, err := s.Cancel(2); err != nil {
+		t.Errorf("was not expecting an error, but returns: %v", err)
+	}
+	close(release)
+	s.Wait(context.Background())
+}
+
+func TestServiceShutdown(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+
+	t.Run("when the running jobs are interrupted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := importjob.NewMockRepository(ctrl)
+		serv := feiralivreServ.NewMockService(ctrl)
+		started := make(chan struct{})
+		repo.
+			EXPECT().
+			Create(gomock.Any()).
+			Return(&entity.ImportJob{ID: 1, Status: entity.ImportJobPending, Filename: "feiras.csv"}, nil)
+		gomock.InOrder(
+			repo.EXPECT().Update(statusIs(entity.ImportJobRunning)).Return(&entity.ImportJob{}, nil),
+			repo.EXPECT().Update(statusIs(entity.ImportJobInterrupted)).Return(&entity.ImportJob{}, nil),
+		)
+		serv.
+			EXPECT().
+			ImportWithProgress(gomock.Any(), gomock.Any(), "", entity.AllAreas, "", gomock.Any()).
+			DoAndReturn(func(ctx context.Context, path, format string, _ entity.Areas, _ string, progress feiralivreServ.ProgressFunc) (feiralivreServ.Progress, error) {
+				close(started)
+				<-ctx.Done()
+				return feiralivreServ.Progress{}, ctx.Err()
+			})
+		ctx, cancel := context.WithCancel(context.Background())
+		s := New(ctx, afero.NewMemMapFs(), "/tmp", 0, repo, serv)
+
+		if _, err := s.Create("feiras.csv", "", entity.AllAreas, "", strings.NewReader("content")); err != nil {
+			t.Fatalf("was not expecting an error, but returns: %v", err)
+		}
+		<-started
+
+		cancel()
+		if err := s.Wait(context.Background()); err != nil {
+			t.Errorf("was not expecting an error, but returns: %v", err)
+		}
+	})
+
+	t.Run("when the wait times out", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		repo := importjob.NewMockRepository(ctrl)
+		serv := feiralivreServ.NewMockService(ctrl)
+		release := make(chan struct{})
+		repo.
+			EXPECT().
+			Create(gomock.Any()).
+			Return(&entity.ImportJob{ID: 1, Status: entity.ImportJobPending, Filename: "feiras.csv"}, nil)
+		repo.EXPECT().Update(gomock.Any()).Return(&entity.ImportJob{}, nil).Times(2)
+		serv.
+			EXPECT().
+			ImportWithProgress(gomock.Any(), gomock.Any(), "", entity.AllAreas, "", gomock.Any()).
+			DoAndReturn(func(ctx context.Context, path, format string, _ entity.Areas, _ string, progress feiralivreServ.ProgressFunc) (feiralivreServ.Progress, error) {
+				<-release
+				return feiralivreServ.Progress{Done: true}, nil
+			})
+		s := New(context.Background(), afero.NewMemMapFs(), "/tmp", 0, repo, serv)
+
+		if _, err := s.Create("feiras.csv", "", entity.AllAreas, "", strings.NewReader("content")); err != nil {
+			t.Fatalf("was not expecting an error, but returns: %v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		if err := s.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("was expecting %v, but returns %v", context.DeadlineExceeded, err)
+		}
+
+		close(release)
+		s.Wait(context.Background())
+	})
+}
+
+func TestServiceHeartbeat(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	ctrl := gomock.NewController(t)
+	repo := importjob.NewMockRepository(ctrl)
+	serv := feiralivreServ.NewMockService(ctrl)
+
+	touched := make(chan struct{})
+	var once sync.Once
+	repo.
+		EXPECT().
+		Create(gomock.Any()).
+		Return(&entity.ImportJob{ID: 1, Status: entity.ImportJobPending, Filename: "feiras.csv", Format: "csv"}, nil)
+	repo.EXPECT().Update(gomock.Any()).Return(&entity.ImportJob{}, nil).Times(2)
+	repo.EXPECT().Touch(1).Return(false, nil).Do(func(int) { once.Do(func() { close(touched) }) }).MinTimes(1)
+	serv.
+		EXPECT().
+		ImportWithProgress(gomock.Any(), gomock.Any(), "csv", entity.AllAreas, "", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, path, format string, _ entity.Areas, _ string, progress feiralivreServ.ProgressFunc) (feiralivreServ.Progress, error) {
+			// the import lasts until the heartbeat is recorded
+			<-touched
+			return feiralivreServ.Progress{Done: true}, nil
+		})
+
+	s := New(context.Background(), afero.NewMemMapFs(), "/tmp", 0, repo, serv).(*service)
+	s.heartbeat = time.Millisecond
+
+	if _, err := s.Create("/uploads/feiras.csv", "csv", entity.AllAreas, "", strings.NewReader("content")); err != nil {
+		t.Fatalf("was not expecting an error, but returns: %v", err)
+	}
+	s.Wait(context.Background())
+}
+
+func TestServiceHeartbeatCancel(t *testing.T) {
+	logrus.SetOutput(ioutil.Discard)
+	ctrl := gomock.NewController(t)
+	repo := importjob.NewMockRepository(ctrl)
+	serv := feiralivreServ.NewMockService(ctrl)
+
+	repo.
+		EXPECT().
+		Create(gomock.Any()).
+		Return(&entity.ImportJob{ID: 1, Status: entity.ImportJobPending, Filename: "feiras.csv"}, nil)
+	gomock.InOrder(
+		repo.EXPECT().Update(statusIs(entity.ImportJobRunning)).Return(&entity.ImportJob{}, nil),
+		repo.EXPECT().Update(statusIs(entity.ImportJobCanceled)).Return(&entity.ImportJob{}, nil),
+	)
+	// the cancel was requested to another instance
+	repo.EXPECT().Touch(1).Return(true, nil).MinTimes(1)
+	serv.
+		EXPECT().
+		ImportWithProgress(gomock.Any(), gomock.Any(), "", entity.AllAreas, "", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, path, format string, _ entity.Areas, _ string, progress feiralivreServ.ProgressFunc) (feiralivreServ.Progress, error) {
+			<-ctx.Done()
+			return feiralivreServ.Progress{}, ctx.Err()
+		})
+
+	s := New(context.Background(), afero.NewMemMapFs(), "/tmp", 0, repo, serv).(*service)
+	s.heartbeat = time.Millisecond
+
+	if _, err := s.Create("feiras.csv", "", entity.AllAreas, "", strings.NewReader("content")); err != nil {
+		t.Fatalf("was not expecting an error, but returns: %v", err)
+	}
+	s.Wait(context.Background())
+}
+
+func TestServiceInterruptUnfinished(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := importjob.NewMockRepository(ctrl)
+	repo.EXPECT().InterruptUnfinished(StaleAfter).Return(int64(2), nil)
+
+	n, err := New(context.Background(), afero.NewMemMapFs(), "/tmp", 0, repo, nil).InterruptUnfinished()
+	if err != nil || n != 2 {
+		t.Errorf("was expecting 2, but returns %d and %v", n, err)
+	}
+}